@@ -11,6 +11,7 @@ type CookieHandler struct {
 	Secret            string
 	Ip                string
 	Authorization     string
+	VerifyCsrf        func(r *http.Request) (int, error)
 }
 
 func NewCookieHandler(verifyToken func(string, string) (bool, string, map[string]interface{}, int64, int64, error), secret string, options ...string) *CookieHandler {
@@ -29,12 +30,24 @@ func NewCookieHandlerWithIp(verifyToken func(string, string) (bool, string, map[
 	return &CookieHandler{Authorization: authorization, GetAndVerifyToken: verifyToken, Secret: secret, Token: token, Ip: ip}
 }
 
+func NewCookieHandlerWithCsrf(verifyToken func(string, string) (bool, string, map[string]interface{}, int64, int64, error), secret string, ip string, verifyCsrf func(*http.Request) (int, error), options ...string) *CookieHandler {
+	c := NewCookieHandlerWithIp(verifyToken, secret, ip, options...)
+	c.VerifyCsrf = verifyCsrf
+	return c
+}
+
 func (c *CookieHandler) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tokenCookie, err := r.Cookie(c.Token)
 		if err != nil || tokenCookie == nil {
 			next.ServeHTTP(w, r)
 		} else {
+			if c.VerifyCsrf != nil {
+				if status, err := c.VerifyCsrf(r); err != nil {
+					http.Error(w, err.Error(), status)
+					return
+				}
+			}
 			authorization := tokenCookie.Value
 			isToken, _, data, _, _, err := c.GetAndVerifyToken(authorization, c.Secret)
 			var ctx context.Context
@@ -0,0 +1,149 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	c "github.com/core-go/core/channel"
+)
+
+// PreferenceAdapter stores the channels of a user as a comma separated list, in the order of preference.
+type PreferenceAdapter struct {
+	DB         *sql.DB
+	BuildParam func(int) string
+	Table      string
+	UserId     string
+	Channels   string
+}
+
+// NewPreferenceAdapter creates the adapter of the preference table; opts are the user id column and the channels column, the defaults are user_id and channels.
+func NewPreferenceAdapter(db *sql.DB, buildParam func(int) string, table string, opts ...string) *PreferenceAdapter {
+	a := &PreferenceAdapter{DB: db, BuildParam: buildParam, Table: table, UserId: "user_id", Channels: "channels"}
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		a.UserId = opts[0]
+	}
+	if len(opts) > 1 && len(opts[1]) > 0 {
+		a.Channels = opts[1]
+	}
+	return a
+}
+func (a *PreferenceAdapter) Load(ctx context.Context, userId string) ([]string, error) {
+	query := fmt.Sprintf("select %s from %s where %s = %s", a.Channels, a.Table, a.UserId, a.BuildParam(1))
+	var channels sql.NullString
+	err := a.DB.QueryRowContext(ctx, query, userId).Scan(&channels)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.Merge(strings.Split(channels.String, ","), nil), nil
+}
+func (a *PreferenceAdapter) Save(ctx context.Context, userId string, channels []string) (int64, error) {
+	v := strings.Join(c.Merge(channels, nil), ",")
+	query := fmt.Sprintf("update %s set %s = %s where %s = %s", a.Table, a.Channels, a.BuildParam(1), a.UserId, a.BuildParam(2))
+	res, err := a.DB.ExecContext(ctx, query, v, userId)
+	if err != nil {
+		return -1, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n > 0 {
+		return n, err
+	}
+	query = fmt.Sprintf("insert into %s(%s,%s) values (%s,%s)", a.Table, a.UserId, a.Channels, a.BuildParam(1), a.BuildParam(2))
+	res, err = a.DB.ExecContext(ctx, query, userId, v)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+
+// SubscriptionAdapter stores the web push subscriptions; a user may have a subscription per browser, the endpoint is the key.
+type SubscriptionAdapter struct {
+	DB         *sql.DB
+	BuildParam func(int) string
+	Table      string
+	Endpoint   string
+	UserId     string
+	P256dh     string
+	Auth       string
+	CreatedAt  string
+}
+
+// NewSubscriptionAdapter creates the adapter of the subscription table, with the columns endpoint, user_id, p256dh, auth and created_at.
+func NewSubscriptionAdapter(db *sql.DB, buildParam func(int) string, table string) *SubscriptionAdapter {
+	return &SubscriptionAdapter{DB: db, BuildParam: buildParam, Table: table, Endpoint: "endpoint", UserId: "user_id", P256dh: "p256dh", Auth: "auth", CreatedAt: "created_at"}
+}
+func (a *SubscriptionAdapter) Load(ctx context.Context, userId string) ([]c.Subscription, error) {
+	query := fmt.Sprintf("select %s,%s,%s,%s,%s from %s where %s = %s", a.Endpoint, a.UserId, a.P256dh, a.Auth, a.CreatedAt, a.Table, a.UserId, a.BuildParam(1))
+	rows, err := a.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var subs []c.Subscription
+	for rows.Next() {
+		var s c.Subscription
+		var createdAt sql.NullTime
+		if err = rows.Scan(&s.Endpoint, &s.UserId, &s.Keys.P256dh, &s.Keys.Auth, &createdAt); err != nil {
+			return subs, err
+		}
+		if createdAt.Valid {
+			s.CreatedAt = &createdAt.Time
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// Save inserts the subscription, or updates its keys if the browser of the same user subscribes again with the same endpoint.
+// An endpoint of another user is never moved to this user: Save returns 0, so the endpoint must be deleted by its user first.
+func (a *SubscriptionAdapter) Save(ctx context.Context, s c.Subscription) (int64, error) {
+	query := fmt.Sprintf("update %s set %s = %s, %s = %s where %s = %s and %s = %s", a.Table,
+		a.P256dh, a.BuildParam(1), a.Auth, a.BuildParam(2), a.Endpoint, a.BuildParam(3), a.UserId, a.BuildParam(4))
+	res, err := a.DB.ExecContext(ctx, query, s.Keys.P256dh, s.Keys.Auth, s.Endpoint, s.UserId)
+	if err != nil {
+		return -1, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n > 0 {
+		return n, err
+	}
+	createdAt := time.Now()
+	if s.CreatedAt != nil {
+		createdAt = *s.CreatedAt
+	}
+	query = fmt.Sprintf("insert into %s(%s,%s,%s,%s,%s) values (%s,%s,%s,%s,%s)", a.Table, a.Endpoint, a.UserId, a.P256dh, a.Auth, a.CreatedAt,
+		a.BuildParam(1), a.BuildParam(2), a.BuildParam(3), a.BuildParam(4), a.BuildParam(5))
+	res, err = a.DB.ExecContext(ctx, query, s.Endpoint, s.UserId, s.Keys.P256dh, s.Keys.Auth, createdAt)
+	if err != nil {
+		// the endpoint is the key, so the insert fails if the endpoint is subscribed by another user
+		if exist, er2 := a.exist(ctx, s.Endpoint); er2 == nil && exist {
+			return 0, nil
+		}
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+func (a *SubscriptionAdapter) exist(ctx context.Context, endpoint string) (bool, error) {
+	query := fmt.Sprintf("select %s from %s where %s = %s", a.Endpoint, a.Table, a.Endpoint, a.BuildParam(1))
+	rows, err := a.DB.QueryContext(ctx, query, endpoint)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+// Delete deletes the subscription of the endpoint, only if it belongs to the user.
+func (a *SubscriptionAdapter) Delete(ctx context.Context, userId string, endpoint string) (int64, error) {
+	query := fmt.Sprintf("delete from %s where %s = %s and %s = %s", a.Table, a.Endpoint, a.BuildParam(1), a.UserId, a.BuildParam(2))
+	res, err := a.DB.ExecContext(ctx, query, endpoint, userId)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,60 @@
+package channel
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	Push  = "push"
+	SMS   = "sms"
+	Email = "email"
+	InApp = "inapp"
+)
+
+// ErrNoAddress is returned by a channel when the contact cannot be reached by this channel, such as no phone number for SMS or no subscription for web push.
+var ErrNoAddress = errors.New("no address for this channel")
+
+// ErrNoChannel is returned when no channel can deliver the message.
+var ErrNoChannel = errors.New("no channel to deliver the message")
+
+type Message struct {
+	Title string                 `yaml:"title" mapstructure:"title" json:"title,omitempty" gorm:"column:title" bson:"title,omitempty" dynamodbav:"title,omitempty" firestore:"title,omitempty"`
+	Body  string                 `yaml:"body" mapstructure:"body" json:"body,omitempty" gorm:"column:body" bson:"body,omitempty" dynamodbav:"body,omitempty" firestore:"body,omitempty"`
+	Html  string                 `yaml:"html" mapstructure:"html" json:"html,omitempty" gorm:"column:html" bson:"html,omitempty" dynamodbav:"html,omitempty" firestore:"html,omitempty"`
+	Url   string                 `yaml:"url" mapstructure:"url" json:"url,omitempty" gorm:"column:url" bson:"url,omitempty" dynamodbav:"url,omitempty" firestore:"url,omitempty"`
+	Data  map[string]interface{} `yaml:"data" mapstructure:"data" json:"data,omitempty" gorm:"column:data" bson:"data,omitempty" dynamodbav:"data,omitempty" firestore:"data,omitempty"`
+	// Sender is the id of the sender, for the in-app notification
+	Sender string `yaml:"sender" mapstructure:"sender" json:"sender,omitempty" gorm:"column:sender" bson:"sender,omitempty" dynamodbav:"sender,omitempty" firestore:"sender,omitempty"`
+}
+
+type Contact struct {
+	Id     string `yaml:"id" mapstructure:"id" json:"id,omitempty" gorm:"column:id;primary_key" bson:"_id,omitempty" dynamodbav:"id,omitempty" firestore:"id,omitempty"`
+	Name   string `yaml:"name" mapstructure:"name" json:"name,omitempty" gorm:"column:name" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
+	Email  string `yaml:"email" mapstructure:"email" json:"email,omitempty" gorm:"column:email" bson:"email,omitempty" dynamodbav:"email,omitempty" firestore:"email,omitempty"`
+	Phone  string `yaml:"phone" mapstructure:"phone" json:"phone,omitempty" gorm:"column:phone" bson:"phone,omitempty" dynamodbav:"phone,omitempty" firestore:"phone,omitempty"`
+	Locale string `yaml:"locale" mapstructure:"locale" json:"locale,omitempty" gorm:"column:locale" bson:"locale,omitempty" dynamodbav:"locale,omitempty" firestore:"locale,omitempty"`
+}
+
+// Channel delivers a message to a contact. It returns ErrNoAddress if the contact cannot be reached by this channel, so that the dispatcher falls back to the next one.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, to Contact, msg Message) error
+}
+
+// ContactPort loads the addresses of a user.
+type ContactPort interface {
+	Load(ctx context.Context, id string) (*Contact, error)
+}
+
+// PreferencePort loads and saves the channels a user wants to be notified by, in the order of preference.
+type PreferencePort interface {
+	Load(ctx context.Context, userId string) ([]string, error)
+	Save(ctx context.Context, userId string, channels []string) (int64, error)
+}
+
+type ContactLoader func(ctx context.Context, id string) (*Contact, error)
+
+func (f ContactLoader) Load(ctx context.Context, id string) (*Contact, error) {
+	return f(ctx, id)
+}
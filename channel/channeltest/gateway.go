@@ -0,0 +1,240 @@
+// Package channeltest provides a local gateway, which acts as an SMS gateway and as a web push service, to test the channels end to end without network access.
+package channeltest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/core-go/core/channel"
+)
+
+type Sms struct {
+	Header   http.Header
+	Body     map[string]string
+	Received time.Time
+}
+
+type Push struct {
+	UserId   string
+	Endpoint string
+	Header   http.Header
+	// Payload is the decrypted payload, if the subscription was created by NewSubscription
+	Payload  []byte
+	Received time.Time
+}
+
+type subscriber struct {
+	userId string
+	key    *ecdsa.PrivateKey
+	auth   []byte
+}
+
+// Gateway receives the SMS on POST /sms and the push messages on POST /push/{id}.
+// The push messages to a subscription, which is expired by Expire, are answered with 410, to test the clean up of the subscriptions.
+type Gateway struct {
+	Addr string
+	// Status is the status to reply, to test the failures; the default is 200 for SMS and 201 for push
+	Status      int
+	server      *http.Server
+	listener    net.Listener
+	mu          sync.Mutex
+	sms         []Sms
+	pushes      []Push
+	subscribers map[string]*subscriber
+	expired     map[string]bool
+	seq         int
+	received    chan struct{}
+}
+
+func NewGateway() *Gateway {
+	return &Gateway{subscribers: make(map[string]*subscriber), expired: make(map[string]bool), received: make(chan struct{}, 1)}
+}
+
+// Start listens on a random port of 127.0.0.1, or on Addr if it is set.
+func (g *Gateway) Start() error {
+	addr := g.Addr
+	if len(addr) == 0 {
+		addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	g.listener = ln
+	g.Addr = ln.Addr().String()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sms", g.handleSms)
+	mux.HandleFunc("/push/", g.handlePush)
+	g.server = &http.Server{Handler: mux}
+	go g.server.Serve(ln)
+	return nil
+}
+func (g *Gateway) Close() error {
+	if g.server == nil {
+		return nil
+	}
+	return g.server.Close()
+}
+func (g *Gateway) Url() string {
+	return "http://" + g.Addr
+}
+
+// SmsConfig returns the config of channel.HttpSmsGateway to send to this gateway.
+func (g *Gateway) SmsConfig() channel.SmsConfig {
+	return channel.SmsConfig{Url: g.Url() + "/sms", From: "test"}
+}
+
+// NewSubscription creates a subscription with a new key pair, whose endpoint is this gateway, so that the gateway can decrypt the payloads.
+func (g *Gateway) NewSubscription(userId string) (channel.Subscription, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return channel.Subscription{}, err
+	}
+	auth := make([]byte, 16)
+	if _, err = rand.Read(auth); err != nil {
+		return channel.Subscription{}, err
+	}
+	g.mu.Lock()
+	g.seq++
+	id := strconv.Itoa(g.seq)
+	g.subscribers[id] = &subscriber{userId: userId, key: key, auth: auth}
+	g.mu.Unlock()
+	now := time.Now()
+	return channel.Subscription{
+		UserId:   userId,
+		Endpoint: g.Url() + "/push/" + id,
+		Keys: channel.SubscriptionKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(elliptic.Marshal(elliptic.P256(), key.X, key.Y)),
+			Auth:   base64.RawURLEncoding.EncodeToString(auth),
+		},
+		CreatedAt: &now,
+	}, nil
+}
+
+// Expire makes the push service answer 410 to the subscription.
+func (g *Gateway) Expire(endpoint string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.expired[endpoint[strings.LastIndex(endpoint, "/")+1:]] = true
+}
+func (g *Gateway) Sms() []Sms {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	res := make([]Sms, len(g.sms))
+	copy(res, g.sms)
+	return res
+}
+func (g *Gateway) Pushes() []Push {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	res := make([]Push, len(g.pushes))
+	copy(res, g.pushes)
+	return res
+}
+func (g *Gateway) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sms = nil
+	g.pushes = nil
+}
+
+// Wait waits until n requests, SMS and push, are received, and returns false on timeout.
+func (g *Gateway) Wait(n int, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		g.mu.Lock()
+		count := len(g.sms) + len(g.pushes)
+		g.mu.Unlock()
+		if count >= n {
+			return true
+		}
+		select {
+		case <-g.received:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+func (g *Gateway) handleSms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body := make(map[string]string)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "cannot decode request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if g.Status >= http.StatusMultipleChoices {
+		http.Error(w, http.StatusText(g.Status), g.Status)
+		return
+	}
+	g.mu.Lock()
+	g.sms = append(g.sms, Sms{Header: r.Header.Clone(), Body: body, Received: time.Now()})
+	g.mu.Unlock()
+	g.notify()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"sent"}`))
+}
+func (g *Gateway) handlePush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/push/")
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "vapid t=") || r.Header.Get("Content-Encoding") != "aes128gcm" {
+		http.Error(w, "missing VAPID authorization or content encoding", http.StatusUnauthorized)
+		return
+	}
+	g.mu.Lock()
+	sub := g.subscribers[id]
+	expired := g.expired[id]
+	g.mu.Unlock()
+	if expired {
+		http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+		return
+	}
+	if g.Status >= http.StatusMultipleChoices {
+		http.Error(w, http.StatusText(g.Status), g.Status)
+		return
+	}
+	p := Push{Endpoint: g.Url() + r.URL.Path, Header: r.Header.Clone(), Received: time.Now()}
+	if sub != nil {
+		p.UserId = sub.userId
+		payload, er1 := channel.Decrypt(sub.key, sub.auth, body)
+		if er1 != nil {
+			http.Error(w, "cannot decrypt payload: "+er1.Error(), http.StatusBadRequest)
+			return
+		}
+		p.Payload = payload
+	}
+	g.mu.Lock()
+	g.pushes = append(g.pushes, p)
+	g.mu.Unlock()
+	g.notify()
+	w.WriteHeader(http.StatusCreated)
+}
+func (g *Gateway) notify() {
+	select {
+	case g.received <- struct{}{}:
+	default:
+	}
+}
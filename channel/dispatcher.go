@@ -0,0 +1,170 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultOrder is the fallback order when the user has no preference.
+var DefaultOrder = []string{Push, SMS, Email}
+
+// Dispatcher sends a message to a user through the first channel that delivers it.
+//   - The channels are tried in the order of the user preferences, then in Order; a channel, which is not registered, is skipped.
+//   - If a channel returns ErrNoAddress or another error, the next channel is tried.
+//   - The channels in Always, such as the in-app channel, receive the message in addition to the fallback chain, and their errors are only logged.
+type Dispatcher struct {
+	Channels    map[string]Channel
+	Order       []string
+	Always      []string
+	Contacts    ContactPort
+	Preferences PreferencePort
+	LogError    func(context.Context, string, ...map[string]interface{})
+}
+
+// NewDispatcher creates a dispatcher with the default order push, SMS, email. An in-app channel is added to Always.
+func NewDispatcher(contacts ContactPort, preferences PreferencePort, logError func(context.Context, string, ...map[string]interface{}), channels ...Channel) *Dispatcher {
+	d := &Dispatcher{Channels: make(map[string]Channel), Order: DefaultOrder, Contacts: contacts, Preferences: preferences, LogError: logError}
+	for _, c := range channels {
+		d.Channels[c.Name()] = c
+		if c.Name() == InApp {
+			d.Always = append(d.Always, InApp)
+		}
+	}
+	return d
+}
+func (d *Dispatcher) Register(c Channel) {
+	d.Channels[c.Name()] = c
+}
+
+// OrderOf returns the channels to try for a user: the preferred channels first, then the default order.
+func (d *Dispatcher) OrderOf(ctx context.Context, userId string) ([]string, error) {
+	var preferred []string
+	if d.Preferences != nil {
+		p, err := d.Preferences.Load(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		preferred = p
+	}
+	return Merge(preferred, d.Order), nil
+}
+
+// Notify sends the message to the user, and returns the name of the channel which delivered it.
+func (d *Dispatcher) Notify(ctx context.Context, userId string, msg Message) (string, error) {
+	to, err := d.Contacts.Load(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	if to == nil {
+		return "", fmt.Errorf("cannot find the contact of %s", userId)
+	}
+	if len(to.Id) == 0 {
+		to.Id = userId
+	}
+	for _, name := range d.Always {
+		if c, ok := d.Channels[name]; ok {
+			if er1 := c.Send(ctx, *to, msg); er1 != nil && !errors.Is(er1, ErrNoAddress) {
+				d.logError(ctx, fmt.Sprintf("cannot send to %s by %s: %s", userId, name, er1.Error()))
+			}
+		}
+	}
+	order, err := d.OrderOf(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	return d.Send(ctx, *to, msg, order...)
+}
+
+// Send tries the channels in order, without the preferences and the channels in Always.
+func (d *Dispatcher) Send(ctx context.Context, to Contact, msg Message, order ...string) (string, error) {
+	if len(order) == 0 {
+		order = d.Order
+	}
+	var errs []string
+	for _, name := range order {
+		c, ok := d.Channels[name]
+		if !ok {
+			continue
+		}
+		err := c.Send(ctx, to, msg)
+		if err == nil {
+			return name, nil
+		}
+		if errors.Is(err, ErrNoAddress) {
+			continue
+		}
+		d.logError(ctx, fmt.Sprintf("cannot send to %s by %s: %s", to.Id, name, err.Error()))
+		errs = append(errs, name+": "+err.Error())
+	}
+	if len(errs) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrNoChannel, strings.Join(errs, "; "))
+	}
+	return "", ErrNoChannel
+}
+func (d *Dispatcher) logError(ctx context.Context, msg string) {
+	if d.LogError != nil {
+		d.LogError(ctx, msg)
+	}
+}
+
+// Merge returns the preferred channels, followed by the channels of the default order which are not preferred.
+func Merge(preferred []string, order []string) []string {
+	res := make([]string, 0, len(preferred)+len(order))
+	seen := make(map[string]bool)
+	for _, list := range [][]string{preferred, order} {
+		for _, c := range list {
+			c = strings.TrimSpace(c)
+			if len(c) > 0 && !seen[c] {
+				seen[c] = true
+				res = append(res, c)
+			}
+		}
+	}
+	return res
+}
+
+// PasscodeSender sends the passcode through the dispatcher, so that it implements passcode.Sender. The "to" is the user id.
+// The passcode is never sent to the channels in Always, because an in-app notification is visible to a signed in session only.
+type PasscodeSender struct {
+	Dispatcher *Dispatcher
+	Format     func(ctx context.Context, to Contact, code string, expireAt time.Time, params interface{}) Message
+}
+
+func NewPasscodeSender(dispatcher *Dispatcher, opts ...func(ctx context.Context, to Contact, code string, expireAt time.Time, params interface{}) Message) *PasscodeSender {
+	s := &PasscodeSender{Dispatcher: dispatcher, Format: FormatPasscode}
+	if len(opts) > 0 && opts[0] != nil {
+		s.Format = opts[0]
+	}
+	return s
+}
+func (s *PasscodeSender) Send(ctx context.Context, to string, code string, expireAt time.Time, params interface{}) error {
+	d := s.Dispatcher
+	contact, err := d.Contacts.Load(ctx, to)
+	if err != nil {
+		return err
+	}
+	if contact == nil {
+		return fmt.Errorf("cannot find the contact of %s", to)
+	}
+	if len(contact.Id) == 0 {
+		contact.Id = to
+	}
+	order, err := d.OrderOf(ctx, to)
+	if err != nil {
+		return err
+	}
+	_, err = d.Send(ctx, *contact, s.Format(ctx, *contact, code, expireAt, params), order...)
+	return err
+}
+
+func FormatPasscode(ctx context.Context, to Contact, code string, expireAt time.Time, params interface{}) Message {
+	minutes := fmt.Sprintf("%.f", time.Until(expireAt).Minutes())
+	return Message{
+		Title: "Verification code",
+		Body:  fmt.Sprintf("Your verification code is %s. It expires in %s minutes.", code, minutes),
+		Data:  map[string]interface{}{"code": code, "expireAt": expireAt},
+	}
+}
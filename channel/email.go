@@ -0,0 +1,34 @@
+package channel
+
+import (
+	"context"
+
+	"github.com/core-go/core/mail"
+)
+
+// EmailChannel sends the message as a mail; if Html is empty, the mail is in plain text.
+type EmailChannel struct {
+	Sender mail.MailSender
+	From   mail.Email
+}
+
+func NewEmailChannel(sender mail.MailSender, from mail.Email) *EmailChannel {
+	return &EmailChannel{Sender: sender, From: from}
+}
+func (c *EmailChannel) Name() string {
+	return Email
+}
+func (c *EmailChannel) Send(ctx context.Context, to Contact, msg Message) error {
+	if len(to.Email) == 0 {
+		return ErrNoAddress
+	}
+	mailTo := []mail.Email{{Name: to.Name, Address: to.Email}}
+	text := SmsText(Message{Body: msg.Body, Url: msg.Url})
+	var m *mail.Mail
+	if len(msg.Html) > 0 {
+		m = mail.NewSingleEmail(c.From, msg.Title, mailTo, nil, text, msg.Html)
+	} else {
+		m = mail.NewPlainTextMail(c.From, msg.Title, mailTo, nil, text)
+	}
+	return c.Sender.Send(*m)
+}
@@ -0,0 +1,178 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/core-go/core"
+)
+
+// PushHosts are the hosts of the push services of the browsers; a host, which starts with "*.", matches its subdomains.
+var PushHosts = []string{"fcm.googleapis.com", "android.googleapis.com", "updates.push.services.mozilla.com", "web.push.apple.com", "*.push.apple.com", "*.notify.windows.com"}
+
+type Preference struct {
+	Channels []string `yaml:"channels" mapstructure:"channels" json:"channels,omitempty" gorm:"column:channels" bson:"channels,omitempty" dynamodbav:"channels,omitempty" firestore:"channels,omitempty"`
+}
+
+// Handler lets the signed in user choose the channels and register the web push subscriptions of the browsers. The user id is from the context.
+// The server posts to the endpoints of the subscriptions, so an endpoint must be an https url of a host of PushHosts, which is PushHosts by default.
+type Handler struct {
+	Preferences   PreferencePort
+	Subscriptions SubscriptionPort
+	PublicKey     string
+	Channels      []string
+	PushHosts     []string
+	LogError      func(context.Context, string, ...map[string]interface{})
+	UserId        string
+}
+
+// NewHandler creates the handler; channels are the channels the user can choose, the default is the default order and the in-app channel. opts[0] is the key of the user id in the context.
+func NewHandler(preferences PreferencePort, subscriptions SubscriptionPort, publicKey string, channels []string, logError func(context.Context, string, ...map[string]interface{}), opts ...string) *Handler {
+	if len(channels) == 0 {
+		channels = append(Merge(nil, DefaultOrder), InApp)
+	}
+	userId := "userId"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		userId = opts[0]
+	}
+	return &Handler{Preferences: preferences, Subscriptions: subscriptions, PublicKey: publicKey, Channels: channels, PushHosts: PushHosts, LogError: logError, UserId: userId}
+}
+
+// GetPublicKey returns the VAPID public key, which is the applicationServerKey of pushManager.subscribe.
+func (h *Handler) GetPublicKey(w http.ResponseWriter, r *http.Request) {
+	core.JSON(w, http.StatusOK, map[string]string{"publicKey": h.PublicKey})
+}
+func (h *Handler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+	channels, err := h.Preferences.Load(r.Context(), id)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	if channels == nil {
+		channels = []string{}
+	}
+	core.JSON(w, http.StatusOK, Preference{Channels: channels})
+}
+func (h *Handler) SavePreferences(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+	var p Preference
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "cannot decode request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	channels := Merge(p.Channels, nil)
+	for _, c := range channels {
+		if !contains(h.Channels, c) {
+			http.Error(w, "invalid channel: "+c+", the channels are "+strings.Join(h.Channels, ", "), http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := h.Preferences.Save(r.Context(), id, channels); err != nil {
+		h.error(w, r, err)
+		return
+	}
+	core.JSON(w, http.StatusOK, Preference{Channels: channels})
+}
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+	var sub Subscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		http.Error(w, "cannot decode request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(sub.Keys.P256dh) == 0 || len(sub.Keys.Auth) == 0 {
+		http.Error(w, "p256dh and auth are required", http.StatusBadRequest)
+		return
+	}
+	if !IsPushEndpoint(sub.Endpoint, h.PushHosts) {
+		http.Error(w, "endpoint must be an https url of a push service", http.StatusBadRequest)
+		return
+	}
+	sub.UserId = id
+	sub.CreatedAt = nil
+	res, err := h.Subscriptions.Save(r.Context(), sub)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	if res == 0 {
+		http.Error(w, "endpoint is subscribed by another user", http.StatusConflict)
+		return
+	}
+	core.JSON(w, http.StatusCreated, sub)
+}
+func (h *Handler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+	var sub Subscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil || len(sub.Endpoint) == 0 {
+		http.Error(w, "endpoint is required", http.StatusBadRequest)
+		return
+	}
+	res, err := h.Subscriptions.Delete(r.Context(), id, sub.Endpoint)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	if res <= 0 {
+		core.JSON(w, http.StatusNotFound, res)
+		return
+	}
+	core.JSON(w, http.StatusOK, res)
+}
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if u, ok := r.Context().Value(h.UserId).(string); ok && len(u) > 0 {
+		return u, true
+	}
+	http.Error(w, "cannot get current user", http.StatusForbidden)
+	return "", false
+}
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, err error) {
+	if h.LogError != nil {
+		h.LogError(r.Context(), err.Error())
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPushEndpoint checks if an endpoint is an https url of a host of the push services, so the server never posts to a private or a loopback address.
+func IsPushEndpoint(endpoint string, hosts []string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.User != nil || (len(u.Port()) > 0 && u.Port() != "443") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range hosts {
+		h = strings.ToLower(h)
+		if strings.HasPrefix(h, "*.") {
+			if strings.HasSuffix(host, h[1:]) && len(host) > len(h)-1 {
+				return true
+			}
+		} else if host == h {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package channel
+
+import (
+	"context"
+
+	"github.com/core-go/core/notification"
+)
+
+// InAppChannel writes the message to the notifications of the user, which are read by the notifications package.
+type InAppChannel struct {
+	Port   notification.NotificationPort
+	Sender string
+}
+
+// NewInAppChannel creates the in-app channel; opts[0] is the default sender, if the message has no sender.
+func NewInAppChannel(port notification.NotificationPort, opts ...string) *InAppChannel {
+	c := &InAppChannel{Port: port}
+	if len(opts) > 0 {
+		c.Sender = opts[0]
+	}
+	return c
+}
+func (c *InAppChannel) Name() string {
+	return InApp
+}
+func (c *InAppChannel) Send(ctx context.Context, to Contact, msg Message) error {
+	if len(to.Id) == 0 {
+		return ErrNoAddress
+	}
+	sender := msg.Sender
+	if len(sender) == 0 {
+		sender = c.Sender
+	}
+	text := msg.Body
+	if len(text) == 0 {
+		text = msg.Title
+	}
+	_, err := c.Port.Push(ctx, notification.Build(sender, to.Id, msg.Url, text))
+	return err
+}
@@ -0,0 +1,131 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/core-go/core/client"
+)
+
+type SmsSender interface {
+	SendSms(ctx context.Context, phone string, text string) error
+}
+
+type SmsConfig struct {
+	Url      string            `yaml:"url" mapstructure:"url" json:"url,omitempty" gorm:"column:url" bson:"url,omitempty" dynamodbav:"url,omitempty" firestore:"url,omitempty"`
+	From     string            `yaml:"from" mapstructure:"from" json:"from,omitempty" gorm:"column:from" bson:"from,omitempty" dynamodbav:"from,omitempty" firestore:"from,omitempty"`
+	Username *string           `yaml:"username" mapstructure:"username" json:"username,omitempty" gorm:"column:username" bson:"username,omitempty" dynamodbav:"username,omitempty" firestore:"username,omitempty"`
+	Password *string           `yaml:"password" mapstructure:"password" json:"password,omitempty" gorm:"column:password" bson:"password,omitempty" dynamodbav:"password,omitempty" firestore:"password,omitempty"`
+	Token    string            `yaml:"token" mapstructure:"token" json:"token,omitempty" gorm:"column:token" bson:"token,omitempty" dynamodbav:"token,omitempty" firestore:"token,omitempty"`
+	Header   map[string]string `yaml:"header" mapstructure:"header" json:"header,omitempty" gorm:"column:header" bson:"header,omitempty" dynamodbav:"header,omitempty" firestore:"header,omitempty"`
+	Client   client.Conf       `yaml:"client" mapstructure:"client" json:"client,omitempty" gorm:"column:client" bson:"client,omitempty" dynamodbav:"client,omitempty" firestore:"client,omitempty"`
+	// the names of the fields of the request body, the defaults are from, to and text
+	FromField string `yaml:"from_field" mapstructure:"from_field" json:"fromField,omitempty" gorm:"column:fromfield" bson:"fromField,omitempty" dynamodbav:"fromField,omitempty" firestore:"fromField,omitempty"`
+	ToField   string `yaml:"to_field" mapstructure:"to_field" json:"toField,omitempty" gorm:"column:tofield" bson:"toField,omitempty" dynamodbav:"toField,omitempty" firestore:"toField,omitempty"`
+	TextField string `yaml:"text_field" mapstructure:"text_field" json:"textField,omitempty" gorm:"column:textfield" bson:"textField,omitempty" dynamodbav:"textField,omitempty" firestore:"textField,omitempty"`
+}
+
+// HttpSmsGateway posts a JSON body {from, to, text} to a generic SMS gateway. Most gateways accept this shape, with the field names set by the config.
+type HttpSmsGateway struct {
+	Client    *http.Client
+	Url       string
+	From      string
+	Header    map[string]string
+	FromField string
+	ToField   string
+	TextField string
+}
+
+func NewHttpSmsGateway(c SmsConfig) (*HttpSmsGateway, error) {
+	hc, err := client.NewClient(c.Client)
+	if err != nil {
+		return nil, err
+	}
+	return NewHttpSmsGatewayWithClient(hc, c), nil
+}
+func NewHttpSmsGatewayWithClient(hc *http.Client, c SmsConfig) *HttpSmsGateway {
+	header := make(map[string]string)
+	for k, v := range c.Header {
+		header[k] = v
+	}
+	if c.Username != nil && c.Password != nil {
+		header["Authorization"] = "Basic " + client.BasicAuth(*c.Username, *c.Password)
+	} else if len(c.Token) > 0 {
+		header["Authorization"] = "Bearer " + c.Token
+	}
+	g := &HttpSmsGateway{Client: hc, Url: c.Url, From: c.From, Header: header, FromField: "from", ToField: "to", TextField: "text"}
+	if len(c.FromField) > 0 {
+		g.FromField = c.FromField
+	}
+	if len(c.ToField) > 0 {
+		g.ToField = c.ToField
+	}
+	if len(c.TextField) > 0 {
+		g.TextField = c.TextField
+	}
+	return g
+}
+func (g *HttpSmsGateway) SendSms(ctx context.Context, phone string, text string) error {
+	body := map[string]string{g.ToField: phone, g.TextField: text}
+	if len(g.From) > 0 {
+		body[g.FromField] = g.From
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	res, err := client.DoJSON(ctx, g.Client, http.MethodPost, g.Url, b, g.Header)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		rs, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return client.NewHttpError(res.StatusCode, nil, time.Since(start).Milliseconds(), fmt.Sprint("Response error with status code: ", res.StatusCode), g.Url, string(b), string(rs))
+	}
+	io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+type SmsChannel struct {
+	Sender SmsSender
+	// Format builds the text of the SMS; the default is the title and the body
+	Format func(msg Message) string
+}
+
+func NewSmsChannel(sender SmsSender, opts ...func(msg Message) string) *SmsChannel {
+	c := &SmsChannel{Sender: sender, Format: SmsText}
+	if len(opts) > 0 && opts[0] != nil {
+		c.Format = opts[0]
+	}
+	return c
+}
+func (c *SmsChannel) Name() string {
+	return SMS
+}
+func (c *SmsChannel) Send(ctx context.Context, to Contact, msg Message) error {
+	if len(to.Phone) == 0 {
+		return ErrNoAddress
+	}
+	return c.Sender.SendSms(ctx, to.Phone, c.Format(msg))
+}
+
+func SmsText(msg Message) string {
+	var parts []string
+	if len(msg.Title) > 0 && msg.Title != msg.Body {
+		parts = append(parts, msg.Title)
+	}
+	if len(msg.Body) > 0 {
+		parts = append(parts, msg.Body)
+	}
+	if len(msg.Url) > 0 {
+		parts = append(parts, msg.Url)
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,347 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/core-go/core/client"
+)
+
+// ErrSubscriptionGone is returned when the push service does not know the subscription anymore (404 or 410), so that it must be deleted.
+var ErrSubscriptionGone = errors.New("push subscription is gone")
+
+type SubscriptionKeys struct {
+	P256dh string `yaml:"p256dh" mapstructure:"p256dh" json:"p256dh,omitempty" gorm:"column:p256dh" bson:"p256dh,omitempty" dynamodbav:"p256dh,omitempty" firestore:"p256dh,omitempty"`
+	Auth   string `yaml:"auth" mapstructure:"auth" json:"auth,omitempty" gorm:"column:auth" bson:"auth,omitempty" dynamodbav:"auth,omitempty" firestore:"auth,omitempty"`
+}
+
+// Subscription is the PushSubscription of the browser, as returned by PushSubscription.toJSON().
+type Subscription struct {
+	UserId    string           `yaml:"user_id" mapstructure:"user_id" json:"userId,omitempty" gorm:"column:user_id" bson:"userId,omitempty" dynamodbav:"userId,omitempty" firestore:"userId,omitempty"`
+	Endpoint  string           `yaml:"endpoint" mapstructure:"endpoint" json:"endpoint,omitempty" gorm:"column:endpoint;primary_key" bson:"_id,omitempty" dynamodbav:"endpoint,omitempty" firestore:"endpoint,omitempty"`
+	Keys      SubscriptionKeys `yaml:"keys" mapstructure:"keys" json:"keys,omitempty" gorm:"embedded" bson:"keys,omitempty" dynamodbav:"keys,omitempty" firestore:"keys,omitempty"`
+	CreatedAt *time.Time       `yaml:"created_at" mapstructure:"created_at" json:"createdAt,omitempty" gorm:"column:created_at" bson:"createdAt,omitempty" dynamodbav:"createdAt,omitempty" firestore:"createdAt,omitempty"`
+}
+
+type SubscriptionPort interface {
+	Load(ctx context.Context, userId string) ([]Subscription, error)
+	Save(ctx context.Context, sub Subscription) (int64, error)
+	Delete(ctx context.Context, userId string, endpoint string) (int64, error)
+}
+
+type VapidConfig struct {
+	// PublicKey is the uncompressed P-256 point, PrivateKey is the 32 bytes of the private key, both in base64 url encoding
+	PublicKey  string         `yaml:"public_key" mapstructure:"public_key" json:"publicKey,omitempty" gorm:"column:publickey" bson:"publicKey,omitempty" dynamodbav:"publicKey,omitempty" firestore:"publicKey,omitempty"`
+	PrivateKey string         `yaml:"private_key" mapstructure:"private_key" json:"privateKey,omitempty" gorm:"column:privatekey" bson:"privateKey,omitempty" dynamodbav:"privateKey,omitempty" firestore:"privateKey,omitempty"`
+	Subject    string         `yaml:"subject" mapstructure:"subject" json:"subject,omitempty" gorm:"column:subject" bson:"subject,omitempty" dynamodbav:"subject,omitempty" firestore:"subject,omitempty"`
+	TTL        *time.Duration `yaml:"ttl" mapstructure:"ttl" json:"ttl,omitempty" gorm:"column:ttl" bson:"ttl,omitempty" dynamodbav:"ttl,omitempty" firestore:"ttl,omitempty"`
+	Urgency    string         `yaml:"urgency" mapstructure:"urgency" json:"urgency,omitempty" gorm:"column:urgency" bson:"urgency,omitempty" dynamodbav:"urgency,omitempty" firestore:"urgency,omitempty"`
+	Client     client.Conf    `yaml:"client" mapstructure:"client" json:"client,omitempty" gorm:"column:client" bson:"client,omitempty" dynamodbav:"client,omitempty" firestore:"client,omitempty"`
+}
+
+// WebPush sends the messages to the push services, with the VAPID authentication (RFC 8292) and the aes128gcm payload encryption (RFC 8291).
+type WebPush struct {
+	Client     *http.Client
+	PrivateKey *ecdsa.PrivateKey
+	PublicKey  string
+	Subject    string
+	TTL        time.Duration
+	Urgency    string
+	Now        func() time.Time
+}
+
+func NewWebPush(c VapidConfig) (*WebPush, error) {
+	hc, err := client.NewClient(c.Client)
+	if err != nil {
+		return nil, err
+	}
+	return NewWebPushWithClient(hc, c)
+}
+func NewWebPushWithClient(hc *http.Client, c VapidConfig) (*WebPush, error) {
+	key, err := ParseVapidPrivateKey(c.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKey := c.PublicKey
+	if len(publicKey) == 0 {
+		publicKey = base64.RawURLEncoding.EncodeToString(elliptic.Marshal(elliptic.P256(), key.X, key.Y))
+	}
+	p := &WebPush{Client: hc, PrivateKey: key, PublicKey: publicKey, Subject: c.Subject, TTL: 24 * time.Hour, Urgency: c.Urgency, Now: time.Now}
+	if c.TTL != nil {
+		p.TTL = *c.TTL
+	}
+	return p, nil
+}
+
+// GenerateVapidKeys returns a new key pair, in the format of VapidConfig.
+func GenerateVapidKeys() (string, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	publicKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y)
+	privateKey := make([]byte, 32)
+	key.D.FillBytes(privateKey)
+	return base64.RawURLEncoding.EncodeToString(publicKey), base64.RawURLEncoding.EncodeToString(privateKey), nil
+}
+func ParseVapidPrivateKey(s string) (*ecdsa.PrivateKey, error) {
+	b, err := decodeBase64(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 32 {
+		return nil, errors.New("invalid VAPID private key")
+	}
+	curve := elliptic.P256()
+	key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(b)}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(b)
+	return key, nil
+}
+
+// Send encrypts the payload for the subscription and posts it to the push service.
+func (p *WebPush) Send(ctx context.Context, sub Subscription, payload []byte) error {
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+	token, err := p.Token(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.FormatInt(int64(p.TTL/time.Second), 10))
+	req.Header.Set("Authorization", "vapid t="+token+", k="+p.PublicKey)
+	if len(p.Urgency) > 0 {
+		req.Header.Set("Urgency", p.Urgency)
+	}
+	start := time.Now()
+	res, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone {
+		io.Copy(io.Discard, res.Body)
+		return ErrSubscriptionGone
+	}
+	if res.StatusCode >= http.StatusMultipleChoices {
+		rs, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return client.NewHttpError(res.StatusCode, nil, time.Since(start).Milliseconds(), fmt.Sprint("Response error with status code: ", res.StatusCode), sub.Endpoint, "", string(rs))
+	}
+	io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+// Token returns the VAPID JWT (ES256) for the origin of the endpoint, valid for 12 hours.
+func (p *WebPush) Token(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims := map[string]interface{}{"aud": u.Scheme + "://" + u.Host, "exp": p.Now().Add(12 * time.Hour).Unix()}
+	if len(p.Subject) > 0 {
+		claims["sub"] = p.Subject
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(c)
+	hash := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, p.PrivateKey, hash[:])
+	if err != nil {
+		return "", err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Encrypt encrypts the payload with the keys of the subscription, in a single aes128gcm record (RFC 8188, RFC 8291).
+func Encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	uaPublic, err := decodeBase64(sub.Keys.P256dh)
+	if err != nil {
+		return nil, err
+	}
+	authSecret, err := decodeBase64(sub.Keys.Auth)
+	if err != nil {
+		return nil, err
+	}
+	curve := elliptic.P256()
+	x, y := elliptic.Unmarshal(curve, uaPublic)
+	if x == nil {
+		return nil, errors.New("invalid p256dh key of the subscription")
+	}
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	asPrivate, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := elliptic.Marshal(curve, asPrivate.X, asPrivate.Y)
+	sx, _ := curve.ScalarMult(x, y, asPrivate.D.Bytes())
+	secret := make([]byte, 32)
+	sx.FillBytes(secret)
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm := hkdf(authSecret, secret, keyInfo, 32)
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plain := append(append(make([]byte, 0, len(payload)+1), payload...), 2)
+	recordSize := uint32(len(plain) + gcm.Overhead())
+	if recordSize < 4096 {
+		recordSize = 4096
+	}
+	header := make([]byte, 0, 21+len(asPublic))
+	header = append(header, salt...)
+	rs := make([]byte, 4)
+	binary.BigEndian.PutUint32(rs, recordSize)
+	header = append(header, rs...)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return gcm.Seal(header, nonce, plain, nil), nil
+}
+
+// hkdf is HKDF-SHA256 (RFC 5869) for an output of at most 32 bytes.
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:length]
+}
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// PushChannel sends the message to all the subscriptions of the user, as a JSON payload {title, body, url, data}. The subscriptions, which are gone, are deleted.
+type PushChannel struct {
+	WebPush       *WebPush
+	Subscriptions SubscriptionPort
+}
+
+func NewPushChannel(webPush *WebPush, subscriptions SubscriptionPort) *PushChannel {
+	return &PushChannel{WebPush: webPush, Subscriptions: subscriptions}
+}
+func (c *PushChannel) Name() string {
+	return Push
+}
+func (c *PushChannel) Send(ctx context.Context, to Contact, msg Message) error {
+	subs, err := c.Subscriptions.Load(ctx, to.Id)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return ErrNoAddress
+	}
+	payload, err := json.Marshal(map[string]interface{}{"title": msg.Title, "body": msg.Body, "url": msg.Url, "data": msg.Data})
+	if err != nil {
+		return err
+	}
+	sent := 0
+	var last error
+	for _, sub := range subs {
+		er1 := c.WebPush.Send(ctx, sub, payload)
+		if er1 == nil {
+			sent++
+		} else if errors.Is(er1, ErrSubscriptionGone) {
+			if _, er2 := c.Subscriptions.Delete(ctx, sub.UserId, sub.Endpoint); er2 != nil {
+				last = er2
+			}
+		} else {
+			last = er1
+		}
+	}
+	if sent > 0 {
+		return nil
+	}
+	if last != nil {
+		return last
+	}
+	return ErrNoAddress
+}
+
+// Decrypt decrypts a single record aes128gcm body with the private key and the auth secret of the subscription. It is the reverse of Encrypt, for the user agent side and the tests.
+func Decrypt(key *ecdsa.PrivateKey, authSecret []byte, body []byte) ([]byte, error) {
+	if len(body) < 21 {
+		return nil, errors.New("invalid aes128gcm body")
+	}
+	salt := body[:16]
+	idLen := int(body[20])
+	if len(body) < 21+idLen {
+		return nil, errors.New("invalid aes128gcm body")
+	}
+	asPublic := body[21 : 21+idLen]
+	curve := elliptic.P256()
+	x, y := elliptic.Unmarshal(curve, asPublic)
+	if x == nil {
+		return nil, errors.New("invalid key id of the aes128gcm body")
+	}
+	sx, _ := curve.ScalarMult(x, y, key.D.Bytes())
+	secret := make([]byte, 32)
+	sx.FillBytes(secret)
+	uaPublic := elliptic.Marshal(curve, key.X, key.Y)
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm := hkdf(authSecret, secret, keyInfo, 32)
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, nonce, body[21+idLen:], nil)
+	if err != nil {
+		return nil, err
+	}
+	i := bytes.LastIndexByte(plain, 2)
+	if i < 0 {
+		return nil, errors.New("invalid padding of the aes128gcm record")
+	}
+	return plain[:i], nil
+}
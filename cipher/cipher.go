@@ -14,8 +14,6 @@ import (
 	"strings"
 )
 
-const defaultKey = "xbmcZMpQoGiRXlTSbHSuYPXynluuNyYh"
-
 func Random() (string, error) {
 	var output strings.Builder
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -63,8 +61,9 @@ func Decrypt(key, text []byte) ([]byte, error) {
 	return data, nil
 }
 func Read(filePath string, outer map[string]string, field string, key string) error {
-	if key == "" {
-		key = defaultKey
+	k, err := getKey(key)
+	if err != nil {
+		return err
 	}
 	var s strings.Builder
 	in, err := os.ReadFile(filePath)
@@ -75,7 +74,7 @@ func Read(filePath string, outer map[string]string, field string, key string) er
 	if err != nil {
 		return err
 	}
-	plain, err := Decrypt([]byte(key), []byte(outer[field]))
+	plain, err := Decrypt(k, []byte(outer[field]))
 	if err != nil {
 		return err
 	}
@@ -84,11 +83,12 @@ func Read(filePath string, outer map[string]string, field string, key string) er
 	return err
 }
 func Write(filePath string, inter map[string]string, field string, key string) error {
-	if key == "" {
-		key = defaultKey
+	k, err := getKey(key)
+	if err != nil {
+		return err
 	}
 	var s strings.Builder
-	ciphered, err := Encrypt([]byte(key), []byte(inter[field]))
+	ciphered, err := Encrypt(k, []byte(inter[field]))
 	s.Write(ciphered)
 	if err != nil {
 		return err
@@ -101,3 +101,10 @@ func Write(filePath string, inter map[string]string, field string, key string) e
 	err = os.WriteFile(filePath, data, 0666)
 	return err
 }
+
+func getKey(key string) ([]byte, error) {
+	if key == "" {
+		return GetKey()
+	}
+	return []byte(key), nil
+}
@@ -0,0 +1,88 @@
+// Command cipher generates AES-GCM keys and encrypts or decrypts config values in the ENC(...) format.
+//
+//	cipher key
+//	cipher encrypt -key-file ./config.key "password"
+//	cipher decrypt -key-file ./config.key "ENC(...)"
+//
+// If -key and -key-file are not set, the key is read from the CONFIG_KEY or CONFIG_KEY_FILE environment variable.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/core-go/core/cipher"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd := os.Args[1]
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	key := fs.String("key", "", "AES key, in base64, hex or raw 16, 24 or 32 bytes")
+	keyFile := fs.String("key-file", "", "file of the AES key")
+	fs.Parse(os.Args[2:])
+	switch cmd {
+	case "key":
+		k, err := cipher.GenerateKey()
+		exitIfError(err)
+		fmt.Println(k)
+	case "encrypt", "decrypt":
+		k, err := getKey(*key, *keyFile)
+		exitIfError(err)
+		value, err := getValue(fs.Args())
+		exitIfError(err)
+		if cmd == "encrypt" {
+			s, err := cipher.EncryptString(k, value)
+			exitIfError(err)
+			fmt.Println("ENC(" + s + ")")
+		} else {
+			if strings.HasPrefix(value, "ENC(") && strings.HasSuffix(value, ")") {
+				value = value[4 : len(value)-1]
+			}
+			s, err := cipher.DecryptString(k, value)
+			exitIfError(err)
+			fmt.Println(s)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func getKey(key string, keyFile string) ([]byte, error) {
+	if len(key) > 0 {
+		return cipher.ParseKey(key)
+	}
+	if len(keyFile) > 0 {
+		return cipher.LoadKey(keyFile)
+	}
+	return cipher.GetKey()
+}
+
+// getValue reads the value from the arguments, or from the standard input, so that it is not kept in the shell history.
+func getValue(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	reader := bufio.NewReader(os.Stdin)
+	s, err := reader.ReadString('\n')
+	if err != nil && len(s) == 0 {
+		return "", err
+	}
+	return strings.TrimRight(s, "\r\n"), nil
+}
+func exitIfError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: cipher key | encrypt [-key key | -key-file file] [value] | decrypt [-key key | -key-file file] [value]")
+}
@@ -0,0 +1,132 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const KeyEnv = "CONFIG_KEY"
+const KeyFileEnv = "CONFIG_KEY_FILE"
+
+// GenerateKey returns a random AES-256 key, encoded in base64.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKey accepts a base64 or hex encoded key, or a raw key of 16, 24 or 32 bytes.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil && validKeySize(len(b)) {
+		return b, nil
+	}
+	if b, err := hex.DecodeString(s); err == nil && validKeySize(len(b)) {
+		return b, nil
+	}
+	if validKeySize(len(s)) {
+		return []byte(s), nil
+	}
+	return nil, fmt.Errorf("invalid key: AES key must be 16, 24 or 32 bytes")
+}
+func validKeySize(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
+func LoadKey(filePath string) ([]byte, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(string(b))
+}
+
+// GetKey gets the key from the CONFIG_KEY environment variable, or from the file of the CONFIG_KEY_FILE environment variable.
+func GetKey() ([]byte, error) {
+	if s := os.Getenv(KeyEnv); len(s) > 0 {
+		return ParseKey(s)
+	}
+	if f := os.Getenv(KeyFileEnv); len(f) > 0 {
+		return LoadKey(f)
+	}
+	return nil, errors.New("key is required: set " + KeyEnv + " or " + KeyFileEnv)
+}
+
+func EncryptGCM(key []byte, plain []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+func DecryptGCM(key []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	size := gcm.NonceSize()
+	if len(data) < size {
+		return nil, errors.New("cipher text too short")
+	}
+	return gcm.Open(nil, data[:size], data[size:], nil)
+}
+func EncryptString(key []byte, text string) (string, error) {
+	b, err := EncryptGCM(key, []byte(text))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+func DecryptString(key []byte, text string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
+	plain, err := DecryptGCM(key, b)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+type GCMDecrypter struct {
+	Key []byte
+}
+
+func NewGCMDecrypter(key []byte) *GCMDecrypter {
+	return &GCMDecrypter{Key: key}
+}
+func NewGCMDecrypterFromFile(filePath string) (*GCMDecrypter, error) {
+	key, err := LoadKey(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &GCMDecrypter{Key: key}, nil
+}
+func (d *GCMDecrypter) Decrypt(cipherText string) (string, error) {
+	return DecryptString(d.Key, cipherText)
+}
+func (d *GCMDecrypter) Encrypt(text string) (string, error) {
+	return EncryptString(d.Key, text)
+}
@@ -40,23 +40,28 @@ func LoadConfig(envName string, c interface{}, fileNames ...string) error {
 
 // LoadConfigWithEnv function will read config from environment or config file.
 func LoadConfigWithEnv(parentPath string, directory string, env string, c interface{}, fileNames ...string) error {
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
-	viper.SetConfigType("yaml")
+	return LoadWithViper(viper.GetViper(), parentPath, directory, env, c, fileNames...)
+}
+
+// LoadWithViper function will read config from environment or config file, using the given viper instance.
+func LoadWithViper(vp *viper.Viper, parentPath string, directory string, env string, c interface{}, fileNames ...string) error {
+	vp.AutomaticEnv()
+	vp.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
+	vp.SetConfigType("yaml")
 
 	fileCount := len(fileNames)
 	if fileCount > 0 {
 		if len(parentPath) == 0 && len(directory) == 0 {
-			viper.AddConfigPath("./")
+			vp.AddConfigPath("./")
 		} else {
-			viper.AddConfigPath("./" + directory + "/")
+			vp.AddConfigPath("./" + directory + "/")
 			if len(parentPath) > 0 {
-				viper.AddConfigPath("./" + parentPath + "/" + directory + "/")
+				vp.AddConfigPath("./" + parentPath + "/" + directory + "/")
 			}
 		}
 
-		viper.SetConfigName(fileNames[0])
-		if er1 := viper.ReadInConfig(); er1 != nil {
+		vp.SetConfigName(fileNames[0])
+		if er1 := vp.ReadInConfig(); er1 != nil {
 			switch er1.(type) {
 			case viper.ConfigFileNotFoundError:
 				log.Println("config file not found")
@@ -66,8 +71,8 @@ func LoadConfigWithEnv(parentPath string, directory string, env string, c interf
 		}
 
 		for i := 1; i < fileCount; i++ {
-			viper.SetConfigName(fileNames[i])
-			if er2b := viper.MergeInConfig(); er2b != nil {
+			vp.SetConfigName(fileNames[i])
+			if er2b := vp.MergeInConfig(); er2b != nil {
 				switch er2b.(type) {
 				case viper.ConfigFileNotFoundError:
 					break
@@ -84,8 +89,8 @@ func LoadConfigWithEnv(parentPath string, directory string, env string, c interf
 		env2 := strings.ToLower(env)
 		for _, fileName2 := range fileNames {
 			name0 := fileName2 + "." + env2
-			viper.SetConfigName(name0)
-			er2a := viper.MergeInConfig()
+			vp.SetConfigName(name0)
+			er2a := vp.MergeInConfig()
 			if er2a != nil {
 				switch er2a.(type) {
 				case viper.ConfigFileNotFoundError:
@@ -95,8 +100,8 @@ func LoadConfigWithEnv(parentPath string, directory string, env string, c interf
 				}
 			}
 			name1 := fileName2 + "-" + env2
-			viper.SetConfigName(name1)
-			er2b := viper.MergeInConfig()
+			vp.SetConfigName(name1)
+			er2b := vp.MergeInConfig()
 			if er2b != nil {
 				switch er2b.(type) {
 				case viper.ConfigFileNotFoundError:
@@ -107,16 +112,22 @@ func LoadConfigWithEnv(parentPath string, directory string, env string, c interf
 			}
 		}
 	}
-	er3 := BindEnvs(c)
+	er3 := BindEnvsWithViper(vp, c)
 	if er3 != nil {
 		return er3
 	}
-	er4 := viper.Unmarshal(c)
-	return er4
+	er4 := vp.Unmarshal(c)
+	if er4 != nil {
+		return er4
+	}
+	return Resolve(c)
 }
 
 // BindEnvs function will bind ymal file to struc model
 func BindEnvs(conf interface{}, parts ...string) error {
+	return BindEnvsWithViper(viper.GetViper(), conf, parts...)
+}
+func BindEnvsWithViper(vp *viper.Viper, conf interface{}, parts ...string) error {
 	ifv := reflect.Indirect(reflect.ValueOf(conf))
 	ift := ifv.Type()
 	num := min(ift.NumField(), ifv.NumField())
@@ -129,12 +140,12 @@ func BindEnvs(conf interface{}, parts ...string) error {
 		}
 		switch v.Kind() {
 		case reflect.Struct:
-			err := BindEnvs(v.Interface(), append(parts, tv)...)
+			err := BindEnvsWithViper(vp, v.Interface(), append(parts, tv)...)
 			if err != nil {
 				return err
 			}
 		default:
-			err := viper.BindEnv(strings.Join(append(parts, tv), "."))
+			err := vp.BindEnv(strings.Join(append(parts, tv), "."))
 			if err != nil {
 				return err
 			}
@@ -205,7 +216,11 @@ func LoadMapWithPath(parentPath string, directory string, env string, fileNames
 		}
 	}
 	er3 := viper.Unmarshal(&innerMap)
-	return innerMap, er3
+	if er3 != nil {
+		return innerMap, er3
+	}
+	er4 := Resolve(innerMap)
+	return innerMap, er4
 }
 func LoadMapWithEnv(env string, fileNames ...string) (map[string]string, error) {
 	return LoadMapWithPath("", "", env, fileNames...)
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/core-go/core/cipher"
+)
+
+const (
+	EncryptedPrefix = "ENC("
+	EncryptedSuffix = ")"
+	SecretPrefix    = "${secret:"
+	SecretSuffix    = "}"
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+type Decrypter interface {
+	Decrypt(cipherText string) (string, error)
+}
+
+type SecretProvider interface {
+	GetSecret(name string) (string, error)
+}
+
+// EnvProvider reads a secret from an environment variable. The name is upper cased, and "-", "." and "/" are replaced by "_".
+type EnvProvider struct {
+	Prefix string
+}
+
+func NewEnvProvider(opts ...string) *EnvProvider {
+	var prefix string
+	if len(opts) > 0 {
+		prefix = opts[0]
+	}
+	return &EnvProvider{Prefix: prefix}
+}
+func (p *EnvProvider) GetSecret(name string) (string, error) {
+	key := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_", "/", "_").Replace(p.Prefix + name))
+	if v, ok := os.LookupEnv(key); ok {
+		return v, nil
+	}
+	return "", ErrSecretNotFound
+}
+
+// FileProvider reads a secret from a file of a mounted directory, such as /run/secrets of docker or a kubernetes secret volume.
+type FileProvider struct {
+	Directory string
+}
+
+func NewFileProvider(opts ...string) *FileProvider {
+	directory := "/run/secrets"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		directory = opts[0]
+	}
+	return &FileProvider{Directory: directory}
+}
+func (p *FileProvider) GetSecret(name string) (string, error) {
+	if strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid secret name '%s'", name)
+	}
+	b, err := os.ReadFile(filepath.Join(p.Directory, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrSecretNotFound
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+type Resolver struct {
+	Decrypter Decrypter
+	Providers []SecretProvider
+}
+
+func NewResolver(decrypter Decrypter, providers ...SecretProvider) *Resolver {
+	return &Resolver{Decrypter: decrypter, Providers: providers}
+}
+
+var resolver *Resolver
+
+// SetResolver sets the resolver used by Load, LoadConfig and LoadConfigWithEnv.
+// If it is not set, ${secret:name} references are resolved from the environment variables and /run/secrets,
+// and ENC(...) values are decrypted with the AES-GCM key of the CONFIG_KEY or CONFIG_KEY_FILE environment variable.
+func SetResolver(r *Resolver) {
+	resolver = r
+}
+func getResolver() *Resolver {
+	if resolver != nil {
+		return resolver
+	}
+	r := &Resolver{Providers: []SecretProvider{NewEnvProvider(), NewFileProvider()}}
+	if key, err := cipher.GetKey(); err == nil {
+		r.Decrypter = cipher.NewGCMDecrypter(key)
+	}
+	return r
+}
+
+func (r *Resolver) GetSecret(name string) (string, error) {
+	for _, p := range r.Providers {
+		v, err := p.GetSecret(name)
+		if err == nil {
+			return v, nil
+		}
+		if err != ErrSecretNotFound {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("secret '%s' not found", name)
+}
+
+// ResolveString decrypts ENC(...) values and replaces ${secret:name} references in a string.
+// The values of the secrets are not resolved again, so a secret, which contains a reference, is kept as it is.
+func (r *Resolver) ResolveString(s string) (string, error) {
+	if strings.HasPrefix(s, EncryptedPrefix) && strings.HasSuffix(s, EncryptedSuffix) {
+		if r.Decrypter == nil {
+			return "", errors.New("decrypter is required to decrypt ENC(...) values")
+		}
+		return r.Decrypter.Decrypt(s[len(EncryptedPrefix) : len(s)-len(EncryptedSuffix)])
+	}
+	var sb strings.Builder
+	for {
+		i := strings.Index(s, SecretPrefix)
+		if i < 0 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		j := strings.Index(s[i:], SecretSuffix)
+		if j < 0 {
+			return "", fmt.Errorf("invalid secret reference '%s'", s[i:])
+		}
+		name := s[i+len(SecretPrefix) : i+j]
+		v, err := r.GetSecret(name)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(s[:i])
+		sb.WriteString(v)
+		s = s[i+j+len(SecretSuffix):]
+	}
+}
+
+// Resolve walks through all string fields, pointers, slices, maps and nested structs of c and resolves the values.
+func (r *Resolver) Resolve(c interface{}) error {
+	return r.resolve(reflect.ValueOf(c), "")
+}
+func (r *Resolver) resolve(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface && v.Elem().Kind() == reflect.String {
+			s, err := r.resolveField(v.Elem().String(), path)
+			if err != nil {
+				return err
+			}
+			if v.CanSet() {
+				v.Set(reflect.ValueOf(s))
+			}
+			return nil
+		}
+		return r.resolve(v.Elem(), path)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			if err := r.resolve(v.Field(i), join(path, t.Field(i).Name)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := r.resolve(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			e := v.MapIndex(k)
+			p := fmt.Sprintf("%s[%v]", path, k.Interface())
+			if e.Kind() == reflect.String || (e.Kind() == reflect.Interface && !e.IsNil() && e.Elem().Kind() == reflect.String) {
+				var s string
+				if e.Kind() == reflect.String {
+					s = e.String()
+				} else {
+					s = e.Elem().String()
+				}
+				resolved, err := r.resolveField(s, p)
+				if err != nil {
+					return err
+				}
+				if resolved != s {
+					v.SetMapIndex(k, reflect.ValueOf(resolved).Convert(e.Type()))
+				}
+				continue
+			}
+			if e.Kind() == reflect.Ptr || e.Kind() == reflect.Map || e.Kind() == reflect.Slice {
+				if err := r.resolve(e, p); err != nil {
+					return err
+				}
+				continue
+			}
+			if e.Kind() == reflect.Struct || e.Kind() == reflect.Interface {
+				copied := reflect.New(e.Type()).Elem()
+				copied.Set(e)
+				if err := r.resolve(copied, p); err != nil {
+					return err
+				}
+				v.SetMapIndex(k, copied)
+			}
+		}
+	case reflect.String:
+		s, err := r.resolveField(v.String(), path)
+		if err != nil {
+			return err
+		}
+		if v.CanSet() && s != v.String() {
+			v.SetString(s)
+		}
+	}
+	return nil
+}
+func (r *Resolver) resolveField(s string, path string) (string, error) {
+	resolved, err := r.ResolveString(s)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve '%s': %w", path, err)
+	}
+	return resolved, nil
+}
+func join(path string, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+func Resolve(c interface{}) error {
+	return getResolver().Resolve(c)
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type fileState struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// Watcher loads a config into a typed snapshot, polls the config files for changes,
+// and on change re-binds, validates and atomically swaps the snapshot, then calls the subscribers.
+type Watcher[T any] struct {
+	ParentPath  string
+	Directory   string
+	Env         string
+	FileNames   []string
+	Interval    time.Duration
+	Validate    func(c *T) error
+	LogError    func(context.Context, string, ...map[string]interface{})
+	value       atomic.Value
+	mu          sync.Mutex
+	subscribers []func(before *T, after *T)
+	files       map[string]fileState
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+func NewWatcher[T any](env string, validate func(*T) error, interval time.Duration, fileNames ...string) *Watcher[T] {
+	return NewWatcherWithPath[T]("", "", env, validate, interval, fileNames...)
+}
+func NewWatcherWithPath[T any](parentPath string, directory string, env string, validate func(*T) error, interval time.Duration, fileNames ...string) *Watcher[T] {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &Watcher[T]{ParentPath: parentPath, Directory: directory, Env: env, FileNames: fileNames, Interval: interval, Validate: validate}
+}
+
+// Get returns the current snapshot. It must not be modified by the caller.
+func (w *Watcher[T]) Get() *T {
+	v, _ := w.value.Load().(*T)
+	return v
+}
+
+// Subscribe registers a callback, called after each successful reload with the previous and the new snapshot.
+func (w *Watcher[T]) Subscribe(f func(before *T, after *T)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, f)
+}
+
+// Load loads the config the first time. It does not call the subscribers.
+func (w *Watcher[T]) Load() (*T, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	files := w.stat()
+	c, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	w.value.Store(c)
+	return c, nil
+}
+func (w *Watcher[T]) load() (*T, error) {
+	c := new(T)
+	if err := LoadWithViper(viper.New(), w.ParentPath, w.Directory, w.Env, c, w.FileNames...); err != nil {
+		return nil, err
+	}
+	if w.Validate != nil {
+		if err := w.Validate(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Reload loads the config again. If loading or validation fails, the current snapshot is kept and the error is returned.
+// It returns false if the new config is the same as the current one.
+// The subscribers are called after the lock is released, so they may call Get, Subscribe or Reload.
+func (w *Watcher[T]) Reload() (bool, error) {
+	w.mu.Lock()
+	// the files are kept unchanged if loading fails, so that the next poll tries again
+	files := w.stat()
+	after, err := w.load()
+	if err != nil {
+		w.mu.Unlock()
+		return false, err
+	}
+	w.files = files
+	before := w.Get()
+	if before != nil && reflect.DeepEqual(before, after) {
+		w.mu.Unlock()
+		return false, nil
+	}
+	w.value.Store(after)
+	subscribers := make([]func(before *T, after *T), len(w.subscribers))
+	copy(subscribers, w.subscribers)
+	w.mu.Unlock()
+	for _, f := range subscribers {
+		f(before, after)
+	}
+	return true, nil
+}
+
+// Start polls the config files every interval, until Stop is called or the context is done.
+func (w *Watcher[T]) Start(ctx context.Context) error {
+	if w.Get() == nil {
+		if _, err := w.Load(); err != nil {
+			return err
+		}
+	}
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.mu.Unlock()
+		return errors.New("watcher is already started")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	w.mu.Unlock()
+	go w.run(ctx)
+	return nil
+}
+func (w *Watcher[T]) Stop() {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel = nil
+	w.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+func (w *Watcher[T]) run(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !w.changed() {
+				continue
+			}
+			if _, err := w.Reload(); err != nil && w.LogError != nil {
+				w.LogError(ctx, "cannot reload config: "+err.Error())
+			}
+		}
+	}
+}
+func (w *Watcher[T]) changed() bool {
+	files := w.stat()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !reflect.DeepEqual(files, w.files)
+}
+
+// stat gets the modification time and the size of all files that LoadWithViper can read, so that added and removed files are detected too.
+func (w *Watcher[T]) stat() map[string]fileState {
+	var dirs []string
+	if len(w.ParentPath) == 0 && len(w.Directory) == 0 {
+		dirs = []string{"./"}
+	} else {
+		dirs = []string{"./" + w.Directory + "/"}
+		if len(w.ParentPath) > 0 {
+			dirs = append(dirs, "./"+w.ParentPath+"/"+w.Directory+"/")
+		}
+	}
+	names := make([]string, 0)
+	for _, n := range w.FileNames {
+		names = append(names, n)
+		if len(w.Env) > 0 {
+			env := strings.ToLower(w.Env)
+			names = append(names, n+"."+env, n+"-"+env)
+		}
+	}
+	files := make(map[string]fileState)
+	for _, d := range dirs {
+		for _, n := range names {
+			for _, ext := range viper.SupportedExts {
+				p := filepath.Join(d, n+"."+ext)
+				if info, err := os.Stat(p); err == nil && !info.IsDir() {
+					files[p] = fileState{ModTime: info.ModTime(), Size: info.Size()}
+				}
+			}
+		}
+	}
+	return files
+}
+
+// Select makes a subscriber of a part of the config, which is called only if this part changes.
+func Select[T any, S any](get func(c *T) S, f func(before S, after S)) func(before *T, after *T) {
+	return func(before *T, after *T) {
+		var b S
+		if before != nil {
+			b = get(before)
+		}
+		a := get(after)
+		if !reflect.DeepEqual(b, a) {
+			f(b, a)
+		}
+	}
+}
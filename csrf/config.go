@@ -0,0 +1,26 @@
+package csrf
+
+import "time"
+
+const (
+	DoubleSubmit = "double_submit"
+	Synchronizer = "synchronizer"
+)
+
+type Config struct {
+	Mode     string         `yaml:"mode" mapstructure:"mode" json:"mode,omitempty" gorm:"column:mode" bson:"mode,omitempty" dynamodbav:"mode,omitempty" firestore:"mode,omitempty"`
+	Secret   string         `yaml:"secret" mapstructure:"secret" json:"secret,omitempty" gorm:"column:secret" bson:"secret,omitempty" dynamodbav:"secret,omitempty" firestore:"secret,omitempty"`
+	Cookie   string         `yaml:"cookie" mapstructure:"cookie" json:"cookie,omitempty" gorm:"column:cookie" bson:"cookie,omitempty" dynamodbav:"cookie,omitempty" firestore:"cookie,omitempty"`
+	Header   string         `yaml:"header" mapstructure:"header" json:"header,omitempty" gorm:"column:header" bson:"header,omitempty" dynamodbav:"header,omitempty" firestore:"header,omitempty"`
+	Field    string         `yaml:"field" mapstructure:"field" json:"field,omitempty" gorm:"column:field" bson:"field,omitempty" dynamodbav:"field,omitempty" firestore:"field,omitempty"`
+	Session  string         `yaml:"session" mapstructure:"session" json:"session,omitempty" gorm:"column:session" bson:"session,omitempty" dynamodbav:"session,omitempty" firestore:"session,omitempty"`
+	Prefix   string         `yaml:"prefix" mapstructure:"prefix" json:"prefix,omitempty" gorm:"column:prefix" bson:"prefix,omitempty" dynamodbav:"prefix,omitempty" firestore:"prefix,omitempty"`
+	Domain   string         `yaml:"domain" mapstructure:"domain" json:"domain,omitempty" gorm:"column:domain" bson:"domain,omitempty" dynamodbav:"domain,omitempty" firestore:"domain,omitempty"`
+	Path     string         `yaml:"path" mapstructure:"path" json:"path,omitempty" gorm:"column:path" bson:"path,omitempty" dynamodbav:"path,omitempty" firestore:"path,omitempty"`
+	Expires  *time.Duration `yaml:"expires" mapstructure:"expires" json:"expires,omitempty" gorm:"column:expires" bson:"expires,omitempty" dynamodbav:"expires,omitempty" firestore:"expires,omitempty"`
+	Insecure bool           `yaml:"insecure" mapstructure:"insecure" json:"insecure,omitempty" gorm:"column:insecure" bson:"insecure,omitempty" dynamodbav:"insecure,omitempty" firestore:"insecure,omitempty"`
+	SameSite string         `yaml:"same_site" mapstructure:"same_site" json:"sameSite,omitempty" gorm:"column:samesite" bson:"sameSite,omitempty" dynamodbav:"sameSite,omitempty" firestore:"sameSite,omitempty"`
+	Origins  string         `yaml:"origins" mapstructure:"origins" json:"origins,omitempty" gorm:"column:origins" bson:"origins,omitempty" dynamodbav:"origins,omitempty" firestore:"origins,omitempty"`
+	Referer  bool           `yaml:"referer" mapstructure:"referer" json:"referer,omitempty" gorm:"column:referer" bson:"referer,omitempty" dynamodbav:"referer,omitempty" firestore:"referer,omitempty"`
+	Exempts  []string       `yaml:"exempts" mapstructure:"exempts" json:"exempts,omitempty" gorm:"column:exempts" bson:"exempts,omitempty" dynamodbav:"exempts,omitempty" firestore:"exempts,omitempty"`
+}
@@ -0,0 +1,367 @@
+package csrf
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingToken   = errors.New("csrf token is required")
+	ErrInvalidToken   = errors.New("invalid csrf token")
+	ErrMissingSession = errors.New("session is required to verify csrf token")
+	ErrInvalidOrigin  = errors.New("origin is not allowed")
+	ErrMissingReferer = errors.New("origin or referer is required")
+)
+
+type Store interface {
+	Put(ctx context.Context, key string, token string, timeToLive time.Duration) error
+	Get(ctx context.Context, key string) (string, error)
+	Remove(ctx context.Context, key string) (bool, error)
+}
+
+type exempt struct {
+	Method string
+	Path   string
+	Prefix bool
+}
+
+type Protector struct {
+	Config
+	Store        Store
+	GetSessionId func(r *http.Request) string
+	origins      []string
+	exempts      []exempt
+	expires      time.Duration
+	sameSite     http.SameSite
+}
+
+func NewProtector(c Config, store Store, opts ...func(*http.Request) string) *Protector {
+	if len(c.Mode) == 0 {
+		c.Mode = DoubleSubmit
+	}
+	if len(c.Cookie) == 0 {
+		c.Cookie = "csrf_token"
+	}
+	if len(c.Header) == 0 {
+		c.Header = "X-CSRF-Token"
+	}
+	if len(c.Field) == 0 {
+		c.Field = "csrf_token"
+	}
+	if len(c.Session) == 0 {
+		c.Session = "token"
+	}
+	if len(c.Prefix) == 0 {
+		c.Prefix = "csrf:"
+	}
+	if len(c.Path) == 0 {
+		c.Path = "/"
+	}
+	expires := 24 * time.Hour
+	if c.Expires != nil && *c.Expires > 0 {
+		expires = *c.Expires
+	}
+	p := &Protector{Config: c, Store: store, expires: expires, sameSite: ToSameSite(c.SameSite)}
+	if len(opts) > 0 && opts[0] != nil {
+		p.GetSessionId = opts[0]
+	} else {
+		p.GetSessionId = p.getSessionFromCookie
+	}
+	if len(c.Origins) > 0 {
+		origins := strings.Split(c.Origins, ",")
+		for _, o := range origins {
+			o = strings.TrimRight(strings.TrimSpace(o), "/")
+			if len(o) > 0 {
+				p.origins = append(p.origins, strings.ToLower(o))
+			}
+		}
+	}
+	for _, s := range c.Exempts {
+		p.Exempt(s)
+	}
+	return p
+}
+
+// Exempt skips verification for a route, in the form "/path", "/prefix/*" or "POST /path".
+func (p *Protector) Exempt(route string) *Protector {
+	route = strings.TrimSpace(route)
+	if len(route) == 0 {
+		return p
+	}
+	e := exempt{}
+	if i := strings.Index(route, " "); i > 0 {
+		e.Method = strings.ToUpper(route[:i])
+		route = strings.TrimSpace(route[i+1:])
+	}
+	if strings.HasSuffix(route, "*") {
+		e.Prefix = true
+		route = route[:len(route)-1]
+	}
+	e.Path = route
+	p.exempts = append(p.exempts, e)
+	return p
+}
+func (p *Protector) IsExempt(method string, path string) bool {
+	for _, e := range p.exempts {
+		if len(e.Method) > 0 && e.Method != method {
+			continue
+		}
+		if e.Prefix {
+			if strings.HasPrefix(path, e.Path) {
+				return true
+			}
+		} else if e.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Protector) Generate(sessionId string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if len(p.Secret) > 0 {
+		token = token + "." + p.sign(sessionId, token)
+	}
+	return token, nil
+}
+func (p *Protector) sign(sessionId string, value string) string {
+	mac := hmac.New(sha256.New, []byte(p.Secret))
+	mac.Write([]byte(sessionId))
+	mac.Write([]byte("!"))
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+func (p *Protector) checkSignature(sessionId string, token string) bool {
+	if len(p.Secret) == 0 {
+		return true
+	}
+	i := strings.LastIndex(token, ".")
+	if i <= 0 {
+		return false
+	}
+	return hmac.Equal([]byte(token[i+1:]), []byte(p.sign(sessionId, token[:i])))
+}
+
+// Issue returns the current token of the request, creating and storing a new one if there is no valid token yet.
+func (p *Protector) Issue(w http.ResponseWriter, r *http.Request) (string, error) {
+	sessionId := p.GetSessionId(r)
+	if p.Mode == Synchronizer {
+		if len(sessionId) == 0 {
+			return "", ErrMissingSession
+		}
+		token, err := p.Store.Get(r.Context(), p.Prefix+sessionId)
+		if err != nil {
+			return "", err
+		}
+		if len(token) > 0 {
+			w.Header().Set(p.Header, token)
+			return token, nil
+		}
+	} else {
+		cookie, err := r.Cookie(p.Cookie)
+		if err == nil && cookie != nil && len(cookie.Value) > 0 && p.checkSignature(sessionId, cookie.Value) {
+			w.Header().Set(p.Header, cookie.Value)
+			return cookie.Value, nil
+		}
+	}
+	return p.Rotate(r.Context(), w, sessionId)
+}
+
+// Rotate always creates a new token for the session. It must be called after login and after a privilege change, with the new session id;
+// Handler.SignIn wraps the success handler of a sign in to call it.
+func (p *Protector) Rotate(ctx context.Context, w http.ResponseWriter, sessionId string) (string, error) {
+	token, err := p.Generate(sessionId)
+	if err != nil {
+		return "", err
+	}
+	if p.Mode == Synchronizer {
+		if len(sessionId) == 0 {
+			return "", ErrMissingSession
+		}
+		if err = p.Store.Put(ctx, p.Prefix+sessionId, token, p.expires); err != nil {
+			return "", err
+		}
+	} else {
+		p.setCookie(w, token, p.expires)
+	}
+	w.Header().Set(p.Header, token)
+	return token, nil
+}
+
+// RotateSignIn rotates the token after a sign in, with the session id of the cookies, which the response sets, or of the request.
+// It must be called before the response is written; Handler.SignIn and the SignIn of the gin and echo handlers call it.
+func (p *Protector) RotateSignIn(w http.ResponseWriter, r *http.Request) (string, error) {
+	// the new session id is in the cookies of the response, if the sign in has just created the session
+	req := r.Clone(r.Context())
+	req.Header.Del("Cookie")
+	cookies := (&http.Response{Header: w.Header()}).Cookies()
+	for _, c := range r.Cookies() {
+		if !hasCookie(cookies, c.Name) {
+			req.AddCookie(c)
+		}
+	}
+	for _, c := range cookies {
+		if len(c.Value) > 0 && c.MaxAge >= 0 {
+			req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+	return p.Rotate(r.Context(), w, p.GetSessionId(req))
+}
+func hasCookie(cookies []*http.Cookie, name string) bool {
+	for _, c := range cookies {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Clear removes the token of the session, it should be called on logout.
+func (p *Protector) Clear(ctx context.Context, w http.ResponseWriter, sessionId string) error {
+	if p.Mode == Synchronizer {
+		if len(sessionId) == 0 {
+			return nil
+		}
+		_, err := p.Store.Remove(ctx, p.Prefix+sessionId)
+		return err
+	}
+	p.setCookie(w, "", -1)
+	return nil
+}
+func (p *Protector) setCookie(w http.ResponseWriter, token string, expires time.Duration) {
+	cookie := &http.Cookie{
+		Name:     p.Cookie,
+		Domain:   p.Domain,
+		Value:    token,
+		Path:     p.Path,
+		HttpOnly: false,
+		SameSite: p.sameSite,
+		Secure:   !p.Insecure,
+	}
+	if expires < 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = time.Now().Add(expires)
+	}
+	http.SetCookie(w, cookie)
+}
+
+// Verify checks the origin and the csrf token of an unsafe request. It returns the http status and the error if the request is rejected.
+func (p *Protector) Verify(r *http.Request) (int, error) {
+	if IsSafeMethod(r.Method) || p.IsExempt(r.Method, r.URL.Path) {
+		return http.StatusOK, nil
+	}
+	if err := p.CheckOrigin(r); err != nil {
+		return http.StatusForbidden, err
+	}
+	token := p.GetRequestToken(r)
+	if len(token) == 0 {
+		return http.StatusForbidden, ErrMissingToken
+	}
+	sessionId := p.GetSessionId(r)
+	if p.Mode == Synchronizer {
+		if len(sessionId) == 0 {
+			return http.StatusForbidden, ErrMissingSession
+		}
+		expected, err := p.Store.Get(r.Context(), p.Prefix+sessionId)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if len(expected) == 0 || subtle.ConstantTimeCompare([]byte(expected), []byte(token)) != 1 {
+			return http.StatusForbidden, ErrInvalidToken
+		}
+		return http.StatusOK, nil
+	}
+	cookie, err := r.Cookie(p.Cookie)
+	if err != nil || cookie == nil || len(cookie.Value) == 0 {
+		return http.StatusForbidden, ErrMissingToken
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(token)) != 1 || !p.checkSignature(sessionId, token) {
+		return http.StatusForbidden, ErrInvalidToken
+	}
+	return http.StatusOK, nil
+}
+func (p *Protector) GetRequestToken(r *http.Request) string {
+	token := r.Header.Get(p.Header)
+	if len(token) > 0 {
+		return token
+	}
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") || strings.HasPrefix(contentType, "multipart/form-data") {
+		return r.PostFormValue(p.Field)
+	}
+	return ""
+}
+
+// CheckOrigin compares the Origin header, or the Referer header if there is no Origin, against the allowed origins.
+// If there are no allowed origins, the request must come from the same host.
+func (p *Protector) CheckOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "null" {
+		return ErrInvalidOrigin
+	}
+	if len(origin) == 0 {
+		referer := r.Header.Get("Referer")
+		if len(referer) == 0 {
+			if p.Referer {
+				return ErrMissingReferer
+			}
+			return nil
+		}
+		u, err := url.Parse(referer)
+		if err != nil || len(u.Host) == 0 {
+			return ErrInvalidOrigin
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+	origin = strings.ToLower(strings.TrimRight(origin, "/"))
+	if len(p.origins) == 0 {
+		u, err := url.Parse(origin)
+		if err != nil || !strings.EqualFold(u.Host, r.Host) {
+			return ErrInvalidOrigin
+		}
+		return nil
+	}
+	for _, o := range p.origins {
+		if o == origin {
+			return nil
+		}
+	}
+	return ErrInvalidOrigin
+}
+func (p *Protector) getSessionFromCookie(r *http.Request) string {
+	cookie, err := r.Cookie(p.Session)
+	if err != nil || cookie == nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+func IsSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || method == http.MethodTrace
+}
+func ToSameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	case "default":
+		return http.SameSiteDefaultMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
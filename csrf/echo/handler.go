@@ -0,0 +1,99 @@
+package echo
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/core-go/core/csrf"
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	Protector *csrf.Protector
+	LogError  func(context.Context, string, ...map[string]interface{})
+	Token     string
+}
+
+func NewHandler(protector *csrf.Protector, logError func(context.Context, string, ...map[string]interface{}), opts ...string) *Handler {
+	token := "token"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		token = opts[0]
+	}
+	return &Handler{Protector: protector, LogError: logError, Token: token}
+}
+
+func (h *Handler) Handle() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			r := ctx.Request()
+			if csrf.IsSafeMethod(r.Method) {
+				if h.Protector.Mode != csrf.Synchronizer || len(h.Protector.GetSessionId(r)) > 0 {
+					if _, err := h.Protector.Issue(ctx.Response(), r); err != nil && h.LogError != nil {
+						h.LogError(r.Context(), err.Error())
+					}
+				}
+				return next(ctx)
+			}
+			status, err := h.Protector.Verify(r)
+			if err != nil {
+				if status == http.StatusInternalServerError {
+					if h.LogError != nil {
+						h.LogError(r.Context(), err.Error())
+					}
+					return ctx.JSON(status, "Internal Server Error")
+				}
+				return ctx.JSON(status, err.Error())
+			}
+			return next(ctx)
+		}
+	}
+}
+func (h *Handler) GetToken(ctx echo.Context) error {
+	r := ctx.Request()
+	token, err := h.Protector.Issue(ctx.Response(), r)
+	if err != nil {
+		if err == csrf.ErrMissingSession {
+			return ctx.JSON(http.StatusUnauthorized, err.Error())
+		}
+		if h.LogError != nil {
+			h.LogError(r.Context(), err.Error())
+		}
+		return ctx.JSON(http.StatusInternalServerError, "Internal Server Error")
+	}
+	m := make(map[string]interface{})
+	m[h.Token] = token
+	return ctx.JSON(http.StatusOK, m)
+}
+
+// SignIn wraps the handler of a sign in, to rotate the token once the user is authenticated,
+// so a token, which was issued before the sign in, is not valid for the new session. It should also wrap the handlers, which change the privileges of a session.
+// The token is rotated before a successful response is written, with the session id of the cookies, which the handler sets, or of the request.
+func (h *Handler) SignIn(success echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		res := ctx.Response()
+		rotated := false
+		rotate := func() {
+			if rotated {
+				return
+			}
+			rotated = true
+			if res.Status < 300 {
+				r := ctx.Request()
+				if _, err := h.Protector.RotateSignIn(res.Writer, r); err != nil && h.LogError != nil {
+					h.LogError(r.Context(), err.Error())
+				}
+			}
+		}
+		res.Before(rotate)
+		var err error
+		if success != nil {
+			err = success(ctx)
+		} else {
+			err = ctx.JSON(http.StatusOK, true)
+		}
+		if err == nil && !res.Committed {
+			rotate()
+		}
+		return err
+	}
+}
@@ -0,0 +1,126 @@
+package gin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/core-go/core/csrf"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	Protector *csrf.Protector
+	LogError  func(context.Context, string, ...map[string]interface{})
+	Token     string
+}
+
+func NewHandler(protector *csrf.Protector, logError func(context.Context, string, ...map[string]interface{}), opts ...string) *Handler {
+	token := "token"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		token = opts[0]
+	}
+	return &Handler{Protector: protector, LogError: logError, Token: token}
+}
+
+func (h *Handler) Handle() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		r := ctx.Request
+		if csrf.IsSafeMethod(r.Method) {
+			if h.Protector.Mode != csrf.Synchronizer || len(h.Protector.GetSessionId(r)) > 0 {
+				if _, err := h.Protector.Issue(ctx.Writer, r); err != nil && h.LogError != nil {
+					h.LogError(r.Context(), err.Error())
+				}
+			}
+			ctx.Next()
+			return
+		}
+		status, err := h.Protector.Verify(r)
+		if err != nil {
+			if status == http.StatusInternalServerError {
+				if h.LogError != nil {
+					h.LogError(r.Context(), err.Error())
+				}
+				ctx.AbortWithStatusJSON(status, "Internal Server Error")
+			} else {
+				ctx.AbortWithStatusJSON(status, err.Error())
+			}
+			return
+		}
+		ctx.Next()
+	}
+}
+func (h *Handler) GetToken(ctx *gin.Context) {
+	r := ctx.Request
+	token, err := h.Protector.Issue(ctx.Writer, r)
+	if err != nil {
+		if err == csrf.ErrMissingSession {
+			ctx.JSON(http.StatusUnauthorized, err.Error())
+			return
+		}
+		if h.LogError != nil {
+			h.LogError(r.Context(), err.Error())
+		}
+		ctx.JSON(http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	m := make(map[string]interface{})
+	m[h.Token] = token
+	ctx.JSON(http.StatusOK, m)
+}
+
+// SignIn wraps the handler of a sign in, to rotate the token once the user is authenticated,
+// so a token, which was issued before the sign in, is not valid for the new session. It should also wrap the handlers, which change the privileges of a session.
+// The token is rotated before a successful response is written, with the session id of the cookies, which the handler sets, or of the request.
+func (h *Handler) SignIn(success gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		w := ctx.Writer
+		rw := &rotateWriter{ResponseWriter: w, rotate: func() {
+			if _, err := h.Protector.RotateSignIn(w, ctx.Request); err != nil && h.LogError != nil {
+				h.LogError(ctx.Request.Context(), err.Error())
+			}
+		}}
+		ctx.Writer = rw
+		defer func() { ctx.Writer = w }()
+		if success != nil {
+			success(ctx)
+		} else {
+			ctx.JSON(http.StatusOK, true)
+		}
+		if !w.Written() {
+			rw.once()
+		}
+	}
+}
+
+type rotateWriter struct {
+	gin.ResponseWriter
+	rotate  func()
+	rotated bool
+}
+
+func (w *rotateWriter) once() {
+	if !w.rotated {
+		w.rotated = true
+		if w.Status() < 300 {
+			w.rotate()
+		}
+	}
+}
+func (w *rotateWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.once()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+func (w *rotateWriter) Write(b []byte) (int, error) {
+	w.WriteHeaderNow()
+	return w.ResponseWriter.Write(b)
+}
+func (w *rotateWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	return w.ResponseWriter.WriteString(s)
+}
+func (w *rotateWriter) Flush() {
+	w.WriteHeaderNow()
+	w.ResponseWriter.Flush()
+}
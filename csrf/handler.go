@@ -0,0 +1,117 @@
+package csrf
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+type Handler struct {
+	Protector *Protector
+	LogError  func(context.Context, string, ...map[string]interface{})
+	Token     string
+}
+
+func NewHandler(protector *Protector, logError func(context.Context, string, ...map[string]interface{}), opts ...string) *Handler {
+	token := "token"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		token = opts[0]
+	}
+	return &Handler{Protector: protector, LogError: logError, Token: token}
+}
+
+func (h *Handler) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsSafeMethod(r.Method) {
+			if h.Protector.Mode != Synchronizer || len(h.Protector.GetSessionId(r)) > 0 {
+				if _, err := h.Protector.Issue(w, r); err != nil && h.LogError != nil {
+					h.LogError(r.Context(), err.Error())
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		status, err := h.Protector.Verify(r)
+		if err != nil {
+			if status == http.StatusInternalServerError {
+				if h.LogError != nil {
+					h.LogError(r.Context(), err.Error())
+				}
+				http.Error(w, "Internal Server Error", status)
+			} else {
+				http.Error(w, err.Error(), status)
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetToken returns the csrf token as json, so that single page applications can send it back in the header.
+func (h *Handler) GetToken(w http.ResponseWriter, r *http.Request) {
+	token, err := h.Protector.Issue(w, r)
+	if err != nil {
+		if err == ErrMissingSession {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if h.LogError != nil {
+			h.LogError(r.Context(), err.Error())
+		}
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	m := make(map[string]interface{})
+	m[h.Token] = token
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(m)
+}
+
+// SignIn wraps the success handler of a sign in, such as the Success of passcode.Handler, to rotate the token once the user is authenticated,
+// so a token, which was issued before the sign in, is not valid for the new session. It should also wrap the handlers, which change the privileges of a session.
+// The token is rotated before the response is written, with the session id of the cookies, which success sets, or of the request.
+func (h *Handler) SignIn(success func(w http.ResponseWriter, r *http.Request, id string)) func(w http.ResponseWriter, r *http.Request, id string) {
+	return func(w http.ResponseWriter, r *http.Request, id string) {
+		rw := &rotateWriter{ResponseWriter: w, rotate: func() { h.rotate(w, r) }}
+		if success != nil {
+			success(rw, r, id)
+		}
+		rw.once()
+		if success == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(true)
+		}
+	}
+}
+func (h *Handler) rotate(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.Protector.RotateSignIn(w, r); err != nil && h.LogError != nil {
+		h.LogError(r.Context(), err.Error())
+	}
+}
+
+type rotateWriter struct {
+	http.ResponseWriter
+	rotate  func()
+	rotated bool
+}
+
+func (w *rotateWriter) once() {
+	if !w.rotated {
+		w.rotated = true
+		w.rotate()
+	}
+}
+func (w *rotateWriter) WriteHeader(statusCode int) {
+	if statusCode < 300 {
+		w.once()
+	} else {
+		w.rotated = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+func (w *rotateWriter) Write(b []byte) (int, error) {
+	w.once()
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,596 @@
+package excel
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MaxRows      = 1048576
+	maxSheetName = 31
+	dateTime     = "yyyy-mm-dd hh:mm:ss"
+)
+
+var builtinFormats = map[string]int{"0": 1, "0.00": 2, "#,##0": 3, "#,##0.00": 4, "0%": 9, "0.00%": 10, "0.00E+00": 11, "mm-dd-yy": 14, "d-mmm-yy": 15, "h:mm": 20, "h:mm:ss": 21, "m/d/yy h:mm": 22, "@": 49}
+
+// HeaderStyle is the style of the header of the sheets; Color and Background are ARGB colors, such as FF000000.
+// It is not named Style, because this package dot-imports excelize, which has its Style.
+type HeaderStyle struct {
+	Bold       bool
+	Color      string
+	Background string
+}
+
+// Builder writes a workbook to a writer, such as an http.ResponseWriter, sheet by sheet: the rows are written to the zip stream when they are added, so the memory does not grow with the number of rows.
+//   - A sheet is added by NewSheet or NewMapSheet; adding a sheet completes the previous sheet, so the sheets are written one after another.
+//   - Close completes the workbook; it does not close the writer.
+type Builder struct {
+	HeaderStyle HeaderStyle
+	zw          *zip.Writer
+	sheets      []string
+	filters     []string
+	formats     []string
+	styles      map[string]int
+	current     *sheet
+	err         error
+	mu          sync.Mutex
+}
+
+func NewBuilder(w io.Writer, opts ...HeaderStyle) *Builder {
+	style := HeaderStyle{Bold: true, Background: "FFD9D9D9"}
+	if len(opts) > 0 {
+		style = opts[0]
+	}
+	return &Builder{HeaderStyle: style, zw: zip.NewWriter(w), styles: make(map[string]int)}
+}
+
+// Column is a column of a sheet: the header, the index of the field of the struct, the number format, such as #,##0.00 or yyyy-mm-dd, and the width in characters; a width of 0 is the default width.
+type Column struct {
+	Header string
+	Field  string
+	Index  []int
+	Format string
+	Width  float64
+	style  int
+}
+
+// GetColumns returns the columns of a struct:
+//   - The header is the tag `excel`, or the json name, or the field name; a field with the tag `excel:"-"` is skipped.
+//   - The tag `order` sets the order of the columns; the fields without order are after the fields with order, in the order of the struct.
+//   - The tag `numFmt` is the number format, and the tag `width` is the width of the column; the default format of time.Time is yyyy-mm-dd hh:mm:ss.
+func GetColumns(modelType reflect.Type) ([]Column, error) {
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return nil, errors.New("bad type")
+	}
+	type ordered struct {
+		column Column
+		order  int
+	}
+	var columns []ordered
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		header := field.Tag.Get("excel")
+		if header == "-" {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if len(header) == 0 {
+			if jsonName == "-" {
+				continue
+			}
+			header = jsonName
+		}
+		if len(header) == 0 {
+			header = field.Name
+		}
+		c := Column{Header: header, Field: field.Name, Index: field.Index, Format: field.Tag.Get("numFmt")}
+		if width := field.Tag.Get("width"); len(width) > 0 {
+			w, err := strconv.ParseFloat(width, 64)
+			if err != nil {
+				return nil, fmt.Errorf("width of %s: %w", field.Name, err)
+			}
+			c.Width = w
+		}
+		order := len(columns) + 1<<20
+		if tag := field.Tag.Get("order"); len(tag) > 0 {
+			o, err := strconv.Atoi(tag)
+			if err != nil {
+				return nil, fmt.Errorf("order of %s: %w", field.Name, err)
+			}
+			order = o
+		}
+		t := field.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if len(c.Format) == 0 && t == reflect.TypeOf(time.Time{}) {
+			c.Format = dateTime
+		}
+		columns = append(columns, ordered{column: c, order: order})
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columns[i].order < columns[j].order
+	})
+	res := make([]Column, len(columns))
+	for i := range columns {
+		res[i] = columns[i].column
+	}
+	return res, nil
+}
+
+// SheetWriter writes the rows of T to a sheet.
+type SheetWriter[T any] struct {
+	Columns []Column
+	builder *Builder
+	sheet   *sheet
+}
+
+// NewSheet adds a sheet of the rows of T, and writes the header; opts are freezing the header and adding an autofilter to the header, which are true by default.
+func NewSheet[T any](b *Builder, name string, opts ...bool) (*SheetWriter[T], error) {
+	var t T
+	columns, err := GetColumns(reflect.TypeOf(t))
+	if err != nil {
+		return nil, err
+	}
+	return newSheet[T](b, name, columns, opts...)
+}
+
+// NewMapSheet adds a sheet of the rows of maps; the values are read by the headers, and the values of time.Time are formatted as yyyy-mm-dd hh:mm:ss.
+func NewMapSheet(b *Builder, name string, headers []string, opts ...bool) (*SheetWriter[map[string]interface{}], error) {
+	columns := make([]Column, len(headers))
+	for i, header := range headers {
+		columns[i] = Column{Header: header, Field: header}
+	}
+	return newSheet[map[string]interface{}](b, name, columns, opts...)
+}
+
+func newSheet[T any](b *Builder, name string, columns []Column, opts ...bool) (*SheetWriter[T], error) {
+	freeze, filter := true, true
+	if len(opts) > 0 {
+		freeze = opts[0]
+	}
+	if len(opts) > 1 {
+		filter = opts[1]
+	}
+	for i := range columns {
+		columns[i].style = b.style(columns[i].Format)
+	}
+	s, err := b.addSheet(name, columns, freeze, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &SheetWriter[T]{Columns: columns, builder: b, sheet: s}, nil
+}
+
+// Write writes a row; it has the signature of the Write of Importer, so a sheet can be the output of an import.
+func (s *SheetWriter[T]) Write(ctx context.Context, model *T) error {
+	if s.builder.err != nil {
+		return s.builder.err
+	}
+	if s.builder.current != s.sheet {
+		return errors.New("the sheet is completed, because another sheet is added")
+	}
+	if s.sheet.rows >= MaxRows {
+		return fmt.Errorf("sheet %s has more than %d rows", s.sheet.name, MaxRows)
+	}
+	s.sheet.startRow()
+	s.encode(s.sheet.w, model, s.sheet.row)
+	return s.builder.fail(s.sheet.endRow())
+}
+
+// Encode encodes a row without the row number, for WriteRows; it can be called concurrently, so the rows can be encoded in parallel, such as by the partitions of an export.
+func (s *SheetWriter[T]) Encode(ctx context.Context, model *T) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("<row>")
+	s.encode(&buf, model, "")
+	buf.WriteString("</row>")
+	return buf.Bytes(), nil
+}
+
+// WriteRows writes n rows encoded by Encode.
+func (s *SheetWriter[T]) WriteRows(r io.Reader, n int64) error {
+	if s.builder.err != nil {
+		return s.builder.err
+	}
+	if s.builder.current != s.sheet {
+		return errors.New("the sheet is completed, because another sheet is added")
+	}
+	if int64(s.sheet.rows)+n > MaxRows {
+		return fmt.Errorf("sheet %s has more than %d rows", s.sheet.name, MaxRows)
+	}
+	s.sheet.rows += int(n)
+	_, err := io.Copy(s.sheet.w, r)
+	return s.builder.fail(err)
+}
+
+// encode writes the cells of a row; if row is empty, the cells have no reference, and the empty cells are written, to keep the positions of the cells.
+func (s *SheetWriter[T]) encode(w textWriter, model *T, row string) {
+	var m map[string]interface{}
+	var v reflect.Value
+	if mp, ok := interface{}(model).(*map[string]interface{}); ok {
+		m = *mp
+	} else {
+		v = reflect.Indirect(reflect.ValueOf(model))
+	}
+	for i, c := range s.Columns {
+		var value interface{}
+		if m != nil {
+			value = m[c.Field]
+		} else {
+			f := v.FieldByIndex(c.Index)
+			if f.Kind() == reflect.Ptr {
+				if f.IsNil() {
+					f = reflect.Value{}
+				} else {
+					f = f.Elem()
+				}
+			}
+			if f.IsValid() {
+				value = f.Interface()
+			}
+		}
+		style := c.style
+		if _, ok := value.(time.Time); ok && len(c.Format) == 0 {
+			style = s.builder.style(dateTime)
+		}
+		ref := ""
+		if len(row) > 0 {
+			ref = ColumnName(i) + row
+		}
+		if !writeCell(w, ref, value, style) && len(row) == 0 {
+			w.WriteString("<c/>")
+		}
+	}
+}
+
+// WriteAll writes the rows.
+func (s *SheetWriter[T]) WriteAll(ctx context.Context, models []T) error {
+	for i := range models {
+		if err := s.Write(ctx, &models[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close completes the workbook; an error of a row, of a sheet or of the writer is returned.
+func (b *Builder) Close() error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.closeSheet(); err != nil {
+		return err
+	}
+	if len(b.sheets) == 0 {
+		return errors.New("the workbook has no sheet")
+	}
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", b.contentTypes()},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", b.workbook()},
+		{"xl/_rels/workbook.xml.rels", b.workbookRels()},
+		{"xl/styles.xml", b.stylesXML()},
+	}
+	for _, f := range files {
+		w, err := b.zw.Create(f.name)
+		if err != nil {
+			return b.fail(err)
+		}
+		if _, err = io.WriteString(w, f.content); err != nil {
+			return b.fail(err)
+		}
+	}
+	err := b.zw.Close()
+	if err == nil {
+		b.err = errors.New("the workbook is closed")
+	}
+	return err
+}
+
+func (b *Builder) fail(err error) error {
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	return err
+}
+
+// style returns the index of the cell style of a number format.
+func (b *Builder) style(format string) int {
+	if len(format) == 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i, ok := b.styles[format]; ok {
+		return i
+	}
+	b.formats = append(b.formats, format)
+	// 0 is the default style, and 1 is the style of the header
+	i := len(b.formats) + 1
+	b.styles[format] = i
+	return i
+}
+
+func (b *Builder) addSheet(name string, columns []Column, freeze bool, filter bool) (*sheet, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	name = strings.TrimSpace(name)
+	if len(name) == 0 || len([]rune(name)) > maxSheetName || strings.ContainsAny(name, `:\/?*[]`) {
+		return nil, fmt.Errorf("invalid sheet name '%s'", name)
+	}
+	for _, s := range b.sheets {
+		if strings.EqualFold(s, name) {
+			return nil, fmt.Errorf("sheet %s is duplicated", name)
+		}
+	}
+	if err := b.closeSheet(); err != nil {
+		return nil, err
+	}
+	w, err := b.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(b.sheets)+1))
+	if err != nil {
+		return nil, b.fail(err)
+	}
+	b.sheets = append(b.sheets, name)
+	b.filters = append(b.filters, "")
+	s := &sheet{name: name, w: bufio.NewWriter(w), columns: len(columns), filter: filter}
+	b.current = s
+	s.w.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	if freeze && len(columns) > 0 {
+		s.w.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/><selection pane="bottomLeft"/></sheetView></sheetViews>`)
+	}
+	hasWidth := false
+	for _, c := range columns {
+		if c.Width > 0 {
+			hasWidth = true
+		}
+	}
+	if hasWidth {
+		s.w.WriteString("<cols>")
+		for i, c := range columns {
+			if c.Width > 0 {
+				fmt.Fprintf(s.w, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, i+1, i+1, strconv.FormatFloat(c.Width, 'f', -1, 64))
+			}
+		}
+		s.w.WriteString("</cols>")
+	}
+	s.w.WriteString("<sheetData>")
+	if len(columns) > 0 {
+		s.startRow()
+		for i, c := range columns {
+			writeCell(s.w, ColumnName(i)+s.row, c.Header, 1)
+		}
+		if err = s.endRow(); err != nil {
+			return nil, b.fail(err)
+		}
+	}
+	return s, nil
+}
+func (b *Builder) closeSheet() error {
+	s := b.current
+	if s == nil {
+		return nil
+	}
+	b.current = nil
+	s.w.WriteString("</sheetData>")
+	if s.filter && s.columns > 0 {
+		ref := fmt.Sprintf("A1:%s%d", ColumnName(s.columns-1), s.rows)
+		fmt.Fprintf(s.w, `<autoFilter ref="%s"/>`, ref)
+		b.filters[len(b.filters)-1] = ref
+	}
+	s.w.WriteString("</worksheet>")
+	return b.fail(s.w.Flush())
+}
+
+func (b *Builder) contentTypes() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range b.sheets {
+		fmt.Fprintf(&sb, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	sb.WriteString("</Types>")
+	return sb.String()
+}
+func (b *Builder) workbook() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range b.sheets {
+		fmt.Fprintf(&sb, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(name), i+1, i+1)
+	}
+	sb.WriteString("</sheets>")
+	names := false
+	for i, ref := range b.filters {
+		if len(ref) == 0 {
+			continue
+		}
+		if !names {
+			sb.WriteString("<definedNames>")
+			names = true
+		}
+		parts := strings.Split(ref, ":")
+		fmt.Fprintf(&sb, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">'%s'!$%s:$%s</definedName>`, i, escape(strings.ReplaceAll(b.sheets[i], "'", "''")), absolute(parts[0]), absolute(parts[1]))
+	}
+	if names {
+		sb.WriteString("</definedNames>")
+	}
+	sb.WriteString("</workbook>")
+	return sb.String()
+}
+func (b *Builder) workbookRels() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range b.sheets {
+		fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(b.sheets)+1)
+	sb.WriteString("</Relationships>")
+	return sb.String()
+}
+func (b *Builder) stylesXML() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	ids := make([]int, len(b.formats))
+	var custom []int
+	for i, format := range b.formats {
+		if id, ok := builtinFormats[format]; ok {
+			ids[i] = id
+		} else {
+			ids[i] = 164 + len(custom)
+			custom = append(custom, i)
+		}
+	}
+	if len(custom) > 0 {
+		fmt.Fprintf(&sb, `<numFmts count="%d">`, len(custom))
+		for _, i := range custom {
+			fmt.Fprintf(&sb, `<numFmt numFmtId="%d" formatCode="%s"/>`, ids[i], escape(b.formats[i]))
+		}
+		sb.WriteString("</numFmts>")
+	}
+	h := b.HeaderStyle
+	sb.WriteString(`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font>`)
+	if h.Bold {
+		sb.WriteString("<b/>")
+	}
+	sb.WriteString(`<sz val="11"/>`)
+	if len(h.Color) > 0 {
+		fmt.Fprintf(&sb, `<color rgb="%s"/>`, escape(h.Color))
+	}
+	sb.WriteString(`<name val="Calibri"/></font></fonts>`)
+	sb.WriteString(`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>`)
+	if len(h.Background) > 0 {
+		fmt.Fprintf(&sb, `<fill><patternFill patternType="solid"><fgColor rgb="%s"/><bgColor indexed="64"/></patternFill></fill>`, escape(h.Background))
+	} else {
+		sb.WriteString(`<fill><patternFill patternType="none"/></fill>`)
+	}
+	sb.WriteString(`</fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`)
+	sb.WriteString(`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`)
+	fmt.Fprintf(&sb, `<cellXfs count="%d"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>`, len(b.formats)+2)
+	for _, id := range ids {
+		fmt.Fprintf(&sb, `<xf numFmtId="%d" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`, id)
+	}
+	sb.WriteString(`</cellXfs><cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles></styleSheet>`)
+	return sb.String()
+}
+
+type sheet struct {
+	name    string
+	w       *bufio.Writer
+	columns int
+	filter  bool
+	rows    int
+	row     string
+}
+
+func (s *sheet) startRow() {
+	s.rows++
+	s.row = strconv.Itoa(s.rows)
+	fmt.Fprintf(s.w, `<row r="%d">`, s.rows)
+}
+func (s *sheet) endRow() error {
+	_, err := s.w.WriteString("</row>")
+	return err
+}
+
+type textWriter interface {
+	io.Writer
+	WriteString(s string) (int, error)
+}
+
+// writeCell writes a cell, and returns false if the value is empty.
+func writeCell(w textWriter, ref string, value interface{}, style int) bool {
+	attrs := ""
+	if len(ref) > 0 {
+		attrs = ` r="` + ref + `"`
+	}
+	if style > 0 {
+		attrs += ` s="` + strconv.Itoa(style) + `"`
+	}
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		writeText(w, attrs, v)
+	case bool:
+		n := "0"
+		if v {
+			n = "1"
+		}
+		fmt.Fprintf(w, `<c%s t="b"><v>%s</v></c>`, attrs, n)
+	case time.Time:
+		if v.IsZero() {
+			return false
+		}
+		// the wall clock of the time is written, because the cells of Excel have no time zone
+		t := time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+		fmt.Fprintf(w, `<c%s><v>%s</v></c>`, attrs, strconv.FormatFloat(TimeToExcelTime(t), 'f', -1, 64))
+	case big.Float:
+		fmt.Fprintf(w, `<c%s><v>%s</v></c>`, attrs, v.Text('f', -1))
+	case fmt.Stringer:
+		writeText(w, attrs, v.String())
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fmt.Fprintf(w, `<c%s><v>%d</v></c>`, attrs, rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fmt.Fprintf(w, `<c%s><v>%d</v></c>`, attrs, rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			fmt.Fprintf(w, `<c%s><v>%s</v></c>`, attrs, strconv.FormatFloat(rv.Float(), 'f', -1, 64))
+		case reflect.String:
+			writeText(w, attrs, rv.String())
+		case reflect.Bool:
+			return writeCell(w, ref, rv.Bool(), style)
+		default:
+			writeText(w, attrs, fmt.Sprint(value))
+		}
+	}
+	return true
+}
+func writeText(w textWriter, attrs string, v string) {
+	fmt.Fprintf(w, `<c%s t="inlineStr"><is><t xml:space="preserve">`, attrs)
+	xml.EscapeText(w, []byte(v))
+	w.WriteString("</t></is></c>")
+}
+
+// ColumnName returns the name of a column from 0, such as A for 0 and AA for 26.
+func ColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+func absolute(ref string) string {
+	i := strings.IndexAny(ref, "0123456789")
+	if i < 0 {
+		return ref
+	}
+	return ref[:i] + "$" + ref[i:]
+}
+func escape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
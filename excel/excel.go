@@ -23,11 +23,11 @@ func ExportMaps(ctx context.Context, headers []string, arr interface{}, format s
 	if rows, ok := arr.([]interface{}); ok {
 		f := NewFile()
 		for i, header := range headers {
-			headerCellName, _ := CoordinatesToCellName(i+1, 1)
+			headerCellName := ToAlphaString(i) + "1"
 			f.SetCellValue("Sheet1", headerCellName, header)
 			for index, rowMap := range rows {
 				if row, ok2 := rowMap.(map[string]interface{}); ok2 {
-					cellName, _ := CoordinatesToCellName(i+1, index+2)
+					cellName := ToAlphaString(i) + strconv.Itoa(index+2)
 					switch value := row[header].(type) {
 					case string:
 						timeValue, err := time.Parse(time.RFC3339, value)
@@ -56,7 +56,7 @@ func ExportMaps(ctx context.Context, headers []string, arr interface{}, format s
 func FormatStringToTime(t time.Time, f *File, cellName string) {
 	time, _ := timeToExcelTime(t.UTC())
 	f.SetCellValue("Sheet1", cellName, time)
-	style, _ := f.NewStyle(&Style{NumFmt: 22})
+	style, _ := f.NewStyle(`{"number_format": 22}`)
 	f.SetCellStyle("Sheet1", cellName, cellName, style)
 }
 
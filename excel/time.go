@@ -0,0 +1,9 @@
+package excel
+
+import "time"
+
+// TimeToExcelTime converts a time to the serial number of the time in Excel, which is the number of days since 1899-12-30.
+func TimeToExcelTime(t time.Time) float64 {
+	v, _ := timeToExcelTime(t.UTC())
+	return v
+}
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	w "github.com/core-go/core/writer"
+)
+
+// Format is a format of the files of an export, such as CSV, NDJSON, fixed-length, or XLSX of the package export/xlsx; Open starts an output, such as by writing the header.
+type Format[T any] interface {
+	Open(out io.Writer) (Output[T], error)
+}
+
+// Output writes the rows of an output in a format.
+//   - Encode encodes a row; it can be called concurrently, so the partitions of an export are encoded in parallel.
+//   - Write writes a row encoded by Encode, and Copy writes n rows encoded by Encode, such as the rows of a partition, which are spooled to a temporary file.
+//   - Close completes the output, such as by writing the footer; it does not close the writer of Open.
+type Output[T any] interface {
+	Encode(ctx context.Context, model *T) ([]byte, error)
+	Write(row []byte) error
+	Copy(rows io.Reader, n int64) error
+	Close() error
+}
+
+// TextFormat is a format, which has a row per line, such as CSV, NDJSON or fixed-length.
+type TextFormat[T any] struct {
+	Header []byte
+	Footer []byte
+	Encode func(ctx context.Context, model *T) ([]byte, error)
+}
+
+// NewTextFormat creates a format by a Transform of the writer package, such as the Transform of writer.FixedLengthTransformer, writer.DelimiterTransformer or writer.LayoutTransformer; opts are the header and the footer, which are written as they are.
+func NewTextFormat[T any](transform func(ctx context.Context, model *T) string, opts ...string) *TextFormat[T] {
+	f := &TextFormat[T]{Encode: func(ctx context.Context, model *T) ([]byte, error) {
+		return []byte(transform(ctx, model)), nil
+	}}
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		f.Header = []byte(opts[0])
+	}
+	if len(opts) > 1 && len(opts[1]) > 0 {
+		f.Footer = []byte(opts[1])
+	}
+	return f
+}
+
+// NewCSVFormat creates a CSV format, which quotes the values by RFC 4180; the values are formatted by the tag `format`, like writer.DelimiterTransformer, and the header is the json names, or the names of the fields.
+// opts are the delimiter, which is a comma by default, and the skip tag of writer.GetIndexesByTag.
+func NewCSVFormat[T any](opts ...string) (*TextFormat[T], error) {
+	comma := ','
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		comma, _ = utf8.DecodeRuneInString(opts[0])
+	}
+	skipTag := ""
+	if len(opts) > 1 {
+		skipTag = opts[1]
+	}
+	var t T
+	modelType := reflect.TypeOf(t)
+	formatCols, err := w.GetIndexesByTag(modelType, "format", skipTag)
+	if err != nil {
+		return nil, err
+	}
+	encode := func(values []string) []byte {
+		var buf bytes.Buffer
+		cw := csv.NewWriter(&buf)
+		cw.Comma = comma
+		cw.Write(values)
+		cw.Flush()
+		return buf.Bytes()
+	}
+	headers := make([]string, 0, len(formatCols))
+	for i := 0; i < modelType.NumField(); i++ {
+		if _, ok := formatCols[i]; !ok {
+			continue
+		}
+		field := modelType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if len(name) == 0 || name == "-" {
+			name = field.Name
+		}
+		headers = append(headers, name)
+	}
+	return &TextFormat[T]{
+		Header: encode(headers),
+		Encode: func(ctx context.Context, model *T) ([]byte, error) {
+			return encode(w.ToValues(model, formatCols)), nil
+		},
+	}, nil
+}
+
+// NewNDJSONFormat creates a JSON lines format, which has a JSON object per line.
+func NewNDJSONFormat[T any]() *TextFormat[T] {
+	return &TextFormat[T]{Encode: func(ctx context.Context, model *T) ([]byte, error) {
+		data, err := json.Marshal(model)
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}}
+}
+
+func (f *TextFormat[T]) Open(out io.Writer) (Output[T], error) {
+	if len(f.Header) > 0 {
+		if _, err := out.Write(f.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &textOutput[T]{format: f, out: out}, nil
+}
+
+type textOutput[T any] struct {
+	format *TextFormat[T]
+	out    io.Writer
+}
+
+func (o *textOutput[T]) Encode(ctx context.Context, model *T) ([]byte, error) {
+	return o.format.Encode(ctx, model)
+}
+func (o *textOutput[T]) Write(row []byte) error {
+	_, err := o.out.Write(row)
+	return err
+}
+func (o *textOutput[T]) Copy(rows io.Reader, n int64) error {
+	_, err := io.Copy(o.out, rows)
+	return err
+}
+func (o *textOutput[T]) Close() error {
+	if len(o.format.Footer) > 0 {
+		_, err := o.out.Write(o.format.Footer)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,532 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/core-go/core/io/zip"
+)
+
+const PartitionToken = "{partition}"
+
+// Partition is a part of the rows of an export: a key range from From to To, To is excluded, or a Value of a partition column.
+type Partition struct {
+	Index int         `yaml:"index" mapstructure:"index" json:"index" gorm:"column:index" bson:"index" dynamodbav:"index" firestore:"index"`
+	Name  string      `yaml:"name" mapstructure:"name" json:"name,omitempty" gorm:"column:name" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
+	From  interface{} `yaml:"from" mapstructure:"from" json:"from,omitempty" gorm:"column:from" bson:"from,omitempty" dynamodbav:"from,omitempty" firestore:"from,omitempty"`
+	To    interface{} `yaml:"to" mapstructure:"to" json:"to,omitempty" gorm:"column:to" bson:"to,omitempty" dynamodbav:"to,omitempty" firestore:"to,omitempty"`
+	Value interface{} `yaml:"value" mapstructure:"value" json:"value,omitempty" gorm:"column:value" bson:"value,omitempty" dynamodbav:"value,omitempty" firestore:"value,omitempty"`
+}
+
+// KeyRanges splits the keys from min to max, max is included, into n ranges of the same size; the names are the numbers of the ranges from 1, with leading zeros, so the files of the partitions are sorted by the names.
+func KeyRanges(min int64, max int64, n int) []Partition {
+	if n <= 0 {
+		n = 1
+	}
+	if max < min {
+		return nil
+	}
+	size := (max - min + 1) / int64(n)
+	if size <= 0 {
+		size = 1
+		n = int(max - min + 1)
+	}
+	width := len(strconv.Itoa(n))
+	partitions := make([]Partition, 0, n)
+	from := min
+	for i := 0; i < n; i++ {
+		to := from + size
+		if i == n-1 {
+			to = max + 1
+		}
+		partitions = append(partitions, Partition{Index: i, Name: fmt.Sprintf("%0*d", width, i+1), From: from, To: to})
+		from = to
+	}
+	return partitions
+}
+
+// ValuePartitions creates a partition for each value of a partition column, such as a region or a month; the names are the values.
+func ValuePartitions(values ...interface{}) []Partition {
+	partitions := make([]Partition, len(values))
+	for i, v := range values {
+		partitions[i] = Partition{Index: i, Name: fmt.Sprint(v), Value: v}
+	}
+	return partitions
+}
+
+// RangeQuery builds the query of a key range: select * from (query) p where column >= From and column < To; buildParam builds the placeholders, such as query.GetBuild(db), and args are the parameters of query.
+func RangeQuery(query string, column string, buildParam func(int) string, args ...interface{}) func(context.Context, Partition) (string, []interface{}) {
+	return func(ctx context.Context, p Partition) (string, []interface{}) {
+		n := len(args)
+		q := fmt.Sprintf("select * from (%s) p where %s >= %s and %s < %s", query, column, buildParam(n+1), column, buildParam(n+2))
+		params := make([]interface{}, 0, n+2)
+		params = append(params, args...)
+		return q, append(params, p.From, p.To)
+	}
+}
+
+// ValueQuery builds the query of a value of a partition column: select * from (query) p where column = Value.
+func ValueQuery(query string, column string, buildParam func(int) string, args ...interface{}) func(context.Context, Partition) (string, []interface{}) {
+	return func(ctx context.Context, p Partition) (string, []interface{}) {
+		n := len(args)
+		q := fmt.Sprintf("select * from (%s) p where %s = %s", query, column, buildParam(n+1))
+		params := make([]interface{}, 0, n+1)
+		params = append(params, args...)
+		return q, append(params, p.Value)
+	}
+}
+
+// Progress is the progress of an export: Rows is the number of the rows of Partition, and Total is the number of the rows of all the partitions.
+type Progress struct {
+	Partition  string
+	Rows       int64
+	Total      int64
+	Done       int
+	Partitions int
+}
+
+type ManifestFile struct {
+	Name      string `yaml:"name" mapstructure:"name" json:"name,omitempty" gorm:"column:name" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
+	Partition string `yaml:"partition" mapstructure:"partition" json:"partition,omitempty" gorm:"column:partition" bson:"partition,omitempty" dynamodbav:"partition,omitempty" firestore:"partition,omitempty"`
+	Rows      int64  `yaml:"rows" mapstructure:"rows" json:"rows" gorm:"column:rows" bson:"rows" dynamodbav:"rows" firestore:"rows"`
+	Size      int64  `yaml:"size" mapstructure:"size" json:"size" gorm:"column:size" bson:"size" dynamodbav:"size" firestore:"size"`
+	Checksum  string `yaml:"checksum" mapstructure:"checksum" json:"checksum,omitempty" gorm:"column:checksum" bson:"checksum,omitempty" dynamodbav:"checksum,omitempty" firestore:"checksum,omitempty"`
+}
+
+// Manifest is the result of an export: the files, with the numbers of the rows, the sizes and the SHA-256 checksums of the files, and the numbers of the rows of the partitions.
+type Manifest struct {
+	Files      []ManifestFile   `yaml:"files" mapstructure:"files" json:"files,omitempty" gorm:"-" bson:"files,omitempty" dynamodbav:"files,omitempty" firestore:"files,omitempty"`
+	Partitions map[string]int64 `yaml:"partitions" mapstructure:"partitions" json:"partitions,omitempty" gorm:"-" bson:"partitions,omitempty" dynamodbav:"partitions,omitempty" firestore:"partitions,omitempty"`
+	Rows       int64            `yaml:"rows" mapstructure:"rows" json:"rows" gorm:"column:rows" bson:"rows" dynamodbav:"rows" firestore:"rows"`
+	StartTime  time.Time        `yaml:"startTime" mapstructure:"startTime" json:"startTime" gorm:"column:starttime" bson:"startTime" dynamodbav:"startTime" firestore:"startTime"`
+	EndTime    time.Time        `yaml:"endTime" mapstructure:"endTime" json:"endTime" gorm:"column:endtime" bson:"endTime" dynamodbav:"endTime" firestore:"endTime"`
+}
+
+func NewParallelExporter[T any](db *sql.DB,
+	buildQuery func(context.Context, Partition) (string, []interface{}),
+	partitions []Partition,
+	format Format[T],
+	fileName string,
+	opts ...func(interface{}) interface {
+		driver.Valuer
+		sql.Scanner
+	},
+) (*ParallelExporter[T], error) {
+	var t T
+	modelType := reflect.TypeOf(t)
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	fieldsIndex, err := GetColumnIndexes(modelType)
+	if err != nil {
+		return nil, err
+	}
+	columns := GetColumnsSelect(modelType)
+	var toArray func(interface{}) interface {
+		driver.Valuer
+		sql.Scanner
+	}
+	if len(opts) > 0 {
+		toArray = opts[0]
+	}
+	return &ParallelExporter[T]{DB: db, columns: columns, Map: fieldsIndex, BuildQuery: buildQuery, Partitions: partitions, Format: format, FileName: fileName, Ordered: true, Workers: 4, Interval: 10000, Array: toArray}, nil
+}
+
+// ParallelExporter exports the partitions of the rows in parallel, with a query per partition, by Workers, which is the number of the connections to the database.
+//   - If FileName has {partition}, such as out/orders_{partition}.csv.gz, each partition is exported to a file, with the name of the partition.
+//   - Otherwise, the partitions are exported to FileName, or to Writer if it is set, such as an http.ResponseWriter; if Ordered is true, the rows are in the order of the partitions, because the partitions are spooled to temporary files and copied in order, otherwise the rows are written when they are read.
+//   - The files are compressed by the extensions of the names, .gz or .zip.
+//   - Progress is called every Interval rows of a partition, and at the end of a partition; it is not called concurrently.
+//   - If Manifest is set, the manifest is written to Manifest as JSON, after the files are completed.
+type ParallelExporter[T any] struct {
+	DB         *sql.DB
+	Map        map[string]int
+	columns    []string
+	BuildQuery func(context.Context, Partition) (string, []interface{})
+	Partitions []Partition
+	Format     Format[T]
+	FileName   string
+	Writer     io.Writer
+	Ordered    bool
+	Workers    int
+	Manifest   string
+	Progress   func(Progress)
+	Interval   int64
+	Array      func(interface{}) interface {
+		driver.Valuer
+		sql.Scanner
+	}
+}
+
+func (s *ParallelExporter[T]) Export(ctx context.Context) (*Manifest, error) {
+	if len(s.Partitions) == 0 {
+		return nil, errors.New("no partition to export")
+	}
+	m := &Manifest{Partitions: make(map[string]int64), StartTime: time.Now()}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run := &run[T]{exporter: s, manifest: m, cancel: cancel}
+	var err error
+	if strings.Contains(s.FileName, PartitionToken) {
+		if s.Writer != nil {
+			return nil, errors.New("a writer cannot be the output of the files of the partitions")
+		}
+		err = run.exportFiles(ctx)
+	} else {
+		err = run.exportFile(ctx)
+	}
+	if err != nil {
+		return m, err
+	}
+	m.EndTime = time.Now()
+	if len(s.Manifest) > 0 {
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return m, err
+		}
+		if err = os.WriteFile(s.Manifest, data, 0644); err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+type run[T any] struct {
+	exporter *ParallelExporter[T]
+	manifest *Manifest
+	cancel   func()
+	mu       sync.Mutex
+	err      error
+	total    int64
+	done     int
+}
+
+func (r *run[T]) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil && err != nil {
+		r.err = err
+		r.cancel()
+	}
+	r.mu.Unlock()
+}
+
+// parallel calls export for each partition, by the workers.
+func (r *run[T]) parallel(ctx context.Context, export func(ctx context.Context, p Partition) error) {
+	workers := r.exporter.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for _, p := range r.exporter.Partitions {
+		wg.Add(1)
+		go func(p Partition) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				r.fail(ctx.Err())
+				return
+			}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			if err := export(ctx, p); err != nil {
+				r.fail(fmt.Errorf("partition %s: %w", p.Name, err))
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+func (r *run[T]) exportFiles(ctx context.Context) error {
+	r.parallel(ctx, func(ctx context.Context, p Partition) error {
+		name := strings.ReplaceAll(r.exporter.FileName, PartitionToken, p.Name)
+		f, err := createFile(name, nil)
+		if err != nil {
+			return err
+		}
+		out, err := r.exporter.Format.Open(f.out)
+		if err != nil {
+			f.close()
+			return err
+		}
+		rows, err := r.scan(ctx, p, out.Encode, out.Write)
+		if err == nil {
+			err = out.Close()
+		}
+		if er1 := f.close(); er1 != nil && err == nil {
+			err = er1
+		}
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		r.manifest.Files = append(r.manifest.Files, ManifestFile{Name: name, Partition: p.Name, Rows: rows, Size: f.size, Checksum: f.checksum()})
+		r.mu.Unlock()
+		return nil
+	})
+	if r.err != nil {
+		return r.err
+	}
+	files := r.manifest.Files
+	// the files are added when the partitions are completed, so they are sorted by the partitions
+	sorted := make([]ManifestFile, 0, len(files))
+	for _, p := range r.exporter.Partitions {
+		for _, file := range files {
+			if file.Partition == p.Name {
+				sorted = append(sorted, file)
+			}
+		}
+	}
+	r.manifest.Files = sorted
+	return nil
+}
+
+func (r *run[T]) exportFile(ctx context.Context) error {
+	s := r.exporter
+	f, err := createFile(s.FileName, s.Writer)
+	if err != nil {
+		return err
+	}
+	out, err := s.Format.Open(f.out)
+	if err != nil {
+		f.close()
+		return err
+	}
+	if s.Ordered {
+		err = r.exportOrdered(ctx, out)
+	} else {
+		var mu sync.Mutex
+		r.parallel(ctx, func(ctx context.Context, p Partition) error {
+			_, err := r.scan(ctx, p, out.Encode, func(row []byte) error {
+				mu.Lock()
+				defer mu.Unlock()
+				return out.Write(row)
+			})
+			return err
+		})
+		err = r.err
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if er1 := f.close(); er1 != nil && err == nil {
+		err = er1
+	}
+	if err != nil {
+		return err
+	}
+	r.manifest.Files = []ManifestFile{{Name: s.FileName, Rows: r.total, Size: f.size, Checksum: f.checksum()}}
+	return nil
+}
+
+// exportOrdered spools the partitions to temporary files, and copies them to out in the order of the partitions, when they are completed.
+func (r *run[T]) exportOrdered(ctx context.Context, out Output[T]) error {
+	type spool struct {
+		file *os.File
+		rows int64
+		err  error
+		done chan struct{}
+	}
+	partitions := r.exporter.Partitions
+	spools := make([]*spool, len(partitions))
+	for i := range spools {
+		spools[i] = &spool{done: make(chan struct{})}
+	}
+	finished := make(chan struct{})
+	defer func() {
+		<-finished
+		for _, sp := range spools {
+			if sp.file != nil {
+				sp.file.Close()
+				os.Remove(sp.file.Name())
+			}
+		}
+	}()
+	positions := make(map[int]int, len(partitions))
+	for i, p := range partitions {
+		positions[p.Index] = i
+	}
+	go func() {
+		defer close(finished)
+		r.parallel(ctx, func(ctx context.Context, p Partition) error {
+			sp := spools[positions[p.Index]]
+			defer close(sp.done)
+			file, err := os.CreateTemp("", "export-*")
+			if err != nil {
+				sp.err = err
+				return err
+			}
+			sp.file = file
+			w := bufio.NewWriter(file)
+			sp.rows, sp.err = r.scan(ctx, p, out.Encode, func(row []byte) error {
+				_, err := w.Write(row)
+				return err
+			})
+			if sp.err == nil {
+				sp.err = w.Flush()
+			}
+			return sp.err
+		})
+		// the spools of the partitions, which are not exported because of an error, are completed
+		for _, sp := range spools {
+			select {
+			case <-sp.done:
+			default:
+				if sp.err == nil {
+					sp.err = context.Canceled
+				}
+				close(sp.done)
+			}
+		}
+	}()
+	for _, sp := range spools {
+		<-sp.done
+		if sp.err != nil {
+			r.fail(sp.err)
+			break
+		}
+		if _, err := sp.file.Seek(0, io.SeekStart); err != nil {
+			r.fail(err)
+			break
+		}
+		if err := out.Copy(bufio.NewReader(sp.file), sp.rows); err != nil {
+			r.fail(err)
+			break
+		}
+		sp.file.Close()
+		os.Remove(sp.file.Name())
+		sp.file = nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// scan queries the rows of a partition, encodes them and passes them to write, and returns the number of the rows.
+func (r *run[T]) scan(ctx context.Context, p Partition, encode func(context.Context, *T) ([]byte, error), write func([]byte) error) (int64, error) {
+	s := r.exporter
+	query, params := s.BuildQuery(ctx, p)
+	rows, err := s.DB.QueryContext(ctx, query, params...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var i int64
+	for rows.Next() {
+		var obj T
+		values, swapValues := StructScan(&obj, s.columns, s.Map, s.Array)
+		if err := rows.Scan(values...); err != nil {
+			return i, err
+		}
+		SwapValuesToBool(&obj, &swapValues)
+		row, err := encode(ctx, &obj)
+		if err != nil {
+			return i, err
+		}
+		if err = write(row); err != nil {
+			return i, err
+		}
+		i++
+		if s.Interval > 0 && i%s.Interval == 0 {
+			r.progress(p, i, s.Interval, false)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return i, err
+	}
+	interval := s.Interval
+	if interval <= 0 {
+		interval = i + 1
+	}
+	r.progress(p, i, i%interval, true)
+	return i, nil
+}
+func (r *run[T]) progress(p Partition, rows int64, added int64, done bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total += added
+	if done {
+		r.done++
+		r.manifest.Partitions[p.Name] = rows
+		r.manifest.Rows = r.total
+	}
+	if r.exporter.Progress != nil {
+		r.exporter.Progress(Progress{Partition: p.Name, Rows: rows, Total: r.total, Done: r.done, Partitions: len(r.exporter.Partitions)})
+	}
+}
+
+// outputFile is a file, or a writer, which is compressed by the extension of the name, and the size and the checksum of which are computed.
+type outputFile struct {
+	out        *bufio.Writer
+	compressor io.Closer
+	file       *os.File
+	hash       hash.Hash
+	size       int64
+}
+
+func createFile(name string, w io.Writer) (*outputFile, error) {
+	f := &outputFile{hash: sha256.New()}
+	if w == nil {
+		if dir := filepath.Dir(name); len(dir) > 0 {
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				return nil, err
+			}
+		}
+		file, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		f.file = file
+		w = file
+	}
+	cw, err := zip.NewWriter(io.MultiWriter(w, f.hash, f), name)
+	if err != nil {
+		if f.file != nil {
+			f.file.Close()
+		}
+		return nil, err
+	}
+	f.compressor = cw
+	f.out = bufio.NewWriterSize(cw, 64*1024)
+	return f, nil
+}
+
+// Write counts the size of the file.
+func (f *outputFile) Write(p []byte) (int, error) {
+	f.size += int64(len(p))
+	return len(p), nil
+}
+func (f *outputFile) close() error {
+	err := f.out.Flush()
+	if er1 := f.compressor.Close(); er1 != nil && err == nil {
+		err = er1
+	}
+	if f.file != nil {
+		if er2 := f.file.Close(); er2 != nil && err == nil {
+			err = er2
+		}
+	}
+	return err
+}
+func (f *outputFile) checksum() string {
+	return hex.EncodeToString(f.hash.Sum(nil))
+}
@@ -0,0 +1,58 @@
+// Package xlsx is the XLSX format of the package export; it is a separate package, so the other formats do not depend on excelize.
+package xlsx
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/core-go/core/excel"
+	"github.com/core-go/core/export"
+)
+
+// ExcelFormat is an XLSX format, which has a sheet, see excel.NewSheet for the columns.
+type ExcelFormat[T any] struct {
+	Sheet       string
+	HeaderStyle *excel.HeaderStyle
+}
+
+// NewExcelFormat creates an XLSX format; opts is the name of the sheet, which is Sheet1 by default.
+func NewExcelFormat[T any](opts ...string) *ExcelFormat[T] {
+	sheet := "Sheet1"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		sheet = opts[0]
+	}
+	return &ExcelFormat[T]{Sheet: sheet}
+}
+
+func (f *ExcelFormat[T]) Open(out io.Writer) (export.Output[T], error) {
+	var b *excel.Builder
+	if f.HeaderStyle != nil {
+		b = excel.NewBuilder(out, *f.HeaderStyle)
+	} else {
+		b = excel.NewBuilder(out)
+	}
+	sheet, err := excel.NewSheet[T](b, f.Sheet)
+	if err != nil {
+		return nil, err
+	}
+	return &excelOutput[T]{builder: b, sheet: sheet}, nil
+}
+
+type excelOutput[T any] struct {
+	builder *excel.Builder
+	sheet   *excel.SheetWriter[T]
+}
+
+func (o *excelOutput[T]) Encode(ctx context.Context, model *T) ([]byte, error) {
+	return o.sheet.Encode(ctx, model)
+}
+func (o *excelOutput[T]) Write(row []byte) error {
+	return o.sheet.WriteRows(bytes.NewReader(row), 1)
+}
+func (o *excelOutput[T]) Copy(rows io.Reader, n int64) error {
+	return o.sheet.WriteRows(rows, n)
+}
+func (o *excelOutput[T]) Close() error {
+	return o.builder.Close()
+}
@@ -3,6 +3,7 @@ package gin
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"net/http"
 )
 
 type CookieHandler struct {
@@ -11,6 +12,7 @@ type CookieHandler struct {
 	Secret            string
 	Ip                string
 	Authorization     string
+	VerifyCsrf        func(r *http.Request) (int, error)
 }
 
 func NewCookieHandler(verifyToken func(string, string) (bool, string, map[string]interface{}, int64, int64, error), secret string, options ...string) *CookieHandler {
@@ -29,6 +31,12 @@ func NewCookieHandlerWithIp(verifyToken func(string, string) (bool, string, map[
 	return &CookieHandler{Authorization: authorization, GetAndVerifyToken: verifyToken, Secret: secret, Token: token, Ip: ip}
 }
 
+func NewCookieHandlerWithCsrf(verifyToken func(string, string) (bool, string, map[string]interface{}, int64, int64, error), secret string, ip string, verifyCsrf func(*http.Request) (int, error), options ...string) *CookieHandler {
+	c := NewCookieHandlerWithIp(verifyToken, secret, ip, options...)
+	c.VerifyCsrf = verifyCsrf
+	return c
+}
+
 func (c *CookieHandler) Handle() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		r := ctx.Request
@@ -36,6 +44,12 @@ func (c *CookieHandler) Handle() gin.HandlerFunc {
 		if err != nil || tokenCookie == nil {
 			ctx.Next()
 		} else {
+			if c.VerifyCsrf != nil {
+				if status, err := c.VerifyCsrf(r); err != nil {
+					ctx.AbortWithStatusJSON(status, err.Error())
+					return
+				}
+			}
 			authorization := tokenCookie.Value
 			isToken, _, data, _, _, err := c.GetAndVerifyToken(authorization, c.Secret)
 			var ctx2 context.Context
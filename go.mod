@@ -1,3 +1,10 @@
 module github.com/core-go/core
 
 go 1.18
+
+require (
+	github.com/360EntSecGroup-Skylar/excelize v1.4.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
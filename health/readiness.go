@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// Readiness is a checker which is DOWN until the service is ready, and DOWN again when the service starts shutting down,
+// so that load balancers stop sending new requests before the connections are drained.
+type Readiness struct {
+	name  string
+	ready int32
+}
+
+func NewReadiness(opts ...string) *Readiness {
+	name := "readiness"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		name = opts[0]
+	}
+	return &Readiness{name: name}
+}
+
+func (s *Readiness) Name() string {
+	return s.name
+}
+func (s *Readiness) SetReady(ready bool) {
+	if ready {
+		atomic.StoreInt32(&s.ready, 1)
+	} else {
+		atomic.StoreInt32(&s.ready, 0)
+	}
+}
+func (s *Readiness) IsReady() bool {
+	return atomic.LoadInt32(&s.ready) == 1
+}
+func (s *Readiness) Check(ctx context.Context) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+	if !s.IsReady() {
+		return res, errors.New("service is not ready")
+	}
+	return res, nil
+}
+func (s *Readiness) Build(ctx context.Context, data map[string]interface{}, err error) map[string]interface{} {
+	if err == nil {
+		return data
+	}
+	if data == nil {
+		data = make(map[string]interface{}, 0)
+	}
+	data["error"] = err.Error()
+	return data
+}
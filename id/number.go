@@ -0,0 +1,109 @@
+package id
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	PeriodNone  = "none"
+	PeriodYear  = "year"
+	PeriodMonth = "month"
+	PeriodDay   = "day"
+)
+
+var numberPattern = regexp.MustCompile(`\{(yyyy|yy|mm|dd|seq(?::(\d+))?)\}`)
+
+// Number formats the business numbers, such as INV-2026-000123, from a sequence.
+//   - Format has the placeholders {yyyy}, {yy}, {mm}, {dd} and {seq} or {seq:n} for a sequence padded with zeros to n digits.
+//   - The sequence restarts for each period: the name of the sequence is the name and the period, such as invoice:2026.
+//     The period is from the format if it is not set: day if it has {dd}, month if it has {mm}, year if it has {yyyy} or {yy}.
+//
+// Next is the next value of a sequence, such as SequenceAdapter.Next of the sequence package, which starts a new sequence at 1.
+type Number struct {
+	Next     func(ctx context.Context, name string) (int64, error)
+	Name     string
+	Format   string
+	Period   string
+	Location *time.Location
+	Now      func() time.Time
+}
+
+// NewNumber creates a number; opts[0] is the period: none, year, month or day.
+func NewNumber(next func(context.Context, string) (int64, error), name string, format string, opts ...string) *Number {
+	n := &Number{Next: next, Name: name, Format: format, Location: time.UTC, Now: time.Now}
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		n.Period = opts[0]
+	} else {
+		n.Period = PeriodOf(format)
+	}
+	return n
+}
+
+// PeriodOf returns the smallest period in the format.
+func PeriodOf(format string) string {
+	if strings.Contains(format, "{dd}") {
+		return PeriodDay
+	}
+	if strings.Contains(format, "{mm}") {
+		return PeriodMonth
+	}
+	if strings.Contains(format, "{yyyy}") || strings.Contains(format, "{yy}") {
+		return PeriodYear
+	}
+	return PeriodNone
+}
+
+// SequenceName returns the name of the sequence for the time.
+func (n *Number) SequenceName(t time.Time) string {
+	switch n.Period {
+	case PeriodYear:
+		return n.Name + ":" + t.Format("2006")
+	case PeriodMonth:
+		return n.Name + ":" + t.Format("200601")
+	case PeriodDay:
+		return n.Name + ":" + t.Format("20060102")
+	default:
+		return n.Name
+	}
+}
+func (n *Number) Generate(ctx context.Context) (string, error) {
+	t := n.Now().In(n.Location)
+	seq, err := n.Next(ctx, n.SequenceName(t))
+	if err != nil {
+		return "", err
+	}
+	if seq < 0 {
+		return "", fmt.Errorf("cannot get the sequence %s", n.SequenceName(t))
+	}
+	return FormatNumber(n.Format, t, seq), nil
+}
+
+// FormatNumber replaces the placeholders of the format.
+func FormatNumber(format string, t time.Time, seq int64) string {
+	return numberPattern.ReplaceAllStringFunc(format, func(s string) string {
+		m := numberPattern.FindStringSubmatch(s)
+		switch m[1] {
+		case "yyyy":
+			return t.Format("2006")
+		case "yy":
+			return t.Format("06")
+		case "mm":
+			return t.Format("01")
+		case "dd":
+			return t.Format("02")
+		}
+		v := strconv.FormatInt(seq, 10)
+		if len(m[2]) > 0 {
+			width, _ := strconv.Atoi(m[2])
+			if len(v) < width {
+				v = strings.Repeat("0", width-len(v)) + v
+			}
+		}
+		return v
+	})
+}
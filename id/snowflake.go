@@ -0,0 +1,118 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SnowflakeConfig struct {
+	Worker       int64      `yaml:"worker" mapstructure:"worker" json:"worker,omitempty" gorm:"column:worker" bson:"worker,omitempty" dynamodbav:"worker,omitempty" firestore:"worker,omitempty"`
+	Epoch        *time.Time `yaml:"epoch" mapstructure:"epoch" json:"epoch,omitempty" gorm:"column:epoch" bson:"epoch,omitempty" dynamodbav:"epoch,omitempty" firestore:"epoch,omitempty"`
+	WorkerBits   uint       `yaml:"worker_bits" mapstructure:"worker_bits" json:"workerBits,omitempty" gorm:"column:workerbits" bson:"workerBits,omitempty" dynamodbav:"workerBits,omitempty" firestore:"workerBits,omitempty"`
+	SequenceBits uint       `yaml:"sequence_bits" mapstructure:"sequence_bits" json:"sequenceBits,omitempty" gorm:"column:sequencebits" bson:"sequenceBits,omitempty" dynamodbav:"sequenceBits,omitempty" firestore:"sequenceBits,omitempty"`
+}
+
+// DefaultEpoch is 2020-01-01T00:00:00Z; with 41 bits of milliseconds, the ids are valid for 69 years.
+var DefaultEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates the 63 bits ids: the milliseconds since the epoch, the worker id and a sequence in the millisecond.
+// The default layout is 41 bits of time, 10 bits of worker and 12 bits of sequence. Each instance must have its own worker id.
+type Snowflake struct {
+	Worker       int64
+	Epoch        time.Time
+	WorkerBits   uint
+	SequenceBits uint
+	Now          func() time.Time
+	mu           sync.Mutex
+	last         int64
+	sequence     int64
+}
+
+func NewSnowflake(c SnowflakeConfig) (*Snowflake, error) {
+	s := &Snowflake{Worker: c.Worker, Epoch: DefaultEpoch, WorkerBits: 10, SequenceBits: 12, Now: time.Now}
+	if c.Epoch != nil {
+		s.Epoch = *c.Epoch
+	}
+	if c.WorkerBits > 0 {
+		s.WorkerBits = c.WorkerBits
+	}
+	if c.SequenceBits > 0 {
+		s.SequenceBits = c.SequenceBits
+	}
+	if s.WorkerBits+s.SequenceBits > 22 {
+		return nil, errors.New("worker bits and sequence bits cannot be more than 22")
+	}
+	if s.Worker < 0 || s.Worker >= 1<<s.WorkerBits {
+		return nil, fmt.Errorf("worker must be from 0 to %d", 1<<s.WorkerBits-1)
+	}
+	return s, nil
+}
+
+// NextId returns the next id. If the sequence of the millisecond is exhausted, it waits for the next millisecond.
+// If the clock moves backwards, it continues from the last millisecond, so that the ids are never duplicated.
+func (s *Snowflake) NextId() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms := s.Now().Sub(s.Epoch).Milliseconds()
+	if ms < 0 {
+		return 0, errors.New("the time is before the epoch")
+	}
+	if ms < s.last {
+		ms = s.last
+	}
+	maxSequence := int64(1)<<s.SequenceBits - 1
+	if ms == s.last {
+		s.sequence++
+		if s.sequence > maxSequence {
+			for ms <= s.last {
+				time.Sleep(100 * time.Microsecond)
+				ms = s.Now().Sub(s.Epoch).Milliseconds()
+			}
+			s.sequence = 0
+		}
+	} else {
+		s.sequence = 0
+	}
+	if ms >= 1<<(63-s.WorkerBits-s.SequenceBits) {
+		return 0, errors.New("the time cannot be encoded in the snowflake id")
+	}
+	s.last = ms
+	return ms<<(s.WorkerBits+s.SequenceBits) | s.Worker<<s.SequenceBits | s.sequence, nil
+}
+func (s *Snowflake) Generate(ctx context.Context) (string, error) {
+	id, err := s.NextId()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// Time returns the time of an id.
+func (s *Snowflake) Time(id int64) time.Time {
+	return s.Epoch.Add(time.Duration(id>>(s.WorkerBits+s.SequenceBits)) * time.Millisecond)
+}
+
+// HostWorker returns a worker id from the host name: the ordinal of a pod of a StatefulSet, such as 3 of "api-3", or a hash of the host name.
+// A hash may collide, so configure the worker id if the number of instances is large.
+func HostWorker(bits uint) int64 {
+	host, _ := os.Hostname()
+	return NameWorker(host, bits)
+}
+func NameWorker(name string, bits uint) int64 {
+	max := int64(1) << bits
+	if i := strings.LastIndexByte(name, '-'); i >= 0 {
+		if n, err := strconv.ParseInt(name[i+1:], 10, 64); err == nil && n >= 0 && n < max {
+			return n
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int64(h.Sum32()) % max
+}
@@ -0,0 +1,172 @@
+package id
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/core-go/core/uuid"
+)
+
+const (
+	StrategyUUID      = "uuid"
+	StrategyUUIDv7    = "uuid7"
+	StrategyULID      = "ulid"
+	StrategySnowflake = "snowflake"
+)
+
+// Strategy generates an id; it has the signature of the generate functions of the services and the adapters.
+type Strategy func(ctx context.Context) (string, error)
+
+// Registry has the id strategies by name, so that a model selects its strategy by the tag `id`:
+//
+//	Id string `json:"id" id:"ulid"`
+//	Id string `json:"id" id:"ulid,prefix=ord"` // ord_01J2Y...
+//	Id string `json:"id" id:"invoice"`         // registered by Register("invoice", NewNumber(...).Generate)
+type Registry struct {
+	mu         sync.RWMutex
+	strategies map[string]Strategy
+}
+
+// NewRegistry creates a registry with uuid, uuid7, ulid and snowflake. The snowflake worker is from the host name, see HostWorker; register another snowflake to configure it.
+func NewRegistry() *Registry {
+	r := &Registry{strategies: make(map[string]Strategy)}
+	r.Register(StrategyUUID, uuid.Generate)
+	r.Register(StrategyUUIDv7, uuid.GenerateV7)
+	r.Register(StrategyULID, GenerateULID)
+	var once sync.Once
+	var snowflake *Snowflake
+	var err error
+	r.Register(StrategySnowflake, func(ctx context.Context) (string, error) {
+		once.Do(func() {
+			snowflake, err = NewSnowflake(SnowflakeConfig{Worker: HostWorker(10)})
+		})
+		if err != nil {
+			return "", err
+		}
+		return snowflake.Generate(ctx)
+	})
+	return r
+}
+func (r *Registry) Register(name string, strategy Strategy) {
+	r.mu.Lock()
+	r.strategies[name] = strategy
+	r.mu.Unlock()
+}
+func (r *Registry) Get(name string) (Strategy, bool) {
+	r.mu.RLock()
+	s, ok := r.strategies[name]
+	r.mu.RUnlock()
+	return s, ok
+}
+func (r *Registry) Generate(ctx context.Context, name string) (string, error) {
+	s, ok := r.Get(name)
+	if !ok {
+		return "", fmt.Errorf("id strategy '%s' is not registered", name)
+	}
+	return s(ctx)
+}
+
+// Parse returns the strategy of a tag: the name of the strategy, and the option prefix.
+func (r *Registry) Parse(tag string) (Strategy, error) {
+	parts := strings.Split(tag, ",")
+	name := strings.TrimSpace(parts[0])
+	s, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("id strategy '%s' is not registered", name)
+	}
+	for _, p := range parts[1:] {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "prefix=") {
+			s = Prefixed(p[len("prefix="):], s)
+		} else if len(p) > 0 {
+			return nil, fmt.Errorf("invalid option '%s' of id strategy '%s'", p, name)
+		}
+	}
+	return s, nil
+}
+
+// Func returns the strategy of the first field with the tag `id` of the model type, to be the generate function of a service or an adapter.
+func (r *Registry) Func(modelType reflect.Type) (Strategy, error) {
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	for i := 0; i < modelType.NumField(); i++ {
+		if tag, ok := modelType.Field(i).Tag.Lookup("id"); ok && len(tag) > 0 {
+			return r.Parse(tag)
+		}
+	}
+	return nil, fmt.Errorf("%s has no field with the tag id", modelType.Name())
+}
+
+// Apply sets the empty string fields with the tag `id` of the model, which must be a pointer to a struct.
+func (r *Registry) Apply(ctx context.Context, model interface{}) error {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("model must be a pointer to a struct")
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("id")
+		if !ok || len(tag) == 0 {
+			continue
+		}
+		f := v.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		if f.Kind() == reflect.Ptr && f.Type().Elem().Kind() == reflect.String {
+			if !f.IsNil() && len(f.Elem().String()) > 0 {
+				continue
+			}
+		} else if f.Kind() != reflect.String || len(f.String()) > 0 {
+			continue
+		}
+		s, err := r.Parse(tag)
+		if err != nil {
+			return err
+		}
+		id, err := s(ctx)
+		if err != nil {
+			return err
+		}
+		if f.Kind() == reflect.Ptr {
+			f.Set(reflect.ValueOf(&id))
+		} else {
+			f.SetString(id)
+		}
+	}
+	return nil
+}
+
+// Prefixed returns the ids of the strategy with a prefix, such as ord_01J2Y..., so that the type of an id is readable. An underscore is added after the prefix if it has none.
+func Prefixed(prefix string, strategy Strategy) Strategy {
+	if len(prefix) > 0 && !strings.HasSuffix(prefix, "_") && !strings.HasSuffix(prefix, "-") {
+		prefix = prefix + "_"
+	}
+	return func(ctx context.Context) (string, error) {
+		id, err := strategy(ctx)
+		if err != nil {
+			return "", err
+		}
+		return prefix + id, nil
+	}
+}
+
+var Strategies = NewRegistry()
+
+func Register(name string, strategy Strategy) {
+	Strategies.Register(name, strategy)
+}
+func GetStrategy(name string) (Strategy, bool) {
+	return Strategies.Get(name)
+}
+func Func(modelType reflect.Type) (Strategy, error) {
+	return Strategies.Func(modelType)
+}
+func Apply(ctx context.Context, model interface{}) error {
+	return Strategies.Apply(ctx, model)
+}
@@ -0,0 +1,112 @@
+package id
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates the Universally Unique Lexicographically Sortable Identifiers: 48 bits of milliseconds and 80 random bits, in 26 characters of Crockford base32.
+// The ids of the same millisecond are monotonic: the random part is incremented.
+type ULID struct {
+	Now  func() time.Time
+	mu   sync.Mutex
+	last uint64
+	rand [10]byte
+}
+
+func NewULID() *ULID {
+	return &ULID{Now: time.Now}
+}
+
+var defaultULID = NewULID()
+
+func (g *ULID) Next() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := uint64(g.Now().UnixNano() / int64(time.Millisecond))
+	if ms <= g.last {
+		ms = g.last
+		if !increment(g.rand[:]) {
+			// the random part overflows, use the next millisecond
+			ms++
+			if _, err := rand.Read(g.rand[:]); err != nil {
+				return "", err
+			}
+		}
+	} else if _, err := rand.Read(g.rand[:]); err != nil {
+		return "", err
+	}
+	if ms >= 1<<48 {
+		return "", errors.New("the time cannot be encoded in a ULID")
+	}
+	g.last = ms
+	var b [16]byte
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*uint(i)))
+	}
+	copy(b[6:], g.rand[:])
+	return EncodeULID(b), nil
+}
+func (g *ULID) Generate(ctx context.Context) (string, error) {
+	return g.Next()
+}
+
+// GenerateULID generates a ULID with the default generator.
+func GenerateULID(ctx context.Context) (string, error) {
+	return defaultULID.Next()
+}
+
+// EncodeULID encodes the 128 bits in 26 characters; the first character has 3 bits only.
+func EncodeULID(b [16]byte) string {
+	var sb strings.Builder
+	sb.Grow(26)
+	// 130 bits with 2 leading zero bits
+	var acc uint32
+	bits := 2
+	for i := 0; i < 16; i++ {
+		acc = acc<<8 | uint32(b[i])
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			sb.WriteByte(crockford[(acc>>uint(bits))&31])
+		}
+	}
+	return sb.String()
+}
+
+// ULIDTime returns the time of a ULID.
+func ULIDTime(s string) (time.Time, error) {
+	if len(s) != 26 {
+		return time.Time{}, errors.New("invalid ULID")
+	}
+	var ms uint64
+	for i := 0; i < 10; i++ {
+		k := strings.IndexByte(crockford, upper(s[i]))
+		if k < 0 {
+			return time.Time{}, errors.New("invalid ULID")
+		}
+		ms = ms<<5 | uint64(k)
+	}
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)), nil
+}
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checkpoint is the progress of an import job: all the records until Line are committed, so a re-run of the same file resumes after Line.
+// Lines are the committed lines after Line, if the records are not written in order; they are skipped too.
+// If the importer reads several files by ReadFile, the lines are the numbers of the records in all the files.
+type Checkpoint struct {
+	Job       string         `yaml:"job" mapstructure:"job" json:"job,omitempty" gorm:"column:job;primary_key" bson:"_id,omitempty" dynamodbav:"job,omitempty" firestore:"-"`
+	Filename  string         `yaml:"filename" mapstructure:"filename" json:"filename,omitempty" gorm:"column:filename" bson:"filename,omitempty" dynamodbav:"filename,omitempty" firestore:"filename,omitempty"`
+	Hash      string         `yaml:"hash" mapstructure:"hash" json:"hash,omitempty" gorm:"column:hash" bson:"hash,omitempty" dynamodbav:"hash,omitempty" firestore:"hash,omitempty"`
+	Line      int            `yaml:"line" mapstructure:"line" json:"line" gorm:"column:line" bson:"line" dynamodbav:"line" firestore:"line"`
+	Lines     []int          `yaml:"lines" mapstructure:"lines" json:"lines,omitempty" gorm:"-" bson:"lines,omitempty" dynamodbav:"lines,omitempty" firestore:"lines,omitempty"`
+	Total     int            `yaml:"total" mapstructure:"total" json:"total" gorm:"column:total" bson:"total" dynamodbav:"total" firestore:"total"`
+	Success   int            `yaml:"success" mapstructure:"success" json:"success" gorm:"column:success" bson:"success" dynamodbav:"success" firestore:"success"`
+	Invalid   int            `yaml:"invalid" mapstructure:"invalid" json:"invalid" gorm:"column:invalid" bson:"invalid" dynamodbav:"invalid" firestore:"invalid"`
+	Failed    int            `yaml:"failed" mapstructure:"failed" json:"failed" gorm:"column:failed" bson:"failed" dynamodbav:"failed" firestore:"failed"`
+	Errors    map[string]int `yaml:"errors" mapstructure:"errors" json:"errors,omitempty" gorm:"-" bson:"errors,omitempty" dynamodbav:"errors,omitempty" firestore:"errors,omitempty"`
+	Completed bool           `yaml:"completed" mapstructure:"completed" json:"completed,omitempty" gorm:"column:completed" bson:"completed,omitempty" dynamodbav:"completed,omitempty" firestore:"completed,omitempty"`
+	UpdatedAt time.Time      `yaml:"updatedAt" mapstructure:"updatedAt" json:"updatedAt,omitempty" gorm:"column:updated_at" bson:"updatedAt,omitempty" dynamodbav:"updatedAt,omitempty" firestore:"updatedAt,omitempty"`
+}
+
+// CheckpointPort stores the checkpoints of the import jobs; Load returns nil if the job has no checkpoint.
+type CheckpointPort interface {
+	Load(ctx context.Context, job string) (*Checkpoint, error)
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+	Delete(ctx context.Context, job string) error
+}
+
+// FileCheckpoint stores a checkpoint per job in a JSON file of a directory; a checkpoint is written to a temporary file, then renamed, so that a crash never leaves a partial checkpoint.
+type FileCheckpoint struct {
+	Directory string
+	mu        sync.Mutex
+}
+
+func NewFileCheckpoint(directory string) (*FileCheckpoint, error) {
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &FileCheckpoint{Directory: directory}, nil
+}
+func (c *FileCheckpoint) Load(ctx context.Context, job string) (*Checkpoint, error) {
+	data, err := os.ReadFile(c.path(job))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var checkpoint Checkpoint
+	if err = json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+func (c *FileCheckpoint) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(checkpoint.Job)
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+func (c *FileCheckpoint) Delete(ctx context.Context, job string) error {
+	err := os.Remove(c.path(job))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+func (c *FileCheckpoint) path(job string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(job)
+	return filepath.Join(c.Directory, name+".checkpoint.json")
+}
+
+// HashFile returns the SHA-256 of a file, so that a checkpoint is used only for the same file.
+func HashFile(fileName string) (string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
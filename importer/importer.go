@@ -132,9 +132,11 @@ func NewImporter[T any, S string | []string](
 	return &Importer[T, S]{Read: read, Transform: transform, Validate: validate, HandleError: handleError, HandleException: handleException, Write: write, Flush: flush, Filename: filename}
 }
 
+// Importer reads, transforms, validates and writes the records. If ReadFile is set, such as ReadFile of reader.ArchiveReader, it is used instead of Read, and the file name of the error handlers is the file of the record.
 type Importer[T any, S string | []string] struct {
 	Transform       func(ctx context.Context, record S) (T, error)
 	Read            func(next func(record S, err error, numLine int) error) error
+	ReadFile        func(next func(record S, err error, numLine int, fileName string) error) error
 	Validate        func(ctx context.Context, model *T) ([]ErrorMessage, error)
 	HandleError     func(ctx context.Context, raw S, rs *T, err []ErrorMessage, i int, fileName string)
 	HandleException func(ctx context.Context, raw S, rs *T, err error, i int, fileName string)
@@ -144,7 +146,7 @@ type Importer[T any, S string | []string] struct {
 }
 
 func (s *Importer[T, S]) Import(ctx context.Context) (total int, success int, err error) {
-	err = s.Read(func(line S, err error, numLine int) error {
+	err = s.readFile(func(line S, err error, numLine int, fileName string) error {
 		if err == io.EOF {
 			if s.Flush != nil {
 				return s.Flush(ctx)
@@ -162,14 +164,14 @@ func (s *Importer[T, S]) Import(ctx context.Context) (total int, success int, er
 				return err
 			}
 			if len(errs) > 0 {
-				s.HandleError(ctx, line, &record, errs, numLine, s.Filename)
+				s.HandleError(ctx, line, &record, errs, numLine, fileName)
 				return nil
 			}
 		}
 		err = s.Write(ctx, &record)
 		if err != nil {
 			if s.HandleException != nil {
-				s.HandleException(ctx, line, &record, err, numLine, s.Filename)
+				s.HandleException(ctx, line, &record, err, numLine, fileName)
 				return nil
 			} else {
 				return err
@@ -183,3 +185,16 @@ func (s *Importer[T, S]) Import(ctx context.Context) (total int, success int, er
 	}
 	return total, success, nil
 }
+func (s *Importer[T, S]) readFile(next func(record S, err error, numLine int, fileName string) error) error {
+	if s.ReadFile != nil {
+		return s.ReadFile(func(record S, err error, numLine int, fileName string) error {
+			if len(fileName) == 0 {
+				fileName = s.Filename
+			}
+			return next(record, err, numLine, fileName)
+		})
+	}
+	return s.Read(func(record S, err error, numLine int) error {
+		return next(record, err, numLine, s.Filename)
+	})
+}
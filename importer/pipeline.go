@@ -0,0 +1,378 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Exception is the code of the records, which cannot be written, in the errors of Report.
+const Exception = "exception"
+
+// PipelineConfig configures a pipeline:
+//   - Workers is the number of goroutines, which transform and validate the records; the default is 1.
+//   - Ordered writes the records in the order of the file; otherwise a record is written as soon as it is validated.
+//   - BatchSize is the number of records per write or per transaction; the default is 1.
+//   - Checkpoint is the minimum number of lines between two checkpoints; the default is 0, which saves a checkpoint after each batch.
+type PipelineConfig struct {
+	Workers    int  `yaml:"workers" mapstructure:"workers" json:"workers,omitempty" gorm:"column:workers" bson:"workers,omitempty" dynamodbav:"workers,omitempty" firestore:"workers,omitempty"`
+	Ordered    bool `yaml:"ordered" mapstructure:"ordered" json:"ordered,omitempty" gorm:"column:ordered" bson:"ordered,omitempty" dynamodbav:"ordered,omitempty" firestore:"ordered,omitempty"`
+	BatchSize  int  `yaml:"batch_size" mapstructure:"batch_size" json:"batchSize,omitempty" gorm:"column:batch_size" bson:"batchSize,omitempty" dynamodbav:"batchSize,omitempty" firestore:"batchSize,omitempty"`
+	Checkpoint int  `yaml:"checkpoint" mapstructure:"checkpoint" json:"checkpoint,omitempty" gorm:"column:checkpoint" bson:"checkpoint,omitempty" dynamodbav:"checkpoint,omitempty" firestore:"checkpoint,omitempty"`
+}
+
+// Report is the result of an import job; the totals include the records of the previous runs, if the job is resumed.
+type Report struct {
+	Job       string         `yaml:"job" mapstructure:"job" json:"job,omitempty" gorm:"column:job" bson:"job,omitempty" dynamodbav:"job,omitempty" firestore:"job,omitempty"`
+	Filename  string         `yaml:"filename" mapstructure:"filename" json:"filename,omitempty" gorm:"column:filename" bson:"filename,omitempty" dynamodbav:"filename,omitempty" firestore:"filename,omitempty"`
+	Hash      string         `yaml:"hash" mapstructure:"hash" json:"hash,omitempty" gorm:"column:hash" bson:"hash,omitempty" dynamodbav:"hash,omitempty" firestore:"hash,omitempty"`
+	Total     int            `yaml:"total" mapstructure:"total" json:"total" gorm:"column:total" bson:"total" dynamodbav:"total" firestore:"total"`
+	Success   int            `yaml:"success" mapstructure:"success" json:"success" gorm:"column:success" bson:"success" dynamodbav:"success" firestore:"success"`
+	Invalid   int            `yaml:"invalid" mapstructure:"invalid" json:"invalid" gorm:"column:invalid" bson:"invalid" dynamodbav:"invalid" firestore:"invalid"`
+	Failed    int            `yaml:"failed" mapstructure:"failed" json:"failed" gorm:"column:failed" bson:"failed" dynamodbav:"failed" firestore:"failed"`
+	Skipped   int            `yaml:"skipped" mapstructure:"skipped" json:"skipped" gorm:"column:skipped" bson:"skipped" dynamodbav:"skipped" firestore:"skipped"`
+	Errors    map[string]int `yaml:"errors" mapstructure:"errors" json:"errors,omitempty" gorm:"-" bson:"errors,omitempty" dynamodbav:"errors,omitempty" firestore:"errors,omitempty"`
+	Resumed   bool           `yaml:"resumed" mapstructure:"resumed" json:"resumed,omitempty" gorm:"column:resumed" bson:"resumed,omitempty" dynamodbav:"resumed,omitempty" firestore:"resumed,omitempty"`
+	From      int            `yaml:"from" mapstructure:"from" json:"from,omitempty" gorm:"column:from" bson:"from,omitempty" dynamodbav:"from,omitempty" firestore:"from,omitempty"`
+	Line      int            `yaml:"line" mapstructure:"line" json:"line,omitempty" gorm:"column:line" bson:"line,omitempty" dynamodbav:"line,omitempty" firestore:"line,omitempty"`
+	Completed bool           `yaml:"completed" mapstructure:"completed" json:"completed,omitempty" gorm:"column:completed" bson:"completed,omitempty" dynamodbav:"completed,omitempty" firestore:"completed,omitempty"`
+	StartTime time.Time      `yaml:"startTime" mapstructure:"startTime" json:"startTime,omitempty" gorm:"column:start_time" bson:"startTime,omitempty" dynamodbav:"startTime,omitempty" firestore:"startTime,omitempty"`
+	EndTime   time.Time      `yaml:"endTime" mapstructure:"endTime" json:"endTime,omitempty" gorm:"column:end_time" bson:"endTime,omitempty" dynamodbav:"endTime,omitempty" firestore:"endTime,omitempty"`
+	Duration  time.Duration  `yaml:"duration" mapstructure:"duration" json:"duration,omitempty" gorm:"column:duration" bson:"duration,omitempty" dynamodbav:"duration,omitempty" firestore:"duration,omitempty"`
+}
+
+// Pipeline imports the records of an importer with a worker pool, batched writes and checkpoints.
+//   - WriteBatch writes the records of a batch; if it is nil, Write of the importer is called per record.
+//   - Transaction runs the writes of a batch in a transaction, such as func(ctx, callback) error { return tx.Callback(ctx, db, callback) }.
+//     If a batch fails and HandleException is set, the records are written one by one, so that only the bad records are rejected.
+//     Without Transaction, WriteBatch must be atomic, such as a single statement, otherwise the records of a failed batch may be written twice.
+//   - Checkpoints and Hash make the job resumable: if the checkpoint of the job has the same hash, the committed lines are skipped.
+//     If Hash is empty, it is the HashFile of Filename, so a checkpoint is never matched by the file name only.
+//     A job, which is completed, is not imported again; delete its checkpoint to import the same file again.
+type Pipeline[T any, S string | []string] struct {
+	Importer    *Importer[T, S]
+	Config      PipelineConfig
+	WriteBatch  func(ctx context.Context, models []T) error
+	Transaction func(ctx context.Context, callback func(ctx context.Context) error) error
+	Checkpoints CheckpointPort
+	Hash        string
+}
+
+func NewPipeline[T any, S string | []string](importer *Importer[T, S], c PipelineConfig, writeBatch func(context.Context, []T) error, checkpoints CheckpointPort, opts ...func(context.Context, func(context.Context) error) error) *Pipeline[T, S] {
+	var transaction func(context.Context, func(context.Context) error) error
+	if len(opts) > 0 {
+		transaction = opts[0]
+	}
+	if c.Workers <= 0 {
+		c.Workers = 1
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 1
+	}
+	return &Pipeline[T, S]{Importer: importer, Config: c, WriteBatch: writeBatch, Transaction: transaction, Checkpoints: checkpoints}
+}
+
+type record[T any, S string | []string] struct {
+	seq      int
+	line     int
+	position int
+	fileName string
+	raw      S
+	model    T
+	errors   []ErrorMessage
+	err      error
+}
+
+// Import imports the file as the job; if job is empty, the job is the file name.
+func (p *Pipeline[T, S]) Import(ctx context.Context, job string) (*Report, error) {
+	s := p.Importer
+	if len(job) == 0 {
+		job = s.Filename
+	}
+	workers, batchSize := p.Config.Workers, p.Config.BatchSize
+	if workers <= 0 {
+		workers = 1
+	}
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	var committed map[int]bool
+	hash := p.Hash
+	report := &Report{Job: job, Filename: s.Filename, Hash: hash, Errors: make(map[string]int), StartTime: time.Now()}
+	finish := func() *Report {
+		report.EndTime = time.Now()
+		report.Duration = report.EndTime.Sub(report.StartTime)
+		return report
+	}
+	if p.Checkpoints != nil {
+		if len(hash) == 0 {
+			h, err := HashFile(s.Filename)
+			if err != nil {
+				return finish(), fmt.Errorf("hash is required to resume the job '%s': %w", job, err)
+			}
+			hash = h
+			report.Hash = h
+		}
+		c, err := p.Checkpoints.Load(ctx, job)
+		if err != nil {
+			return finish(), err
+		}
+		if c != nil && len(c.Hash) > 0 && c.Hash == hash {
+			report.Resumed = true
+			report.From = c.Line
+			report.Line = c.Line
+			report.Total, report.Success, report.Invalid, report.Failed = c.Total, c.Success, c.Invalid, c.Failed
+			for k, v := range c.Errors {
+				report.Errors[k] = v
+			}
+			if len(c.Lines) > 0 {
+				committed = make(map[int]bool)
+				for _, l := range c.Lines {
+					committed[l] = true
+				}
+			}
+			if c.Completed {
+				report.Completed = true
+				return finish(), nil
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	window := make(chan struct{}, workers*batchSize*2)
+	items := make(chan record[T, S], workers)
+	results := make(chan record[T, S], workers)
+
+	var readErr error
+	go func() {
+		defer close(items)
+		seq := 0
+		position := 0
+		readErr = s.readFile(func(raw S, err error, numLine int, fileName string) error {
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			// the position of a record is its line, or its number in all the files if the importer reads several files
+			position++
+			if s.ReadFile == nil {
+				position = numLine
+			}
+			if position <= report.From || committed[position] {
+				report.Skipped++
+				return nil
+			}
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case items <- record[T, S]{seq: seq, line: numLine, position: position, fileName: fileName, raw: raw}:
+				seq++
+				return nil
+			case <-ctx.Done():
+				<-window
+				return ctx.Err()
+			}
+		})
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range items {
+				if r.err = ctx.Err(); r.err == nil {
+					r.model, r.err = s.Transform(ctx, r.raw)
+					if r.err == nil && s.Validate != nil {
+						r.errors, r.err = s.Validate(ctx, &r.model)
+					}
+				}
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var fatal error
+	var batch []record[T, S]
+	done := make(map[int]int)
+	next, saved := 0, report.Line
+	commit := func(seq int, line int) {
+		report.Total++
+		done[seq] = line
+		for {
+			l, ok := done[next]
+			if !ok {
+				break
+			}
+			delete(done, next)
+			if l > report.Line {
+				report.Line = l
+			}
+			next++
+		}
+	}
+	checkpoint := func(completed bool) *Checkpoint {
+		c := &Checkpoint{Job: job, Filename: s.Filename, Hash: hash, Line: report.Line, Total: report.Total, Success: report.Success,
+			Invalid: report.Invalid, Failed: report.Failed, Errors: report.Errors, Completed: completed, UpdatedAt: time.Now()}
+		for _, l := range done {
+			c.Lines = append(c.Lines, l)
+		}
+		for l := range committed {
+			if l > report.Line {
+				c.Lines = append(c.Lines, l)
+			}
+		}
+		sort.Ints(c.Lines)
+		return c
+	}
+	save := func(completed bool) error {
+		if p.Checkpoints == nil || (!completed && report.Line-saved < p.Config.Checkpoint) {
+			return nil
+		}
+		if err := p.Checkpoints.Save(ctx, checkpoint(completed)); err != nil {
+			return err
+		}
+		saved = report.Line
+		return nil
+	}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		written := false
+		if p.Transaction != nil || p.WriteBatch != nil {
+			err := p.write(ctx, batch)
+			if err == nil {
+				report.Success += len(batch)
+				written = true
+			} else if s.HandleException == nil {
+				return err
+			}
+		}
+		if !written {
+			for i := range batch {
+				r := &batch[i]
+				if err := p.write(ctx, batch[i:i+1]); err != nil {
+					if s.HandleException == nil {
+						return err
+					}
+					s.HandleException(ctx, r.raw, &r.model, err, r.line, r.fileName)
+					report.Failed++
+					report.Errors[Exception]++
+				} else {
+					report.Success++
+				}
+			}
+		}
+		for _, r := range batch {
+			commit(r.seq, r.position)
+			<-window
+		}
+		batch = nil
+		return save(false)
+	}
+	handle := func(r record[T, S]) error {
+		if r.err != nil {
+			if r.fileName != s.Filename {
+				return fmt.Errorf("%s line %d: %w", r.fileName, r.line, r.err)
+			}
+			return fmt.Errorf("line %d: %w", r.line, r.err)
+		}
+		if len(r.errors) > 0 {
+			if s.HandleError != nil {
+				s.HandleError(ctx, r.raw, &r.model, r.errors, r.line, r.fileName)
+			}
+			report.Invalid++
+			for _, e := range r.errors {
+				report.Errors[e.Code]++
+			}
+			commit(r.seq, r.position)
+			<-window
+			return nil
+		}
+		batch = append(batch, r)
+		if len(batch) >= batchSize {
+			return flush()
+		}
+		return nil
+	}
+	pending := make(map[int]record[T, S])
+	expected := 0
+	for r := range results {
+		if fatal != nil {
+			continue
+		}
+		if !p.Config.Ordered {
+			fatal = handle(r)
+		} else {
+			pending[r.seq] = r
+			for fatal == nil {
+				r2, ok := pending[expected]
+				if !ok {
+					break
+				}
+				delete(pending, expected)
+				expected++
+				fatal = handle(r2)
+			}
+		}
+		if fatal != nil {
+			cancel()
+		}
+	}
+	if fatal == nil {
+		fatal = flush()
+	}
+	if fatal == nil && readErr != nil {
+		fatal = readErr
+	}
+	if fatal != nil {
+		if p.Checkpoints != nil {
+			p.Checkpoints.Save(context.Background(), checkpoint(false))
+		}
+		return finish(), fatal
+	}
+	if s.Flush != nil {
+		if err := s.Flush(ctx); err != nil {
+			return finish(), err
+		}
+	}
+	report.Completed = true
+	if err := save(true); err != nil {
+		return finish(), err
+	}
+	return finish(), nil
+}
+func (p *Pipeline[T, S]) write(ctx context.Context, batch []record[T, S]) error {
+	write := func(ctx context.Context) error {
+		if p.WriteBatch != nil {
+			models := make([]T, len(batch))
+			for i := range batch {
+				models[i] = batch[i].model
+			}
+			return p.WriteBatch(ctx, models)
+		}
+		for i := range batch {
+			if err := p.Importer.Write(ctx, &batch[i].model); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if p.Transaction != nil {
+		return p.Transaction(ctx, write)
+	}
+	return write(ctx)
+}
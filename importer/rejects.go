@@ -0,0 +1,348 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ReportJSON = "json"
+	ReportCSV  = "csv"
+)
+
+// Reject is an entry of the error report: an error of a rejected record, with the line and the raw record.
+type Reject struct {
+	Filename string `yaml:"filename" mapstructure:"filename" json:"filename,omitempty" gorm:"column:filename" bson:"filename,omitempty" dynamodbav:"filename,omitempty" firestore:"filename,omitempty"`
+	Line     int    `yaml:"line" mapstructure:"line" json:"line" gorm:"column:line" bson:"line" dynamodbav:"line" firestore:"line"`
+	Field    string `yaml:"field" mapstructure:"field" json:"field,omitempty" gorm:"column:field" bson:"field,omitempty" dynamodbav:"field,omitempty" firestore:"field,omitempty"`
+	Code     string `yaml:"code" mapstructure:"code" json:"code,omitempty" gorm:"column:code" bson:"code,omitempty" dynamodbav:"code,omitempty" firestore:"code,omitempty"`
+	Message  string `yaml:"message" mapstructure:"message" json:"message,omitempty" gorm:"column:message" bson:"message,omitempty" dynamodbav:"message,omitempty" firestore:"message,omitempty"`
+	Param    string `yaml:"param" mapstructure:"param" json:"param,omitempty" gorm:"column:param" bson:"param,omitempty" dynamodbav:"param,omitempty" firestore:"param,omitempty"`
+	Raw      string `yaml:"raw" mapstructure:"raw" json:"raw,omitempty" gorm:"column:raw" bson:"raw,omitempty" dynamodbav:"raw,omitempty" firestore:"raw,omitempty"`
+}
+
+// Summary is the summary of the rejected records: Invalid records have validation errors, Failed records cannot be written; Errors is the number of errors per code, Fields per field.
+type Summary struct {
+	Rejected int            `yaml:"rejected" mapstructure:"rejected" json:"rejected" gorm:"column:rejected" bson:"rejected" dynamodbav:"rejected" firestore:"rejected"`
+	Invalid  int            `yaml:"invalid" mapstructure:"invalid" json:"invalid" gorm:"column:invalid" bson:"invalid" dynamodbav:"invalid" firestore:"invalid"`
+	Failed   int            `yaml:"failed" mapstructure:"failed" json:"failed" gorm:"column:failed" bson:"failed" dynamodbav:"failed" firestore:"failed"`
+	Errors   map[string]int `yaml:"errors" mapstructure:"errors" json:"errors,omitempty" gorm:"-" bson:"errors,omitempty" dynamodbav:"errors,omitempty" firestore:"errors,omitempty"`
+	Fields   map[string]int `yaml:"fields" mapstructure:"fields" json:"fields,omitempty" gorm:"-" bson:"fields,omitempty" dynamodbav:"fields,omitempty" firestore:"fields,omitempty"`
+	Lines    []int          `yaml:"lines" mapstructure:"lines" json:"lines,omitempty" gorm:"-" bson:"lines,omitempty" dynamodbav:"lines,omitempty" firestore:"lines,omitempty"`
+}
+
+// Rejects writes the rejected records to a rejects file, in the format of the source file, so that they can be fixed and imported again, and writes the errors to an error report.
+//   - The records of type string, such as the lines of a fixed-length or a delimited file, are written as they are; the records of type []string are written as CSV with Comma.
+//   - If Transform is set, such as Transform of a transformer of the package writer, the rejects file has the models instead of the raw records.
+//   - The report is a JSON array or a CSV file with the columns line, field, code, message, param and raw; a record has a row per error.
+//   - HandleError and HandleException have the signatures of the handlers of Importer; OnError and OnException are called after, such as the handlers of ErrorHandler to log.
+type Rejects[T any, S string | []string] struct {
+	Out         io.Writer
+	Report      io.Writer
+	Format      string
+	Comma       rune
+	Transform   func(ctx context.Context, model *T) string
+	OnError     func(ctx context.Context, raw S, rs *T, err []ErrorMessage, i int, fileName string)
+	OnException func(ctx context.Context, raw S, rs *T, err error, i int, fileName string)
+	mu          sync.Mutex
+	summary     Summary
+	count       int
+	resumed     bool
+	closed      bool
+	err         error
+	closers     []io.Closer
+}
+
+// NewRejects creates the rejects with opts[0] is the format of the report, json or csv, and opts[1] is the delimiter of the CSV records and of the CSV report; the defaults are json and comma.
+// out or report can be nil, to write only the report or only the rejects file. The header of a CSV report is written at once, so the report of a file without errors has the header.
+func NewRejects[T any, S string | []string](out io.Writer, report io.Writer, opts ...string) *Rejects[T, S] {
+	return newRejects[T, S](out, report, 0, false, opts...)
+}
+func newRejects[T any, S string | []string](out io.Writer, report io.Writer, count int, resumed bool, opts ...string) *Rejects[T, S] {
+	format := ReportJSON
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		format = strings.ToLower(opts[0])
+	}
+	comma := ','
+	if len(opts) > 1 && len(opts[1]) > 0 {
+		comma = []rune(opts[1])[0]
+	}
+	r := &Rejects[T, S]{Out: out, Report: report, Format: format, Comma: comma, summary: Summary{Errors: make(map[string]int), Fields: make(map[string]int)}, count: count, resumed: resumed}
+	if report != nil && format == ReportCSV && count == 0 {
+		r.fail(r.writeCSV([]string{"filename", "line", "field", "code", "message", "param", "raw"}))
+	}
+	return r
+}
+
+// NewRejectFiles creates the rejects file and the report file; the format of the report is from the extension of the report file, .csv or .json. Close closes the files.
+// If resume is true, such as when a Pipeline resumes a job, the files are opened to append, so the rejects of the previous run are kept; the header of the source file
+// is not written again, and a JSON report is reopened to append to its array.
+func NewRejectFiles[T any, S string | []string](rejectsFile string, reportFile string, resume bool, opts ...string) (*Rejects[T, S], error) {
+	var files []io.Closer
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	open := func(name string) (*os.File, error) {
+		if len(name) == 0 {
+			return nil, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+			return nil, err
+		}
+		flag := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+		if resume {
+			flag = os.O_RDWR | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(name, flag, 0644)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		return f, nil
+	}
+	rejectsOut, err := open(rejectsFile)
+	if err != nil {
+		return nil, err
+	}
+	reportOut, err := open(reportFile)
+	if err != nil {
+		closeFiles()
+		return nil, err
+	}
+	format := ReportJSON
+	if strings.EqualFold(filepath.Ext(reportFile), ".csv") {
+		format = ReportCSV
+	}
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		format = strings.ToLower(opts[0])
+	}
+	options := []string{format}
+	if len(opts) > 1 {
+		options = append(options, opts[1])
+	}
+	var out, report io.Writer
+	resumed := false
+	if rejectsOut != nil {
+		out = rejectsOut
+		if resume {
+			info, err := rejectsOut.Stat()
+			if err != nil {
+				closeFiles()
+				return nil, err
+			}
+			resumed = info.Size() > 0
+		}
+	}
+	count := 0
+	if reportOut != nil {
+		report = reportOut
+		if resume {
+			if count, err = reopen(reportOut, format); err != nil {
+				closeFiles()
+				return nil, err
+			}
+		}
+	}
+	r := newRejects[T, S](out, report, count, resumed, options...)
+	r.closers = files
+	return r, nil
+}
+
+// reopen prepares a report of a previous run to append to: a JSON report is truncated before its closing bracket.
+// It returns 1 if the report has the header or an entry, or 0 if it is empty.
+func reopen(f *os.File, format string) (int, error) {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return 0, err
+	}
+	if format == ReportCSV {
+		return 1, nil
+	}
+	n := info.Size()
+	if n > 64 {
+		n = 64
+	}
+	tail := make([]byte, n)
+	if _, err = f.ReadAt(tail, info.Size()-n); err != nil {
+		return 0, err
+	}
+	tail = bytes.TrimRight(tail, " \t\r\n")
+	if !bytes.HasSuffix(tail, []byte("]")) {
+		// the previous run stopped before Close, so the array is not closed
+		return 1, nil
+	}
+	tail = bytes.TrimRight(tail[:len(tail)-1], " \t\r\n")
+	size := info.Size() - n + int64(len(tail))
+	if bytes.HasSuffix(tail, []byte("[")) {
+		size = 0
+	}
+	if err = f.Truncate(size); err != nil || size == 0 {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// WriteHeader writes the header of the source file to the rejects file, such as the header row of a CSV file; it is skipped if the rejects file is resumed.
+func (r *Rejects[T, S]) WriteHeader(header S) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Out == nil || r.resumed {
+		return nil
+	}
+	_, err := io.WriteString(r.Out, r.text(header))
+	return r.fail(err)
+}
+func (r *Rejects[T, S]) HandleError(ctx context.Context, raw S, rs *T, errs []ErrorMessage, i int, fileName string) {
+	r.mu.Lock()
+	r.summary.Invalid++
+	rejects := make([]Reject, 0, len(errs))
+	for _, e := range errs {
+		rejects = append(rejects, Reject{Filename: fileName, Line: i, Field: e.Field, Code: e.Code, Message: e.Message, Param: e.Param})
+	}
+	r.reject(ctx, raw, rs, i, rejects)
+	r.mu.Unlock()
+	if r.OnError != nil {
+		r.OnError(ctx, raw, rs, errs, i, fileName)
+	}
+}
+func (r *Rejects[T, S]) HandleException(ctx context.Context, raw S, rs *T, err error, i int, fileName string) {
+	r.mu.Lock()
+	r.summary.Failed++
+	r.reject(ctx, raw, rs, i, []Reject{{Filename: fileName, Line: i, Code: Exception, Message: err.Error()}})
+	r.mu.Unlock()
+	if r.OnException != nil {
+		r.OnException(ctx, raw, rs, err, i, fileName)
+	}
+}
+func (r *Rejects[T, S]) reject(ctx context.Context, raw S, rs *T, i int, rejects []Reject) {
+	r.summary.Rejected++
+	if i > 0 {
+		r.summary.Lines = append(r.summary.Lines, i)
+	}
+	var text string
+	if r.Transform != nil && rs != nil {
+		text = r.Transform(ctx, rs)
+		if !strings.HasSuffix(text, "\n") {
+			text = text + "\n"
+		}
+	} else {
+		text = r.text(raw)
+	}
+	if r.Out != nil {
+		_, err := io.WriteString(r.Out, text)
+		r.fail(err)
+	}
+	rawText := strings.TrimRight(text, "\r\n")
+	for _, e := range rejects {
+		r.summary.Errors[e.Code]++
+		if len(e.Field) > 0 {
+			r.summary.Fields[e.Field]++
+		}
+		e.Raw = rawText
+		r.fail(r.write(e))
+	}
+}
+func (r *Rejects[T, S]) write(e Reject) error {
+	if r.Report == nil {
+		return nil
+	}
+	if r.Format == ReportCSV {
+		r.count++
+		return r.writeCSV([]string{e.Filename, strconv.Itoa(e.Line), e.Field, e.Code, e.Message, e.Param, e.Raw})
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	prefix := ",\n"
+	if r.count == 0 {
+		prefix = "[\n"
+	}
+	r.count++
+	_, err = io.WriteString(r.Report, prefix+string(data))
+	return err
+}
+func (r *Rejects[T, S]) writeCSV(record []string) error {
+	w := csv.NewWriter(r.Report)
+	w.Comma = r.Comma
+	w.Write(record)
+	w.Flush()
+	return w.Error()
+}
+func (r *Rejects[T, S]) text(raw S) string {
+	switch v := any(raw).(type) {
+	case string:
+		return v + "\n"
+	case []string:
+		var b bytes.Buffer
+		w := csv.NewWriter(&b)
+		w.Comma = r.Comma
+		w.Write(v)
+		w.Flush()
+		return b.String()
+	}
+	return ""
+}
+func (r *Rejects[T, S]) fail(err error) error {
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+// Summary returns the summary of the rejected records.
+func (r *Rejects[T, S]) Summary() Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.summary
+	s.Errors = make(map[string]int, len(r.summary.Errors))
+	for k, v := range r.summary.Errors {
+		s.Errors[k] = v
+	}
+	s.Fields = make(map[string]int, len(r.summary.Fields))
+	for k, v := range r.summary.Fields {
+		s.Fields[k] = v
+	}
+	s.Lines = append([]int(nil), r.summary.Lines...)
+	return s
+}
+
+// Err returns the first error to write the rejects file or the report.
+func (r *Rejects[T, S]) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close ends the JSON report, which is an empty array if there is no error, and closes the files of NewRejectFiles; it can be called more than once.
+func (r *Rejects[T, S]) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return r.err
+	}
+	r.closed = true
+	if r.Report != nil && r.Format != ReportCSV {
+		end := "\n]\n"
+		if r.count == 0 {
+			end = "[]\n"
+		}
+		_, err := io.WriteString(r.Report, end)
+		r.fail(err)
+	}
+	for _, c := range r.closers {
+		r.fail(c.Close())
+	}
+	r.closers = nil
+	return r.err
+}
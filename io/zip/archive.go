@@ -0,0 +1,152 @@
+package zip
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path"
+	"strings"
+)
+
+// Decompress returns a reader of the decompressed content if r is a gzip stream, otherwise a reader of r; the content is detected by the magic number, not by the file name.
+func Decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// Walk calls next for each file of a stream: the members of a zip or a tar archive, or the stream itself if it is not an archive.
+//   - A gzip stream is decompressed first, so .gz and .tar.gz files are supported; the name of a .gz file is the name without .gz.
+//   - The directories are skipped, and the name of a member is its path in the archive.
+//   - A zip archive needs random access: if r is an io.ReaderAt and an io.Seeker, such as *os.File or multipart.File, it is read in place, see ReaderAt, otherwise it is buffered in memory.
+func Walk(name string, r io.Reader, next func(name string, r io.Reader) error) error {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	if len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		if strings.HasSuffix(strings.ToLower(name), ".tgz") {
+			name = name[:len(name)-4] + ".tar"
+		} else if strings.HasSuffix(strings.ToLower(name), ".gz") {
+			name = name[:len(name)-3]
+		}
+		return Walk(name, gr, next)
+	}
+	if bytes.Equal(magic, []byte("PK\x03\x04")) || bytes.Equal(magic, []byte("PK\x05\x06")) {
+		var zr *zip.Reader
+		var err error
+		if ra, size, ok := ReaderAt(r); ok {
+			zr, err = zip.NewReader(ra, size)
+		} else {
+			var data []byte
+			data, err = io.ReadAll(br)
+			if err != nil {
+				return err
+			}
+			zr, err = zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		}
+		if err != nil {
+			return err
+		}
+		return walkZip(zr, next)
+	}
+	header, _ := br.Peek(262)
+	if len(header) >= 262 && string(header[257:262]) == "ustar" {
+		tr := tar.NewReader(br)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if !h.FileInfo().Mode().IsRegular() {
+				continue
+			}
+			if err = next(h.Name, tr); err != nil {
+				return err
+			}
+		}
+	}
+	return next(name, br)
+}
+
+// ReaderAt returns r as an io.ReaderAt and its size, if r is an io.ReaderAt with a Size method or an io.Seeker.
+func ReaderAt(r io.Reader) (io.ReaderAt, int64, bool) {
+	ra, ok := r.(io.ReaderAt)
+	if !ok {
+		return nil, 0, false
+	}
+	if s, ok := r.(interface{ Size() int64 }); ok {
+		return ra, s.Size(), true
+	}
+	if s, ok := r.(io.Seeker); ok {
+		current, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, false
+		}
+		size, err := s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, false
+		}
+		s.Seek(current, io.SeekStart)
+		return ra, size, true
+	}
+	return nil, 0, false
+}
+func walkZip(zr *zip.Reader, next func(name string, r io.Reader) error) error {
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = next(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type writeCloser struct {
+	io.Writer
+	close func() error
+}
+
+func (w writeCloser) Close() error {
+	return w.close()
+}
+
+// NewWriter returns a writer, which compresses by the extension of name: .gz with gzip, .zip with a zip archive, which has a member of the name without .zip; otherwise the content is written as it is.
+// Close must be called to complete the compressed stream; it does not close w.
+func NewWriter(w io.Writer, name string) (io.WriteCloser, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".gz"):
+		gw := gzip.NewWriter(w)
+		gw.Name = path.Base(name[:len(name)-3])
+		return gw, nil
+	case strings.HasSuffix(lower, ".zip"):
+		zw := zip.NewWriter(w)
+		f, err := zw.Create(path.Base(name[:len(name)-4]))
+		if err != nil {
+			return nil, err
+		}
+		return writeCloser{Writer: f, close: zw.Close}, nil
+	}
+	return writeCloser{Writer: w, close: func() error { return nil }}, nil
+}
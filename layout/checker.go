@@ -0,0 +1,128 @@
+package layout
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	Count = "count"
+	Sum   = "sum"
+)
+
+// Checker counts the detail records and sums their numeric fields, to check the fields of a trailer with Check, or to build a trailer.
+//   - "count" is the number of the detail records, and "count:record" is the number of the records of a name.
+//   - "sum:field" is the sum of a field of the detail records, and "sum:record.field" is the sum of a field of the records of a name.
+type Checker struct {
+	Layout *Layout
+	counts map[string]int64
+	sums   map[string]*big.Rat
+}
+
+func NewChecker(layout *Layout) *Checker {
+	return &Checker{Layout: layout, counts: make(map[string]int64), sums: make(map[string]*big.Rat)}
+}
+
+// Add adds a decoded record; the headers and the trailers are not counted.
+func (c *Checker) Add(r *Record, values map[string]interface{}) error {
+	if r.Kind != Detail {
+		return nil
+	}
+	c.counts[""]++
+	c.counts[r.Name]++
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		if f.Type != Int && f.Type != Decimal {
+			continue
+		}
+		v, ok := values[f.Name]
+		if !ok {
+			continue
+		}
+		n, err := rat(v)
+		if err != nil {
+			return &FieldError{Record: r.Name, Field: f.Name, Value: fmt.Sprint(v), Err: err}
+		}
+		c.add(f.Name, n)
+		c.add(r.Name+"."+f.Name, n)
+	}
+	return nil
+}
+
+// Reset clears the counts and the sums, such as after a trailer of a batch of a file with several batches.
+func (c *Checker) Reset() {
+	c.counts = make(map[string]int64)
+	c.sums = make(map[string]*big.Rat)
+}
+
+// Check checks the fields of a trailer with Check.
+func (c *Checker) Check(r *Record, values map[string]interface{}) error {
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		if len(f.Check) == 0 {
+			continue
+		}
+		expected, err := c.Value(f.Check)
+		if err != nil {
+			return &FieldError{Record: r.Name, Field: f.Name, Err: err}
+		}
+		v, ok := values[f.Name]
+		if !ok {
+			v = int64(0)
+		}
+		actual, err := rat(v)
+		if err != nil {
+			return &FieldError{Record: r.Name, Field: f.Name, Value: fmt.Sprint(v), Err: err}
+		}
+		if actual.Cmp(expected) != 0 {
+			return &FieldError{Record: r.Name, Field: f.Name, Value: actual.FloatString(f.Scale), Err: fmt.Errorf("%s is %s", f.Check, expected.FloatString(f.Scale))}
+		}
+	}
+	return nil
+}
+
+// Values returns the values of the fields of a record with Check, such as the count and the total of a trailer to write.
+func (c *Checker) Values(r *Record) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		if len(f.Check) == 0 {
+			continue
+		}
+		v, err := c.Value(f.Check)
+		if err != nil {
+			return values, &FieldError{Record: r.Name, Field: f.Name, Err: err}
+		}
+		values[f.Name] = v
+	}
+	return values, nil
+}
+
+// Value returns the value of a check, such as count, count:payment or sum:amount.
+func (c *Checker) Value(check string) (*big.Rat, error) {
+	name, param := check, ""
+	if i := strings.Index(check, ":"); i >= 0 {
+		name, param = check[:i], check[i+1:]
+	}
+	switch name {
+	case Count:
+		return new(big.Rat).SetInt64(c.counts[param]), nil
+	case Sum:
+		if len(param) == 0 {
+			return nil, fmt.Errorf("check '%s' must have a field", check)
+		}
+		if n, ok := c.sums[param]; ok {
+			return new(big.Rat).Set(n), nil
+		}
+		return new(big.Rat), nil
+	}
+	return nil, fmt.Errorf("check '%s' is not supported", check)
+}
+func (c *Checker) add(key string, n *big.Rat) {
+	if sum, ok := c.sums[key]; ok {
+		sum.Add(sum, n)
+	} else {
+		c.sums[key] = new(big.Rat).Set(n)
+	}
+}
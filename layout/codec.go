@@ -0,0 +1,513 @@
+package layout
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError is an error of a field of a record.
+type FieldError struct {
+	Record string
+	Field  string
+	Value  string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s.%s '%s': %v", e.Record, e.Field, e.Value, e.Err)
+}
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+var ErrRequired = errors.New("is required")
+
+// Decode decodes a line to a map by the record, which is selected by the discriminator; the values are string, int64, float64, time.Time or bool, by the types of the fields, and the empty values are not in the map.
+func (l *Layout) Decode(line string) (*Record, map[string]interface{}, error) {
+	r, texts, err := l.texts(line)
+	if err != nil {
+		return r, nil, err
+	}
+	values := make(map[string]interface{}, len(r.Fields))
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		v, err := parse(f, texts[i])
+		if err != nil {
+			return r, values, &FieldError{Record: r.Name, Field: f.Name, Value: texts[i], Err: err}
+		}
+		if v != nil {
+			values[f.Name] = v
+		}
+	}
+	return r, values, nil
+}
+
+// DecodeTo decodes a line to v, which is a pointer to a struct or to a map[string]interface{}; the fields of the struct are matched by Field, with the json names or the names of the fields.
+func (l *Layout) DecodeTo(line string, v interface{}) (*Record, error) {
+	if m, ok := v.(*map[string]interface{}); ok {
+		r, values, err := l.Decode(line)
+		if err != nil {
+			return r, err
+		}
+		*m = values
+		return r, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("v must be a pointer to a struct or to a map[string]interface{}")
+	}
+	r, texts, err := l.texts(line)
+	if err != nil {
+		return r, err
+	}
+	model := rv.Elem()
+	indexes := getIndexes(model.Type())
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		value, err := parse(f, texts[i])
+		if err != nil {
+			return r, &FieldError{Record: r.Name, Field: f.Name, Value: texts[i], Err: err}
+		}
+		index, ok := indexes[f.Field]
+		if !ok || value == nil {
+			continue
+		}
+		if err = set(model.FieldByIndex(index), f, value, texts[i]); err != nil {
+			return r, &FieldError{Record: r.Name, Field: f.Name, Value: texts[i], Err: err}
+		}
+	}
+	return r, nil
+}
+
+// Encode encodes v, which is a struct, a pointer to a struct or a map[string]interface{}, to a line of the record of a name; the fields with Value are written with Value if they are empty.
+func (l *Layout) Encode(name string, v interface{}) (string, error) {
+	r, ok := l.Record(name)
+	if !ok {
+		return "", fmt.Errorf("record %s is not defined in layout %s", name, l.Name)
+	}
+	get, err := getter(v)
+	if err != nil {
+		return "", err
+	}
+	texts := make([]string, len(r.Fields))
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		value := get(f)
+		text, err := format(f, value)
+		if err != nil {
+			return "", &FieldError{Record: r.Name, Field: f.Name, Value: fmt.Sprint(value), Err: err}
+		}
+		if len(text) == 0 {
+			text = f.Value
+		}
+		if len(text) == 0 && f.Required {
+			return "", &FieldError{Record: r.Name, Field: f.Name, Err: ErrRequired}
+		}
+		texts[i] = text
+	}
+	if l.Format == Delimited {
+		return l.join(r, texts), nil
+	}
+	line := []byte(strings.Repeat(" ", r.Length))
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		text, err := pad(f, texts[i])
+		if err != nil {
+			return "", &FieldError{Record: r.Name, Field: f.Name, Value: texts[i], Err: err}
+		}
+		end := f.Start - 1 + f.Length
+		if end > len(line) {
+			line = append(line, []byte(strings.Repeat(" ", end-len(line)))...)
+		}
+		copy(line[f.Start-1:end], text)
+	}
+	return string(line), nil
+}
+
+func (l *Layout) texts(line string) (*Record, []string, error) {
+	r, err := l.Match(line)
+	if err != nil {
+		return nil, nil, err
+	}
+	texts := make([]string, len(r.Fields))
+	if l.Format == Delimited {
+		columns, err := l.split(line)
+		if err != nil {
+			return r, nil, err
+		}
+		for i := range r.Fields {
+			if r.Fields[i].Index < len(columns) {
+				texts[i] = strings.TrimSpace(columns[r.Fields[i].Index])
+			}
+		}
+	} else {
+		for i := range r.Fields {
+			f := &r.Fields[i]
+			start := f.Start - 1
+			if start >= len(line) {
+				continue
+			}
+			end := start + f.Length
+			if end > len(line) {
+				end = len(line)
+			}
+			texts[i] = trim(f, line[start:end])
+		}
+	}
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		if len(texts[i]) == 0 && f.Required {
+			return r, nil, &FieldError{Record: r.Name, Field: f.Name, Err: ErrRequired}
+		}
+		if len(f.Value) > 0 && texts[i] != f.Value {
+			return r, nil, &FieldError{Record: r.Name, Field: f.Name, Value: texts[i], Err: fmt.Errorf("must be '%s'", f.Value)}
+		}
+	}
+	return r, texts, nil
+}
+func (l *Layout) split(line string) ([]string, error) {
+	if utf8.RuneCountInString(l.Delimiter) != 1 {
+		return strings.Split(line, l.Delimiter), nil
+	}
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.Comma, _ = utf8.DecodeRuneInString(l.Delimiter)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.Read()
+}
+func (l *Layout) join(r *Record, texts []string) string {
+	size := 0
+	for i := range r.Fields {
+		if r.Fields[i].Index >= size {
+			size = r.Fields[i].Index + 1
+		}
+	}
+	columns := make([]string, size)
+	for i := range r.Fields {
+		text := texts[i]
+		if strings.Contains(text, l.Delimiter) || strings.ContainsAny(text, "\"\r\n") {
+			text = `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+		}
+		columns[r.Fields[i].Index] = text
+	}
+	return strings.Join(columns, l.Delimiter)
+}
+
+func trim(f *Field, text string) string {
+	if f.Pad != " " && f.Type != Int && f.Type != Decimal {
+		if f.Align == Right {
+			text = strings.TrimLeft(text, f.Pad)
+		} else {
+			text = strings.TrimRight(text, f.Pad)
+		}
+	}
+	return strings.TrimSpace(text)
+}
+func pad(f *Field, text string) (string, error) {
+	size := utf8.RuneCountInString(text)
+	if size > f.Length {
+		return "", fmt.Errorf("length is %d, but the maximum length is %d", size, f.Length)
+	}
+	padding := strings.Repeat(f.Pad, f.Length-size)
+	if f.Align != Right {
+		return text + padding, nil
+	}
+	if f.Pad == "0" && strings.HasPrefix(text, "-") {
+		return "-" + padding + text[1:], nil
+	}
+	return padding + text, nil
+}
+
+func parse(f *Field, text string) (interface{}, error) {
+	if len(text) == 0 {
+		return nil, nil
+	}
+	switch f.Type {
+	case Int:
+		return strconv.ParseInt(text, 10, 64)
+	case Decimal:
+		return strconv.ParseFloat(decimalText(f, text), 64)
+	case Date:
+		return time.Parse(f.Format, text)
+	case Bool:
+		if len(f.Format) > 0 {
+			values := strings.Split(f.Format, "|")
+			if text == values[0] {
+				return true, nil
+			}
+			if len(values) > 1 && text == values[1] {
+				return false, nil
+			}
+			return nil, fmt.Errorf("must be one of '%s'", f.Format)
+		}
+		return strconv.ParseBool(text)
+	default:
+		return text, nil
+	}
+}
+
+// decimalText inserts the decimal point of an implied decimal.
+func decimalText(f *Field, text string) string {
+	if !f.Implied || f.Scale <= 0 {
+		return text
+	}
+	sign := ""
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		sign = text[:1]
+		text = text[1:]
+	}
+	if len(text) <= f.Scale {
+		text = strings.Repeat("0", f.Scale-len(text)+1) + text
+	}
+	return sign + text[:len(text)-f.Scale] + "." + text[len(text)-f.Scale:]
+}
+
+func format(f *Field, v interface{}) (string, error) {
+	if s, ok := v.(string); v == nil || ok && len(strings.TrimSpace(s)) == 0 {
+		return "", nil
+	}
+	switch f.Type {
+	case Int:
+		r, err := rat(v)
+		if err != nil {
+			return "", err
+		}
+		if !r.IsInt() {
+			return "", errors.New("must be an integer")
+		}
+		return r.Num().String(), nil
+	case Decimal:
+		r, err := rat(v)
+		if err != nil {
+			return "", err
+		}
+		text := r.FloatString(f.Scale)
+		if f.Implied {
+			text = strings.Replace(text, ".", "", 1)
+		}
+		return text, nil
+	case Date:
+		switch t := v.(type) {
+		case time.Time:
+			if t.IsZero() {
+				return "", nil
+			}
+			return t.Format(f.Format), nil
+		case string:
+			return t, nil
+		}
+		return "", fmt.Errorf("cannot format %T as a date", v)
+	case Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Sprint(v), nil
+		}
+		if len(f.Format) > 0 {
+			values := strings.Split(f.Format, "|")
+			if b {
+				return values[0], nil
+			}
+			if len(values) > 1 {
+				return values[1], nil
+			}
+			return "", nil
+		}
+		return strconv.FormatBool(b), nil
+	default:
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case time.Time:
+			if len(f.Format) > 0 {
+				return s.Format(f.Format), nil
+			}
+		}
+		return fmt.Sprint(v), nil
+	}
+}
+
+// rat converts a number, such as an int, a float64, a *big.Float or a string, to a *big.Rat, so that the decimals are formatted and summed without rounding errors.
+func rat(v interface{}) (*big.Rat, error) {
+	r := new(big.Rat)
+	switch n := v.(type) {
+	case *big.Rat:
+		return r.Set(n), nil
+	case big.Rat:
+		return r.Set(&n), nil
+	case *big.Float:
+		if n == nil {
+			return r, nil
+		}
+		return ratString(r, n.Text('f', -1))
+	case big.Float:
+		return ratString(r, n.Text('f', -1))
+	case *big.Int:
+		return r.SetInt(n), nil
+	case float32:
+		return ratString(r, strconv.FormatFloat(float64(n), 'f', -1, 32))
+	case float64:
+		return ratString(r, strconv.FormatFloat(n, 'f', -1, 64))
+	case string:
+		return ratString(r, strings.TrimSpace(n))
+	case fmt.Stringer:
+		return ratString(r, n.String())
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.SetInt64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return r.SetInt(new(big.Int).SetUint64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return ratString(r, strconv.FormatFloat(rv.Float(), 'f', -1, 64))
+	}
+	return nil, fmt.Errorf("cannot format %T as a number", v)
+}
+
+// SetString of big.Rat returns a bool, not an error.
+func ratString(r *big.Rat, s string) (*big.Rat, error) {
+	if _, ok := r.SetString(s); !ok {
+		return nil, fmt.Errorf("'%s' is not a number", s)
+	}
+	return r, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var bigFloatType = reflect.TypeOf(big.Float{})
+var indexCache sync.Map
+
+// getIndexes returns the indexes of the fields of a struct by the json names and by the names of the fields.
+func getIndexes(modelType reflect.Type) map[string][]int {
+	if v, ok := indexCache.Load(modelType); ok {
+		return v.(map[string][]int)
+	}
+	indexes := make(map[string][]int)
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		indexes[field.Name] = field.Index
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			name := strings.Split(tag, ",")[0]
+			if len(name) > 0 && name != "-" {
+				indexes[name] = field.Index
+			}
+		}
+	}
+	indexCache.Store(modelType, indexes)
+	return indexes
+}
+func getter(v interface{}) (func(f *Field) interface{}, error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		return func(f *Field) interface{} {
+			if value, ok := m[f.Field]; ok {
+				return value
+			}
+			return m[f.Name]
+		}, nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	indexes := getIndexes(rv.Type())
+	return func(f *Field) interface{} {
+		index, ok := indexes[f.Field]
+		if !ok {
+			return nil
+		}
+		field := rv.FieldByIndex(index)
+		for field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				return nil
+			}
+			field = field.Elem()
+		}
+		if field.Type() == bigFloatType {
+			return field.Addr().Interface()
+		}
+		return field.Interface()
+	}, nil
+}
+func set(field reflect.Value, f *Field, value interface{}, text string) error {
+	if field.Kind() == reflect.Ptr {
+		v := reflect.New(field.Type().Elem())
+		if err := set(v.Elem(), f, value, text); err != nil {
+			return err
+		}
+		field.Set(v)
+		return nil
+	}
+	if field.Type() == timeType {
+		t, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("cannot set %T to time.Time", value)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if field.Type() == bigFloatType {
+		n, _, err := big.ParseFloat(decimalText(f, text), 10, 256, big.ToNearestEven)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(*n))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			field.SetString(s)
+		} else if f.Type == Decimal {
+			field.SetString(decimalText(f, text))
+		} else {
+			field.SetString(text)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("cannot set %T to %s", value, field.Type())
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %s", n, field.Type())
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := value.(int64)
+		if !ok || n < 0 || field.OverflowUint(uint64(n)) {
+			return fmt.Errorf("cannot set %v to %s", value, field.Type())
+		}
+		field.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		switch n := value.(type) {
+		case float64:
+			field.SetFloat(n)
+		case int64:
+			field.SetFloat(float64(n))
+		default:
+			return fmt.Errorf("cannot set %T to %s", value, field.Type())
+		}
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("cannot set %T to bool", value)
+		}
+		field.SetBool(b)
+	default:
+		v := reflect.ValueOf(value)
+		if !v.Type().ConvertibleTo(field.Type()) {
+			return fmt.Errorf("cannot set %T to %s", value, field.Type())
+		}
+		field.Set(v.Convert(field.Type()))
+	}
+	return nil
+}
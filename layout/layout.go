@@ -0,0 +1,253 @@
+package layout
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FixedLength = "fixed"
+	Delimited   = "delimited"
+
+	Header  = "header"
+	Detail  = "detail"
+	Trailer = "trailer"
+
+	String  = "string"
+	Int     = "int"
+	Decimal = "decimal"
+	Date    = "date"
+	Bool    = "bool"
+
+	Left  = "left"
+	Right = "right"
+)
+
+// Field is a field of a record.
+//   - Start is the position of the field from 1, for the fixed-length files; if it is 0, the field is after the previous field. Index is the column from 0, for the delimited files; if it is 0 and it is not the first field, the field is after the previous field.
+//   - Type is string, int, decimal, date or bool. Format is the layout of a date, such as 20060102, or the true and the false values of a bool, such as Y|N.
+//   - Scale is the number of decimals; if Implied is true, the decimal point is not in the file, such as 000001250 for 12.50 with Length 9 and Scale 2.
+//   - Pad and Align are the padding of the fixed-length fields: the defaults are a space and left for the strings, 0 and right for the numbers.
+//   - Value is a constant, such as the record type; it is written as it is, and it is checked when the record is read.
+//   - Check is a check of a trailer field: count, or count:record, is the number of the detail records; sum:field is the sum of a field of the detail records.
+//   - Field is the json name or the name of the field of the struct; the default is Name.
+type Field struct {
+	Name     string `yaml:"name" mapstructure:"name" json:"name,omitempty" gorm:"column:name" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
+	Field    string `yaml:"field" mapstructure:"field" json:"field,omitempty" gorm:"column:field" bson:"field,omitempty" dynamodbav:"field,omitempty" firestore:"field,omitempty"`
+	Start    int    `yaml:"start" mapstructure:"start" json:"start,omitempty" gorm:"column:start" bson:"start,omitempty" dynamodbav:"start,omitempty" firestore:"start,omitempty"`
+	Length   int    `yaml:"length" mapstructure:"length" json:"length,omitempty" gorm:"column:length" bson:"length,omitempty" dynamodbav:"length,omitempty" firestore:"length,omitempty"`
+	Index    int    `yaml:"index" mapstructure:"index" json:"index,omitempty" gorm:"column:index" bson:"index,omitempty" dynamodbav:"index,omitempty" firestore:"index,omitempty"`
+	Type     string `yaml:"type" mapstructure:"type" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
+	Format   string `yaml:"format" mapstructure:"format" json:"format,omitempty" gorm:"column:format" bson:"format,omitempty" dynamodbav:"format,omitempty" firestore:"format,omitempty"`
+	Scale    int    `yaml:"scale" mapstructure:"scale" json:"scale,omitempty" gorm:"column:scale" bson:"scale,omitempty" dynamodbav:"scale,omitempty" firestore:"scale,omitempty"`
+	Implied  bool   `yaml:"implied" mapstructure:"implied" json:"implied,omitempty" gorm:"column:implied" bson:"implied,omitempty" dynamodbav:"implied,omitempty" firestore:"implied,omitempty"`
+	Pad      string `yaml:"pad" mapstructure:"pad" json:"pad,omitempty" gorm:"column:pad" bson:"pad,omitempty" dynamodbav:"pad,omitempty" firestore:"pad,omitempty"`
+	Align    string `yaml:"align" mapstructure:"align" json:"align,omitempty" gorm:"column:align" bson:"align,omitempty" dynamodbav:"align,omitempty" firestore:"align,omitempty"`
+	Required bool   `yaml:"required" mapstructure:"required" json:"required,omitempty" gorm:"column:required" bson:"required,omitempty" dynamodbav:"required,omitempty" firestore:"required,omitempty"`
+	Value    string `yaml:"value" mapstructure:"value" json:"value,omitempty" gorm:"column:value" bson:"value,omitempty" dynamodbav:"value,omitempty" firestore:"value,omitempty"`
+	Check    string `yaml:"check" mapstructure:"check" json:"check,omitempty" gorm:"column:check" bson:"check,omitempty" dynamodbav:"check,omitempty" firestore:"check,omitempty"`
+}
+
+// Record is a type of record: Kind is header, detail or trailer, and Match is the value of the discriminator of the record.
+type Record struct {
+	Name   string  `yaml:"name" mapstructure:"name" json:"name,omitempty" gorm:"column:name" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
+	Kind   string  `yaml:"kind" mapstructure:"kind" json:"kind,omitempty" gorm:"column:kind" bson:"kind,omitempty" dynamodbav:"kind,omitempty" firestore:"kind,omitempty"`
+	Match  string  `yaml:"match" mapstructure:"match" json:"match,omitempty" gorm:"column:match" bson:"match,omitempty" dynamodbav:"match,omitempty" firestore:"match,omitempty"`
+	Length int     `yaml:"length" mapstructure:"length" json:"length,omitempty" gorm:"column:length" bson:"length,omitempty" dynamodbav:"length,omitempty" firestore:"length,omitempty"`
+	Fields []Field `yaml:"fields" mapstructure:"fields" json:"fields,omitempty" gorm:"-" bson:"fields,omitempty" dynamodbav:"fields,omitempty" firestore:"fields,omitempty"`
+}
+
+// Discriminator is the position of the record type: Start and Length for the fixed-length files, Index for the delimited files.
+type Discriminator struct {
+	Start  int `yaml:"start" mapstructure:"start" json:"start,omitempty" gorm:"column:start" bson:"start,omitempty" dynamodbav:"start,omitempty" firestore:"start,omitempty"`
+	Length int `yaml:"length" mapstructure:"length" json:"length,omitempty" gorm:"column:length" bson:"length,omitempty" dynamodbav:"length,omitempty" firestore:"length,omitempty"`
+	Index  int `yaml:"index" mapstructure:"index" json:"index,omitempty" gorm:"column:index" bson:"index,omitempty" dynamodbav:"index,omitempty" firestore:"index,omitempty"`
+}
+
+// Layout is the definition of the records of a file, such as a bank file, which is loaded from YAML or JSON, so that a change of the layout does not need a change of the code.
+//
+//	name: payments
+//	format: fixed
+//	discriminator: {start: 1, length: 1}
+//	records:
+//	  - {name: header, kind: header, match: H, fields: [{name: type, length: 1, value: H}, {name: date, length: 8, type: date, format: "20060102"}]}
+//	  - {name: payment, kind: detail, match: D, fields: [{name: type, length: 1, value: D}, {name: account, length: 10}, {name: amount, length: 12, type: decimal, scale: 2, implied: true}]}
+//	  - {name: trailer, kind: trailer, match: T, fields: [{name: type, length: 1, value: T}, {name: count, length: 6, type: int, check: count}, {name: total, length: 14, type: decimal, scale: 2, implied: true, check: sum:amount}]}
+type Layout struct {
+	Name          string         `yaml:"name" mapstructure:"name" json:"name,omitempty" gorm:"column:name" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
+	Format        string         `yaml:"format" mapstructure:"format" json:"format,omitempty" gorm:"column:format" bson:"format,omitempty" dynamodbav:"format,omitempty" firestore:"format,omitempty"`
+	Delimiter     string         `yaml:"delimiter" mapstructure:"delimiter" json:"delimiter,omitempty" gorm:"column:delimiter" bson:"delimiter,omitempty" dynamodbav:"delimiter,omitempty" firestore:"delimiter,omitempty"`
+	Discriminator *Discriminator `yaml:"discriminator" mapstructure:"discriminator" json:"discriminator,omitempty" gorm:"-" bson:"discriminator,omitempty" dynamodbav:"discriminator,omitempty" firestore:"discriminator,omitempty"`
+	Records       []Record       `yaml:"records" mapstructure:"records" json:"records,omitempty" gorm:"-" bson:"records,omitempty" dynamodbav:"records,omitempty" firestore:"records,omitempty"`
+}
+
+// Load loads a layout from a YAML or a JSON file, and compiles it.
+func Load(fileName string) (*Layout, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(fileName), ".json") {
+		return ParseJSON(data)
+	}
+	return Parse(data)
+}
+
+// Parse parses a layout from YAML, and compiles it.
+func Parse(data []byte) (*Layout, error) {
+	var l Layout
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return nil, err
+	}
+	return &l, l.Compile()
+}
+
+// ParseJSON parses a layout from JSON, and compiles it.
+func ParseJSON(data []byte) (*Layout, error) {
+	var l Layout
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, err
+	}
+	return &l, l.Compile()
+}
+
+// Compile sets the defaults and the positions of the fields, and checks the layout; it must be called if the layout is not created by Load, Parse or ParseJSON.
+func (l *Layout) Compile() error {
+	if len(l.Format) == 0 {
+		l.Format = FixedLength
+	}
+	if l.Format != FixedLength && l.Format != Delimited {
+		return fmt.Errorf("layout %s: format '%s' is not supported", l.Name, l.Format)
+	}
+	if l.Format == Delimited && len(l.Delimiter) == 0 {
+		l.Delimiter = ","
+	}
+	if len(l.Records) == 0 {
+		return fmt.Errorf("layout %s has no record", l.Name)
+	}
+	if len(l.Records) > 1 && l.Discriminator == nil {
+		return fmt.Errorf("layout %s has several records, but no discriminator", l.Name)
+	}
+	if l.Discriminator != nil && l.Format == FixedLength && (l.Discriminator.Start <= 0 || l.Discriminator.Length <= 0) {
+		return fmt.Errorf("layout %s: the discriminator must have start and length", l.Name)
+	}
+	names := make(map[string]bool)
+	for i := range l.Records {
+		r := &l.Records[i]
+		if len(r.Kind) == 0 {
+			r.Kind = Detail
+		}
+		if r.Kind != Header && r.Kind != Detail && r.Kind != Trailer {
+			return fmt.Errorf("record %s: kind '%s' is not supported", r.Name, r.Kind)
+		}
+		if len(r.Name) == 0 {
+			r.Name = r.Kind
+		}
+		if names[r.Name] {
+			return fmt.Errorf("layout %s: record %s is duplicated", l.Name, r.Name)
+		}
+		names[r.Name] = true
+		position := 1
+		for j := range r.Fields {
+			f := &r.Fields[j]
+			if len(f.Name) == 0 {
+				return fmt.Errorf("record %s: field %d has no name", r.Name, j+1)
+			}
+			if len(f.Type) == 0 {
+				f.Type = String
+			}
+			switch f.Type {
+			case String, Int, Decimal, Date, Bool:
+			default:
+				return fmt.Errorf("field %s.%s: type '%s' is not supported", r.Name, f.Name, f.Type)
+			}
+			if f.Type == Date && len(f.Format) == 0 {
+				f.Format = "2006-01-02"
+			}
+			if len(f.Field) == 0 {
+				f.Field = f.Name
+			}
+			if len(f.Check) > 0 && !strings.HasPrefix(f.Check, Count) && !strings.HasPrefix(f.Check, Sum+":") {
+				return fmt.Errorf("field %s.%s: check '%s' is not supported", r.Name, f.Name, f.Check)
+			}
+			if len(f.Align) == 0 {
+				if f.Type == Int || f.Type == Decimal {
+					f.Align = Right
+				} else {
+					f.Align = Left
+				}
+			}
+			if len(f.Pad) == 0 {
+				if f.Type == Int || f.Type == Decimal {
+					f.Pad = "0"
+				} else {
+					f.Pad = " "
+				}
+			}
+			if l.Format == FixedLength {
+				if f.Length <= 0 {
+					return fmt.Errorf("field %s.%s must have length", r.Name, f.Name)
+				}
+				if f.Start <= 0 {
+					f.Start = position
+				}
+				position = f.Start + f.Length
+			} else {
+				if j > 0 && f.Index == 0 {
+					f.Index = r.Fields[j-1].Index + 1
+				}
+			}
+		}
+		if l.Format == FixedLength && r.Length <= 0 {
+			r.Length = position - 1
+		}
+	}
+	return nil
+}
+
+// Record returns the record of a name.
+func (l *Layout) Record(name string) (*Record, bool) {
+	for i := range l.Records {
+		if l.Records[i].Name == name {
+			return &l.Records[i], true
+		}
+	}
+	return nil, false
+}
+
+// Match returns the record of a line by the discriminator.
+func (l *Layout) Match(line string) (*Record, error) {
+	if l.Discriminator == nil {
+		return &l.Records[0], nil
+	}
+	var value string
+	if l.Format == FixedLength {
+		start := l.Discriminator.Start - 1
+		end := start + l.Discriminator.Length
+		if end > len(line) {
+			return nil, fmt.Errorf("the line is shorter than the discriminator")
+		}
+		value = line[start:end]
+	} else {
+		columns, err := l.split(line)
+		if err != nil {
+			return nil, err
+		}
+		if l.Discriminator.Index >= len(columns) {
+			return nil, fmt.Errorf("the line has no column %d of the discriminator", l.Discriminator.Index)
+		}
+		value = columns[l.Discriminator.Index]
+	}
+	value = strings.TrimSpace(value)
+	for i := range l.Records {
+		if l.Records[i].Match == value {
+			return &l.Records[i], nil
+		}
+	}
+	return nil, fmt.Errorf("record type '%s' is not defined in layout %s", value, l.Name)
+}
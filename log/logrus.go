@@ -64,6 +64,28 @@ func Initialize(c Config, opts...func(logLocation string, rotationTime time.Dura
 	return l
 }
 
+// SetLevel changes the level of the logger at runtime.
+func SetLevel(level string) error {
+	lv, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	if logger != nil {
+		logger.SetLevel(lv)
+	}
+	logrus.SetLevel(lv)
+	return nil
+}
+
+// Reload applies the level of the new config. It can be subscribed to a config watcher with config.Select.
+func Reload(before Config, after Config) {
+	if before.Level != after.Level && len(after.Level) > 0 {
+		if err := SetLevel(after.Level); err != nil {
+			logrus.Errorf("Can't parse LOG_LEVEL: %s.", after.Level)
+		}
+	}
+}
+
 func IsTraceEnable() bool {
 	return logrus.IsLevelEnabled(logrus.TraceLevel)
 }
@@ -0,0 +1,147 @@
+package mail
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// CaptureMailSender keeps the mails in memory instead of sending them, so that the tests can check what is sent.
+// If Err is set, Send returns it and does not keep the mail.
+type CaptureMailSender struct {
+	Err   error
+	mu    sync.Mutex
+	mails []Mail
+	added chan struct{}
+}
+
+func NewCaptureMailSender() *CaptureMailSender {
+	return &CaptureMailSender{added: make(chan struct{}, 1)}
+}
+func (s *CaptureMailSender) Send(m Mail) error {
+	s.mu.Lock()
+	if s.Err != nil {
+		err := s.Err
+		s.mu.Unlock()
+		return err
+	}
+	s.mails = append(s.mails, m)
+	if s.added == nil {
+		s.added = make(chan struct{}, 1)
+	}
+	added := s.added
+	s.mu.Unlock()
+	select {
+	case added <- struct{}{}:
+	default:
+	}
+	return nil
+}
+func (s *CaptureMailSender) SetError(err error) {
+	s.mu.Lock()
+	s.Err = err
+	s.mu.Unlock()
+}
+
+// Mails returns a copy of the captured mails, in the order they are sent.
+func (s *CaptureMailSender) Mails() []Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mails := make([]Mail, len(s.mails))
+	copy(mails, s.mails)
+	return mails
+}
+func (s *CaptureMailSender) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.mails)
+}
+func (s *CaptureMailSender) Last() *Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.mails) == 0 {
+		return nil
+	}
+	m := s.mails[len(s.mails)-1]
+	return &m
+}
+func (s *CaptureMailSender) Reset() {
+	s.mu.Lock()
+	s.mails = nil
+	s.mu.Unlock()
+}
+func (s *CaptureMailSender) Filter(f func(m Mail) bool) []Mail {
+	var mails []Mail
+	for _, m := range s.Mails() {
+		if f(m) {
+			mails = append(mails, m)
+		}
+	}
+	return mails
+}
+
+// SentTo returns the mails of which the address is in To, Cc or Bcc.
+func (s *CaptureMailSender) SentTo(address string) []Mail {
+	return s.Filter(func(m Mail) bool {
+		return HasRecipient(m, address)
+	})
+}
+
+// WithSubject returns the mails of which the subject contains the text.
+func (s *CaptureMailSender) WithSubject(text string) []Mail {
+	return s.Filter(func(m Mail) bool {
+		return strings.Contains(m.Subject, text)
+	})
+}
+
+// Wait waits until at least n mails are captured, for the senders which send in a goroutine. It returns false after the timeout.
+func (s *CaptureMailSender) Wait(n int, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		s.mu.Lock()
+		count := len(s.mails)
+		if s.added == nil {
+			s.added = make(chan struct{}, 1)
+		}
+		added := s.added
+		s.mu.Unlock()
+		if count >= n {
+			return true
+		}
+		select {
+		case <-added:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+func HasRecipient(m Mail, address string) bool {
+	for _, e := range m.To {
+		if strings.EqualFold(e.Address, address) {
+			return true
+		}
+	}
+	for _, emails := range []*[]Email{m.Cc, m.Bcc} {
+		if emails == nil {
+			continue
+		}
+		for _, e := range *emails {
+			if strings.EqualFold(e.Address, address) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Body returns the value of the content of the type, such as "text/html", or "" if there is no such content.
+func Body(m Mail, contentType string) string {
+	for _, c := range m.Content {
+		if c.Type == contentType || strings.HasPrefix(c.Type, contentType+";") {
+			return c.Value
+		}
+	}
+	return ""
+}
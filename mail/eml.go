@@ -0,0 +1,253 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WriteEml writes the mail as an RFC 5322 message, with a multipart/alternative body for text/plain and text/html,
+// and a multipart/mixed body if there are attachments. The content of an attachment is base64 encoded, as for SendGrid.
+// Bcc is not written.
+func WriteEml(w io.Writer, m Mail, opts ...time.Time) error {
+	if len(m.From.Address) == 0 {
+		return errors.New("must have from field")
+	}
+	if len(m.To) == 0 || len(m.To[0].Address) == 0 {
+		return errors.New("must have at least 1 receiver")
+	}
+	now := time.Now()
+	if len(opts) > 0 {
+		now = opts[0]
+	}
+	bw := bufio.NewWriter(w)
+	header := func(k, v string) {
+		fmt.Fprintf(bw, "%s: %s\r\n", k, v)
+	}
+	header("From", FormatAddress(m.From))
+	header("To", FormatAddresses(m.To))
+	if m.Cc != nil && len(*m.Cc) > 0 {
+		header("Cc", FormatAddresses(*m.Cc))
+	}
+	if m.ReplyTo != nil && len(m.ReplyTo.Address) > 0 {
+		header("Reply-To", FormatAddress(*m.ReplyTo))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	keys := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	hasId := false
+	for _, k := range keys {
+		ck := textproto.CanonicalMIMEHeaderKey(k)
+		switch ck {
+		case "From", "To", "Cc", "Bcc", "Reply-To", "Subject", "Date", "Mime-Version", "Content-Type", "Content-Transfer-Encoding":
+			continue
+		case "Message-Id":
+			hasId = true
+			ck = "Message-ID"
+		}
+		header(ck, mime.QEncoding.Encode("utf-8", m.Headers[k]))
+	}
+	if !hasId {
+		header("Message-ID", "<"+randomId()+"@"+domainOf(m.From.Address)+">")
+	}
+	header("MIME-Version", "1.0")
+
+	var body bytes.Buffer
+	var contentType string
+	var err error
+	if len(m.Attachments) == 0 {
+		contentType, err = writeContents(&body, m.Content)
+	} else {
+		mw := multipart.NewWriter(&body)
+		contentType = "multipart/mixed; boundary=" + mw.Boundary()
+		var alt bytes.Buffer
+		altType, er1 := writeContents(&alt, m.Content)
+		if er1 != nil {
+			return er1
+		}
+		part, er2 := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {altType}})
+		if er2 != nil {
+			return er2
+		}
+		if _, err = part.Write(alt.Bytes()); err != nil {
+			return err
+		}
+		for _, a := range m.Attachments {
+			if a == nil {
+				continue
+			}
+			if err = writeAttachment(mw, *a); err != nil {
+				return err
+			}
+		}
+		err = mw.Close()
+	}
+	if err != nil {
+		return err
+	}
+	header("Content-Type", contentType)
+	if !strings.HasPrefix(contentType, "multipart/") {
+		header("Content-Transfer-Encoding", "quoted-printable")
+	}
+	bw.WriteString("\r\n")
+	bw.Write(body.Bytes())
+	return bw.Flush()
+}
+
+// writeContents writes a single part, or a multipart/alternative part with text/plain first, and returns the content type.
+func writeContents(w io.Writer, contents []Content) (string, error) {
+	if len(contents) == 0 {
+		contents = []Content{{Type: "text/plain", Value: ""}}
+	}
+	if len(contents) == 1 {
+		return contentTypeOf(contents[0].Type), writeQuotedPrintable(w, contents[0].Value)
+	}
+	sorted := make([]Content, len(contents))
+	copy(sorted, contents)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Type == "text/plain" && sorted[j].Type != "text/plain"
+	})
+	mw := multipart.NewWriter(w)
+	for _, c := range sorted {
+		part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {contentTypeOf(c.Type)}, "Content-Transfer-Encoding": {"quoted-printable"}})
+		if err != nil {
+			return "", err
+		}
+		if err = writeQuotedPrintable(part, c.Value); err != nil {
+			return "", err
+		}
+	}
+	return "multipart/alternative; boundary=" + mw.Boundary(), mw.Close()
+}
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+func writeAttachment(mw *multipart.Writer, a Attachment) error {
+	data, err := base64.StdEncoding.DecodeString(a.Content)
+	if err != nil {
+		return fmt.Errorf("cannot decode attachment '%s': %w", a.Filename, err)
+	}
+	contentType := a.Type
+	if len(contentType) == 0 {
+		contentType = "application/octet-stream"
+	}
+	filename := a.Filename
+	if len(filename) == 0 {
+		filename = a.Name
+	}
+	disposition := a.Disposition
+	if len(disposition) == 0 {
+		disposition = "attachment"
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"name": filename}))
+	h.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+	h.Set("Content-Transfer-Encoding", "base64")
+	if len(a.ContentID) > 0 {
+		h.Set("Content-ID", "<"+strings.Trim(a.ContentID, "<>")+">")
+	}
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err = io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+func contentTypeOf(t string) string {
+	if len(t) == 0 {
+		t = "text/plain"
+	}
+	if !strings.Contains(t, "charset") {
+		t = t + "; charset=utf-8"
+	}
+	return t
+}
+
+// FormatAddress formats the email as an RFC 5322 address. The name may be quoted by EscapeName.
+func FormatAddress(e Email) string {
+	name := e.Name
+	if len(name) > 1 && name[0] == '"' && name[len(name)-1] == '"' {
+		name = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(name[1 : len(name)-1])
+	}
+	a := mail.Address{Name: name, Address: e.Address}
+	return a.String()
+}
+func FormatAddresses(emails []Email) string {
+	addresses := make([]string, 0, len(emails))
+	for _, e := range emails {
+		addresses = append(addresses, FormatAddress(e))
+	}
+	return strings.Join(addresses, ", ")
+}
+func randomId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+func domainOf(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 && i < len(address)-1 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
+
+// FileMailSender writes each mail as an .eml file to a directory, which can be opened by a mail client, for development.
+type FileMailSender struct {
+	Directory string
+	Now       func() time.Time
+}
+
+func NewFileMailSender(directory string) *FileMailSender {
+	return &FileMailSender{Directory: directory, Now: time.Now}
+}
+func (s *FileMailSender) Send(m Mail) error {
+	if err := os.MkdirAll(s.Directory, os.ModePerm); err != nil {
+		return err
+	}
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	name := now.Format("20060102-150405.000000000") + "-" + randomId()[:8] + ".eml"
+	f, err := os.Create(filepath.Join(s.Directory, name))
+	if err != nil {
+		return err
+	}
+	err = WriteEml(f, m, now)
+	if er2 := f.Close(); err == nil {
+		err = er2
+	}
+	return err
+}
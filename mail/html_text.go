@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	reHtmlHidden = regexp.MustCompile(`(?is)<(head|style|script|title)[^>]*>.*?</(head|style|script|title)>`)
+	reHtmlLink   = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a>`)
+	reHtmlBreak  = regexp.MustCompile(`(?i)<br\s*/?>`)
+	reHtmlBlock  = regexp.MustCompile(`(?i)</?(p|div|h[1-6]|table|tr|ul|ol|blockquote|hr)[^>]*>`)
+	reHtmlItem   = regexp.MustCompile(`(?i)<li[^>]*>`)
+	reHtmlCell   = regexp.MustCompile(`(?i)</t[dh]>`)
+	reHtmlTag    = regexp.MustCompile(`(?s)<[^>]*>`)
+	reSpaces     = regexp.MustCompile(`[ \t\r\f\v]+`)
+	reEmptyLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// HtmlToText converts an HTML body to a text/plain alternative: the links are written as "text (url)", the blocks and the line breaks become new lines, and the other tags are removed.
+func HtmlToText(s string) string {
+	s = reHtmlHidden.ReplaceAllString(s, "")
+	s = reHtmlLink.ReplaceAllStringFunc(s, func(m string) string {
+		sm := reHtmlLink.FindStringSubmatch(m)
+		url := sm[1]
+		text := strings.TrimSpace(reHtmlTag.ReplaceAllString(sm[2], ""))
+		if len(text) == 0 || text == url || strings.HasPrefix(url, "#") {
+			if len(text) == 0 {
+				return url
+			}
+			return text
+		}
+		return text + " (" + url + ")"
+	})
+	s = strings.NewReplacer("\r\n", " ", "\n", " ").Replace(s)
+	s = reHtmlBreak.ReplaceAllString(s, "\n")
+	s = reHtmlBlock.ReplaceAllString(s, "\n\n")
+	s = reHtmlItem.ReplaceAllString(s, "\n- ")
+	s = reHtmlCell.ReplaceAllString(s, " ")
+	s = reHtmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = reSpaces.ReplaceAllString(s, " ")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	s = reEmptyLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(s)
+}
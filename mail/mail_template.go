@@ -0,0 +1,113 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MailTemplate is the source of a mail. Html and Text are templates of the bundled template engine.
+// If Text is empty, the text/plain body is generated from the HTML body. Layout is the id of the layout template, which renders the body by {{template "content" .}}.
+type MailTemplate struct {
+	Id      string `yaml:"id" mapstructure:"id" json:"id,omitempty" gorm:"column:id;primary_key" bson:"_id,omitempty" dynamodbav:"id,omitempty" firestore:"id,omitempty"`
+	Locale  string `yaml:"locale" mapstructure:"locale" json:"locale,omitempty" gorm:"column:locale;primary_key" bson:"locale,omitempty" dynamodbav:"locale,omitempty" firestore:"locale,omitempty"`
+	Subject string `yaml:"subject" mapstructure:"subject" json:"subject,omitempty" gorm:"column:subject" bson:"subject,omitempty" dynamodbav:"subject,omitempty" firestore:"subject,omitempty"`
+	Html    string `yaml:"html" mapstructure:"html" json:"html,omitempty" gorm:"column:html" bson:"html,omitempty" dynamodbav:"html,omitempty" firestore:"html,omitempty"`
+	Text    string `yaml:"text" mapstructure:"text" json:"text,omitempty" gorm:"column:text" bson:"text,omitempty" dynamodbav:"text,omitempty" firestore:"text,omitempty"`
+	Layout  string `yaml:"layout" mapstructure:"layout" json:"layout,omitempty" gorm:"column:layout" bson:"layout,omitempty" dynamodbav:"layout,omitempty" firestore:"layout,omitempty"`
+}
+
+// MailTemplateLoader loads a template of a locale. It returns nil if the template does not exist for this locale.
+type MailTemplateLoader interface {
+	LoadTemplate(ctx context.Context, id string, locale string) (*MailTemplate, error)
+}
+
+// Locales returns the locales to try, from the most specific to the default locale: "vi-VN" gives "vi-VN", "vi", then the default locale and "".
+func Locales(locale string, defaultLocale string) []string {
+	locales := make([]string, 0, 4)
+	add := func(l string) {
+		for _, x := range locales {
+			if strings.EqualFold(x, l) {
+				return
+			}
+		}
+		locales = append(locales, l)
+	}
+	locale = strings.Replace(locale, "_", "-", -1)
+	for len(locale) > 0 {
+		add(locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	if len(defaultLocale) > 0 {
+		add(defaultLocale)
+	}
+	add("")
+	return locales
+}
+
+// FileTemplateLoader loads the templates from a directory. The files of the template "welcome" of the locale "vi" are
+// welcome.vi.subject, welcome.vi.html and welcome.vi.txt, and the files without locale are welcome.subject, welcome.html and welcome.txt.
+type FileTemplateLoader struct {
+	Directory string
+}
+
+func NewFileTemplateLoader(directory string) *FileTemplateLoader {
+	return &FileTemplateLoader{Directory: directory}
+}
+func (l *FileTemplateLoader) LoadTemplate(ctx context.Context, id string, locale string) (*MailTemplate, error) {
+	if strings.Contains(id, "..") || strings.ContainsAny(id, `/\`) || strings.Contains(locale, "..") || strings.ContainsAny(locale, `/\`) {
+		return nil, nil
+	}
+	name := id
+	if len(locale) > 0 {
+		name = id + "." + locale
+	}
+	t := MailTemplate{Id: id, Locale: locale}
+	found := false
+	for _, f := range []struct {
+		ext   string
+		value *string
+	}{{".subject", &t.Subject}, {".html", &t.Html}, {".txt", &t.Text}} {
+		b, err := os.ReadFile(filepath.Join(l.Directory, name+f.ext))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		*f.value = string(b)
+		found = true
+	}
+	if !found {
+		return nil, nil
+	}
+	t.Subject = strings.TrimSpace(t.Subject)
+	return &t, nil
+}
+
+// TemplateLoaderAdapter adapts a TemplateLoader, such as DefaultTemplateLoader, which returns the subject and the HTML body.
+type TemplateLoaderAdapter struct {
+	Loader TemplateLoader
+}
+
+func NewTemplateLoaderAdapter(loader TemplateLoader) *TemplateLoaderAdapter {
+	return &TemplateLoaderAdapter{Loader: loader}
+}
+func (l *TemplateLoaderAdapter) LoadTemplate(ctx context.Context, id string, locale string) (*MailTemplate, error) {
+	if len(locale) > 0 {
+		return nil, nil
+	}
+	subject, body, err := l.Loader.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(subject) == 0 && len(body) == 0 {
+		return nil, nil
+	}
+	return &MailTemplate{Id: id, Subject: subject, Html: body}, nil
+}
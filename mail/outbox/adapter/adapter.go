@@ -0,0 +1,239 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	o "github.com/core-go/core/mail/outbox"
+	q "github.com/core-go/core/query"
+)
+
+type OutboxAdapter struct {
+	DB          *sql.DB
+	BuildParam  func(int) string
+	Driver      string
+	Tx          string
+	Table       string
+	Id          string
+	Status      string
+	From        string
+	To          string
+	Subject     string
+	Data        string
+	Attempts    string
+	LastError   string
+	NextAttempt string
+	CreatedAt   string
+	UpdatedAt   string
+	SentAt      string
+	columns     string
+}
+
+// NewOutboxAdapter creates the adapter of the outbox table. If the tx key is in the context, Insert joins the transaction, so that a mail is queued only if the business data is committed.
+func NewOutboxAdapter(db *sql.DB, buildParam func(int) string, table string, opts ...string) *OutboxAdapter {
+	tx := "tx"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		tx = opts[0]
+	}
+	a := &OutboxAdapter{DB: db, BuildParam: buildParam, Driver: q.GetDriver(db), Tx: tx, Table: table, Id: "id", Status: "status", From: "sender", To: "recipients", Subject: "subject", Data: "data",
+		Attempts: "attempts", LastError: "last_error", NextAttempt: "next_attempt", CreatedAt: "created_at", UpdatedAt: "updated_at", SentAt: "sent_at"}
+	return a
+}
+func (a *OutboxAdapter) getColumns() string {
+	if len(a.columns) == 0 {
+		a.columns = strings.Join([]string{a.Id, a.Status, a.From, a.To, a.Subject, a.Data, a.Attempts, a.LastError, a.NextAttempt, a.CreatedAt, a.UpdatedAt, a.SentAt}, ",")
+	}
+	return a.columns
+}
+func (a *OutboxAdapter) query(ctx context.Context, query string, args ...interface{}) ([]o.Message, error) {
+	rows, err := getExec(ctx, a.DB, a.Tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var msgs []o.Message
+	for rows.Next() {
+		var m o.Message
+		var lastError sql.NullString
+		var nextAttempt, createdAt, updatedAt, sentAt sql.NullTime
+		err = rows.Scan(&m.Id, &m.Status, &m.From, &m.To, &m.Subject, &m.Data, &m.Attempts, &lastError, &nextAttempt, &createdAt, &updatedAt, &sentAt)
+		if err != nil {
+			return msgs, err
+		}
+		if lastError.Valid {
+			m.LastError = &lastError.String
+		}
+		m.NextAttempt = toTime(nextAttempt)
+		m.CreatedAt = toTime(createdAt)
+		m.UpdatedAt = toTime(updatedAt)
+		m.SentAt = toTime(sentAt)
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+func toTime(t sql.NullTime) *time.Time {
+	if t.Valid {
+		return &t.Time
+	}
+	return nil
+}
+
+func (a *OutboxAdapter) Load(ctx context.Context, id string) (*o.Message, error) {
+	query := fmt.Sprintf("select %s from %s where %s = %s", a.getColumns(), a.Table, a.Id, a.BuildParam(1))
+	msgs, err := a.query(ctx, query, id)
+	if err != nil || len(msgs) == 0 {
+		return nil, err
+	}
+	return &msgs[0], nil
+}
+func (a *OutboxAdapter) exist(ctx context.Context, id string) (bool, error) {
+	query := fmt.Sprintf("select %s from %s where %s = %s", a.Id, a.Table, a.Id, a.BuildParam(1))
+	rows, err := getExec(ctx, a.DB, a.Tx).QueryContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+func (a *OutboxAdapter) Insert(ctx context.Context, msg *o.Message) (int64, error) {
+	exist, err := a.exist(ctx, msg.Id)
+	if err != nil {
+		return -1, err
+	}
+	if exist {
+		return 0, nil
+	}
+	query := fmt.Sprintf("insert into %s(%s) values (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s)", a.Table, a.getColumns(),
+		a.BuildParam(1), a.BuildParam(2), a.BuildParam(3), a.BuildParam(4), a.BuildParam(5), a.BuildParam(6),
+		a.BuildParam(7), a.BuildParam(8), a.BuildParam(9), a.BuildParam(10), a.BuildParam(11), a.BuildParam(12))
+	res, err := getExec(ctx, a.DB, a.Tx).ExecContext(ctx, query, msg.Id, msg.Status, msg.From, msg.To, msg.Subject, msg.Data, msg.Attempts, msg.LastError, msg.NextAttempt, msg.CreatedAt, msg.UpdatedAt, msg.SentAt)
+	if err != nil {
+		// the same id may be inserted concurrently, the unique constraint of the id keeps only one
+		if exist, er2 := a.exist(ctx, msg.Id); er2 == nil && exist {
+			return 0, nil
+		}
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+func (a *OutboxAdapter) Due(ctx context.Context, now time.Time, limit int) ([]o.Message, error) {
+	query := fmt.Sprintf("select %s from %s where (%s = %s or %s = %s) and %s <= %s order by %s", a.getColumns(), a.Table,
+		a.Status, a.BuildParam(1), a.Status, a.BuildParam(2), a.NextAttempt, a.BuildParam(3), a.NextAttempt)
+	query = q.BuildPagingQuery(query, int64(limit), 0, a.Driver)
+	return a.query(ctx, query, o.StatusQueued, o.StatusSending, now)
+}
+func (a *OutboxAdapter) Claim(ctx context.Context, id string, attempts int, lease time.Time, now time.Time) (int64, error) {
+	query := fmt.Sprintf("update %s set %s = %s, %s = %s, %s = %s, %s = %s where %s = %s and %s = %s and (%s = %s or %s = %s) and %s <= %s", a.Table,
+		a.Status, a.BuildParam(1), a.Attempts, a.BuildParam(2), a.NextAttempt, a.BuildParam(3), a.UpdatedAt, a.BuildParam(4),
+		a.Id, a.BuildParam(5), a.Attempts, a.BuildParam(6), a.Status, a.BuildParam(7), a.Status, a.BuildParam(8), a.NextAttempt, a.BuildParam(9))
+	res, err := a.DB.ExecContext(ctx, query, o.StatusSending, attempts+1, lease, now, id, attempts, o.StatusQueued, o.StatusSending, now)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+func (a *OutboxAdapter) Complete(ctx context.Context, id string, attempts int, status string, lastError *string, nextAttempt *time.Time, now time.Time) (int64, error) {
+	var sentAt *time.Time
+	if status == o.StatusSent {
+		sentAt = &now
+	}
+	query := fmt.Sprintf("update %s set %s = %s, %s = %s, %s = %s, %s = %s, %s = %s where %s = %s and %s = %s and %s = %s", a.Table,
+		a.Status, a.BuildParam(1), a.LastError, a.BuildParam(2), a.NextAttempt, a.BuildParam(3), a.SentAt, a.BuildParam(4), a.UpdatedAt, a.BuildParam(5),
+		a.Id, a.BuildParam(6), a.Attempts, a.BuildParam(7), a.Status, a.BuildParam(8))
+	res, err := a.DB.ExecContext(ctx, query, status, lastError, nextAttempt, sentAt, now, id, attempts, o.StatusSending)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+func (a *OutboxAdapter) Retry(ctx context.Context, id string, now time.Time) (int64, error) {
+	query := fmt.Sprintf("update %s set %s = %s, %s = 0, %s = %s, %s = %s where %s = %s and (%s = %s or %s = %s)", a.Table,
+		a.Status, a.BuildParam(1), a.Attempts, a.NextAttempt, a.BuildParam(2), a.UpdatedAt, a.BuildParam(3),
+		a.Id, a.BuildParam(4), a.Status, a.BuildParam(5), a.Status, a.BuildParam(6))
+	res, err := a.DB.ExecContext(ctx, query, o.StatusQueued, now, now, id, o.StatusFailed, o.StatusBounced)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+func (a *OutboxAdapter) Bounce(ctx context.Context, id string, reason string, now time.Time) (int64, error) {
+	query := fmt.Sprintf("update %s set %s = %s, %s = %s, %s = %s where %s = %s", a.Table,
+		a.Status, a.BuildParam(1), a.LastError, a.BuildParam(2), a.UpdatedAt, a.BuildParam(3), a.Id, a.BuildParam(4))
+	res, err := a.DB.ExecContext(ctx, query, o.StatusBounced, reason, now, id)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+func (a *OutboxAdapter) Search(ctx context.Context, filter *o.MessageFilter, limit int64, offset int64) ([]o.Message, int64, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, a.BuildParam(len(args))))
+	}
+	if filter != nil {
+		if len(filter.Id) > 0 {
+			add(a.Id+" = %s", filter.Id)
+		}
+		if len(filter.Status) > 0 {
+			var ss []string
+			for _, s := range filter.Status {
+				args = append(args, s)
+				ss = append(ss, a.BuildParam(len(args)))
+			}
+			where = append(where, fmt.Sprintf("%s in (%s)", a.Status, strings.Join(ss, ",")))
+		}
+		if len(filter.From) > 0 {
+			add(a.From+" like %s", filter.From+"%")
+		}
+		if len(filter.To) > 0 {
+			add(a.To+" like %s", "%"+filter.To+"%")
+		}
+		if len(filter.Subject) > 0 {
+			add(a.Subject+" like %s", "%"+filter.Subject+"%")
+		}
+		if filter.CreatedAt != nil {
+			if filter.CreatedAt.Min != nil {
+				add(a.CreatedAt+" >= %s", *filter.CreatedAt.Min)
+			}
+			if filter.CreatedAt.Max != nil {
+				add(a.CreatedAt+" < %s", *filter.CreatedAt.Max)
+			}
+		}
+	}
+	var sWhere string
+	if len(where) > 0 {
+		sWhere = " where " + strings.Join(where, " and ")
+	}
+	var total int64
+	row := a.DB.QueryRowContext(ctx, fmt.Sprintf("select count(*) from %s%s", a.Table, sWhere), args...)
+	if err := row.Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []o.Message{}, 0, nil
+	}
+	query := q.BuildPagingQuery(fmt.Sprintf("select %s from %s%s order by %s desc", a.getColumns(), a.Table, sWhere, a.CreatedAt), limit, offset, a.Driver)
+	msgs, err := a.query(ctx, query, args...)
+	return msgs, total, err
+}
+
+type executor interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func getExec(ctx context.Context, db *sql.DB, name string) executor {
+	txi := ctx.Value(name)
+	if txi != nil {
+		txx, ok := txi.(*sql.Tx)
+		if ok {
+			return txx
+		}
+	}
+	return db
+}
@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/core-go/core"
+	s "github.com/core-go/core/search/handler"
+)
+
+type OutboxService interface {
+	Load(ctx context.Context, id string) (*Message, error)
+	Search(ctx context.Context, filter *MessageFilter, limit int64, offset int64) ([]Message, int64, error)
+	Retry(ctx context.Context, id string) (int64, error)
+}
+
+// Handler lets the support staff search the outbox, view a mail with its delivery status, and queue a failed or bounced mail again.
+type Handler struct {
+	*s.SearchHandler[Message, *MessageFilter]
+	Service  OutboxService
+	LogError func(context.Context, string, ...map[string]interface{})
+	WriteLog func(ctx context.Context, resource string, action string, success bool, desc string) error
+	Resource string
+}
+
+func NewOutboxHandler(service OutboxService, logError func(context.Context, string, ...map[string]interface{}), writeLog func(context.Context, string, string, bool, string) error, options ...string) *Handler {
+	resource := "outbox"
+	if len(options) > 3 && len(options[3]) > 0 {
+		resource = options[3]
+	}
+	searchHandler := s.NewSearchHandler[Message, *MessageFilter](service.Search, logError, writeLog, options...)
+	return &Handler{SearchHandler: searchHandler, Service: service, LogError: logError, WriteLog: writeLog, Resource: resource}
+}
+func (h *Handler) Load(w http.ResponseWriter, r *http.Request) {
+	id, err := core.GetRequiredString(w, r)
+	if err == nil {
+		msg, err := h.Service.Load(r.Context(), id)
+		core.ReturnWithLog(w, r, msg, err, h.LogError, h.WriteLog, h.Resource, "load")
+	}
+}
+
+// Retry handles POST /{id}/retry.
+func (h *Handler) Retry(w http.ResponseWriter, r *http.Request) {
+	id, err := core.GetRequiredString(w, r, 1)
+	if err == nil {
+		res, err := h.Service.Retry(r.Context(), id)
+		if err != nil {
+			if h.LogError != nil {
+				h.LogError(r.Context(), err.Error())
+				http.Error(w, core.InternalServerError, http.StatusInternalServerError)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		if h.WriteLog != nil {
+			h.WriteLog(r.Context(), h.Resource, "retry", res > 0, "POST "+r.URL.Path)
+		}
+		if res > 0 {
+			core.JSON(w, http.StatusOK, res)
+		} else {
+			core.JSON(w, http.StatusConflict, res)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/core-go/core/mail"
+	"github.com/core-go/core/search"
+)
+
+const (
+	StatusQueued  = "queued"
+	StatusSending = "sending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusBounced = "bounced"
+)
+
+// Message is a mail persisted in the outbox. Data is the JSON of the mail, including To, Cc and Bcc.
+type Message struct {
+	Id          string     `yaml:"id" mapstructure:"id" json:"id,omitempty" gorm:"column:id;primary_key" bson:"_id,omitempty" dynamodbav:"id,omitempty" firestore:"id,omitempty"`
+	Status      string     `yaml:"status" mapstructure:"status" json:"status,omitempty" gorm:"column:status" bson:"status,omitempty" dynamodbav:"status,omitempty" firestore:"status,omitempty"`
+	From        string     `yaml:"from" mapstructure:"from" json:"from,omitempty" gorm:"column:sender" bson:"from,omitempty" dynamodbav:"from,omitempty" firestore:"from,omitempty"`
+	To          string     `yaml:"to" mapstructure:"to" json:"to,omitempty" gorm:"column:recipients" bson:"to,omitempty" dynamodbav:"to,omitempty" firestore:"to,omitempty"`
+	Subject     string     `yaml:"subject" mapstructure:"subject" json:"subject,omitempty" gorm:"column:subject" bson:"subject,omitempty" dynamodbav:"subject,omitempty" firestore:"subject,omitempty"`
+	Data        string     `yaml:"data" mapstructure:"data" json:"-" gorm:"column:data" bson:"data,omitempty" dynamodbav:"data,omitempty" firestore:"data,omitempty"`
+	Attempts    int        `yaml:"attempts" mapstructure:"attempts" json:"attempts,omitempty" gorm:"column:attempts" bson:"attempts,omitempty" dynamodbav:"attempts,omitempty" firestore:"attempts,omitempty"`
+	LastError   *string    `yaml:"last_error" mapstructure:"last_error" json:"lastError,omitempty" gorm:"column:last_error" bson:"lastError,omitempty" dynamodbav:"lastError,omitempty" firestore:"lastError,omitempty"`
+	NextAttempt *time.Time `yaml:"next_attempt" mapstructure:"next_attempt" json:"nextAttempt,omitempty" gorm:"column:next_attempt" bson:"nextAttempt,omitempty" dynamodbav:"nextAttempt,omitempty" firestore:"nextAttempt,omitempty"`
+	CreatedAt   *time.Time `yaml:"created_at" mapstructure:"created_at" json:"createdAt,omitempty" gorm:"column:created_at" bson:"createdAt,omitempty" dynamodbav:"createdAt,omitempty" firestore:"createdAt,omitempty"`
+	UpdatedAt   *time.Time `yaml:"updated_at" mapstructure:"updated_at" json:"updatedAt,omitempty" gorm:"column:updated_at" bson:"updatedAt,omitempty" dynamodbav:"updatedAt,omitempty" firestore:"updatedAt,omitempty"`
+	SentAt      *time.Time `yaml:"sent_at" mapstructure:"sent_at" json:"sentAt,omitempty" gorm:"column:sent_at" bson:"sentAt,omitempty" dynamodbav:"sentAt,omitempty" firestore:"sentAt,omitempty"`
+	Mail        *mail.Mail `yaml:"mail" mapstructure:"mail" json:"mail,omitempty" gorm:"-" bson:"-" dynamodbav:"-" firestore:"-"`
+}
+
+type MessageFilter struct {
+	*search.Filter
+	Id        string            `yaml:"id" mapstructure:"id" json:"id,omitempty" gorm:"column:id" bson:"_id,omitempty" dynamodbav:"id,omitempty" firestore:"id,omitempty"`
+	Status    []string          `yaml:"status" mapstructure:"status" json:"status,omitempty" gorm:"column:status" bson:"status,omitempty" dynamodbav:"status,omitempty" firestore:"status,omitempty"`
+	From      string            `yaml:"from" mapstructure:"from" json:"from,omitempty" gorm:"column:sender" bson:"from,omitempty" dynamodbav:"from,omitempty" firestore:"from,omitempty"`
+	To        string            `yaml:"to" mapstructure:"to" json:"to,omitempty" gorm:"column:recipients" bson:"to,omitempty" dynamodbav:"to,omitempty" firestore:"to,omitempty"`
+	Subject   string            `yaml:"subject" mapstructure:"subject" json:"subject,omitempty" gorm:"column:subject" bson:"subject,omitempty" dynamodbav:"subject,omitempty" firestore:"subject,omitempty"`
+	CreatedAt *search.TimeRange `yaml:"created_at" mapstructure:"created_at" json:"createdAt,omitempty" gorm:"column:created_at" bson:"createdAt,omitempty" dynamodbav:"createdAt,omitempty" firestore:"createdAt,omitempty"`
+}
+
+type OutboxConfig struct {
+	MaxAttempts int            `yaml:"max_attempts" mapstructure:"max_attempts" json:"maxAttempts,omitempty" gorm:"column:maxattempts" bson:"maxAttempts,omitempty" dynamodbav:"maxAttempts,omitempty" firestore:"maxAttempts,omitempty"`
+	Batch       int            `yaml:"batch" mapstructure:"batch" json:"batch,omitempty" gorm:"column:batch" bson:"batch,omitempty" dynamodbav:"batch,omitempty" firestore:"batch,omitempty"`
+	Interval    *time.Duration `yaml:"interval" mapstructure:"interval" json:"interval,omitempty" gorm:"column:interval" bson:"interval,omitempty" dynamodbav:"interval,omitempty" firestore:"interval,omitempty"`
+	Backoff     *time.Duration `yaml:"backoff" mapstructure:"backoff" json:"backoff,omitempty" gorm:"column:backoff" bson:"backoff,omitempty" dynamodbav:"backoff,omitempty" firestore:"backoff,omitempty"`
+	MaxBackoff  *time.Duration `yaml:"max_backoff" mapstructure:"max_backoff" json:"maxBackoff,omitempty" gorm:"column:maxbackoff" bson:"maxBackoff,omitempty" dynamodbav:"maxBackoff,omitempty" firestore:"maxBackoff,omitempty"`
+	Lease       *time.Duration `yaml:"lease" mapstructure:"lease" json:"lease,omitempty" gorm:"column:lease" bson:"lease,omitempty" dynamodbav:"lease,omitempty" firestore:"lease,omitempty"`
+}
+
+// OutboxPort persists the messages.
+//   - Insert returns 0 if a message with the same id already exists.
+//   - Due returns the queued messages, and the sending messages of which the lease is expired, with next attempt before now.
+//   - Claim sets the status to sending and increases the attempts, only if the attempts are not changed by another worker. It returns 0 if the message is claimed by another worker.
+//   - Complete sets the result of an attempt, only if the message is still claimed by this attempt.
+type OutboxPort interface {
+	Load(ctx context.Context, id string) (*Message, error)
+	Insert(ctx context.Context, msg *Message) (int64, error)
+	Due(ctx context.Context, now time.Time, limit int) ([]Message, error)
+	Claim(ctx context.Context, id string, attempts int, lease time.Time, now time.Time) (int64, error)
+	Complete(ctx context.Context, id string, attempts int, status string, lastError *string, nextAttempt *time.Time, now time.Time) (int64, error)
+	Retry(ctx context.Context, id string, now time.Time) (int64, error)
+	Bounce(ctx context.Context, id string, reason string, now time.Time) (int64, error)
+	Search(ctx context.Context, filter *MessageFilter, limit int64, offset int64) ([]Message, int64, error)
+}
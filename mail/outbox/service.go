@@ -0,0 +1,279 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/core-go/core/mail"
+)
+
+// envelope keeps To, Cc and Bcc, which are not serialized by the json tags of mail.Mail.
+type envelope struct {
+	Mail mail.Mail     `json:"mail"`
+	To   []mail.Email  `json:"to,omitempty"`
+	Cc   *[]mail.Email `json:"cc,omitempty"`
+	Bcc  *[]mail.Email `json:"bcc,omitempty"`
+}
+
+func Marshal(m mail.Mail) (string, error) {
+	b, err := json.Marshal(envelope{Mail: m, To: m.To, Cc: m.Cc, Bcc: m.Bcc})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+func Unmarshal(data string) (*mail.Mail, error) {
+	var e envelope
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return nil, err
+	}
+	m := e.Mail
+	m.To = e.To
+	m.Cc = e.Cc
+	m.Bcc = e.Bcc
+	return &m, nil
+}
+
+// Outbox persists each mail, then delivers it asynchronously through the MailSender, with retry and exponential backoff.
+// It implements mail.MailSender, so that it can replace the sender of MailWriter, DefaultSimpleMailSender or PasscodeSender.
+type Outbox struct {
+	Repository  OutboxPort
+	Sender      mail.MailSender
+	Generate    func(ctx context.Context) (string, error)
+	MaxAttempts int
+	Batch       int
+	Interval    time.Duration
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Lease       time.Duration
+	IsPermanent func(err error) bool
+	LogError    func(context.Context, string, ...map[string]interface{})
+	Now         func() time.Time
+	mu          sync.Mutex
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+func NewOutbox(repository OutboxPort, sender mail.MailSender, generate func(ctx context.Context) (string, error), c OutboxConfig, logError func(context.Context, string, ...map[string]interface{}), opts ...func(err error) bool) *Outbox {
+	o := &Outbox{Repository: repository, Sender: sender, Generate: generate, MaxAttempts: c.MaxAttempts, Batch: c.Batch, LogError: logError, Now: time.Now}
+	if len(opts) > 0 {
+		o.IsPermanent = opts[0]
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.Batch <= 0 {
+		o.Batch = 20
+	}
+	o.Interval = duration(c.Interval, 10*time.Second)
+	o.Backoff = duration(c.Backoff, 30*time.Second)
+	o.MaxBackoff = duration(c.MaxBackoff, time.Hour)
+	o.Lease = duration(c.Lease, 5*time.Minute)
+	return o
+}
+func duration(d *time.Duration, v time.Duration) time.Duration {
+	if d != nil && *d > 0 {
+		return *d
+	}
+	return v
+}
+
+// Send enqueues the mail. The id is the Message-ID header if it exists, or is generated.
+func (o *Outbox) Send(m mail.Mail) error {
+	_, err := o.Enqueue(context.Background(), "", m)
+	return err
+}
+
+// Enqueue persists the mail with the given id. It returns 0 if a mail with the same id is already in the outbox, so that a mail is never sent twice.
+func (o *Outbox) Enqueue(ctx context.Context, id string, m mail.Mail) (int64, error) {
+	if len(id) == 0 && m.Headers != nil {
+		id = strings.Trim(m.Headers["Message-ID"], "<>")
+	}
+	if len(id) == 0 {
+		if o.Generate == nil {
+			return -1, errors.New("message id is required")
+		}
+		var err error
+		id, err = o.Generate(ctx)
+		if err != nil {
+			return -1, err
+		}
+	}
+	data, err := Marshal(m)
+	if err != nil {
+		return -1, err
+	}
+	now := o.Now()
+	msg := &Message{Id: id, Status: StatusQueued, From: m.From.Address, To: Recipients(m), Subject: m.Subject, Data: data, NextAttempt: &now, CreatedAt: &now, UpdatedAt: &now}
+	return o.Repository.Insert(ctx, msg)
+}
+
+func Recipients(m mail.Mail) string {
+	var addresses []string
+	for _, e := range m.To {
+		addresses = append(addresses, e.Address)
+	}
+	if m.Cc != nil {
+		for _, e := range *m.Cc {
+			addresses = append(addresses, e.Address)
+		}
+	}
+	if m.Bcc != nil {
+		for _, e := range *m.Bcc {
+			addresses = append(addresses, e.Address)
+		}
+	}
+	return strings.Join(addresses, ",")
+}
+
+// Load returns the message with the decoded mail.
+func (o *Outbox) Load(ctx context.Context, id string) (*Message, error) {
+	msg, err := o.Repository.Load(ctx, id)
+	if err != nil || msg == nil {
+		return msg, err
+	}
+	if len(msg.Data) > 0 {
+		msg.Mail, err = Unmarshal(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+func (o *Outbox) Search(ctx context.Context, filter *MessageFilter, limit int64, offset int64) ([]Message, int64, error) {
+	return o.Repository.Search(ctx, filter, limit, offset)
+}
+
+// Retry queues a failed or bounced message again, with the attempts reset.
+func (o *Outbox) Retry(ctx context.Context, id string) (int64, error) {
+	return o.Repository.Retry(ctx, id, o.Now())
+}
+
+// Bounce marks a message as bounced, for example from the webhook of the mail provider.
+func (o *Outbox) Bounce(ctx context.Context, id string, reason string) (int64, error) {
+	return o.Repository.Bounce(ctx, id, reason, o.Now())
+}
+
+// BackoffOf returns the delay before the next attempt, after the given number of attempts: Backoff, 2*Backoff, 4*Backoff... up to MaxBackoff.
+func (o *Outbox) BackoffOf(attempts int) time.Duration {
+	d := o.Backoff
+	for i := 1; i < attempts; i++ {
+		d = d * 2
+		if d >= o.MaxBackoff {
+			return o.MaxBackoff
+		}
+	}
+	if d > o.MaxBackoff {
+		return o.MaxBackoff
+	}
+	return d
+}
+
+// Process delivers the due messages once. It returns the number of messages which are sent.
+func (o *Outbox) Process(ctx context.Context) (int, error) {
+	now := o.Now()
+	msgs, err := o.Repository.Due(ctx, now, o.Batch)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, msg := range msgs {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		ok, err := o.deliver(ctx, msg)
+		if err != nil {
+			return sent, err
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+func (o *Outbox) deliver(ctx context.Context, msg Message) (bool, error) {
+	now := o.Now()
+	attempts := msg.Attempts + 1
+	claimed, err := o.Repository.Claim(ctx, msg.Id, msg.Attempts, now.Add(o.Lease), now)
+	if err != nil || claimed <= 0 {
+		return false, err
+	}
+	permanent := false
+	m, err := Unmarshal(msg.Data)
+	if err == nil {
+		err = o.Sender.Send(*m)
+		permanent = err != nil && o.IsPermanent != nil && o.IsPermanent(err)
+	} else {
+		err = errors.New("cannot decode mail: " + err.Error())
+		permanent = true
+	}
+	// the result is saved even if the context is cancelled while sending, so that a sent mail is not sent again after the lease
+	bg := context.Background()
+	now = o.Now()
+	if err == nil {
+		_, err = o.Repository.Complete(bg, msg.Id, attempts, StatusSent, nil, nil, now)
+		return err == nil, err
+	}
+	if o.LogError != nil {
+		o.LogError(ctx, "cannot send mail "+msg.Id+": "+err.Error())
+	}
+	lastError := err.Error()
+	if permanent || attempts >= o.MaxAttempts {
+		_, err = o.Repository.Complete(bg, msg.Id, attempts, StatusFailed, &lastError, nil, now)
+		return false, err
+	}
+	next := now.Add(o.BackoffOf(attempts))
+	_, err = o.Repository.Complete(bg, msg.Id, attempts, StatusQueued, &lastError, &next, now)
+	return false, err
+}
+
+// Start processes the due messages every interval, until Stop is called or the context is done.
+func (o *Outbox) Start(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.cancel != nil {
+		return errors.New("outbox is already started")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	o.cancel = cancel
+	o.done = make(chan struct{})
+	go o.run(ctx)
+	return nil
+}
+
+// Stop stops polling and waits for the current batch. It can be registered to the server lifecycle.
+func (o *Outbox) Stop(ctx context.Context) error {
+	o.mu.Lock()
+	cancel, done := o.cancel, o.done
+	o.cancel = nil
+	o.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+func (o *Outbox) run(ctx context.Context) {
+	defer close(o.done)
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := o.Process(ctx); err != nil && ctx.Err() == nil && o.LogError != nil {
+			o.LogError(ctx, "cannot process mail outbox: "+err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SqlTemplateLoader loads the templates from a table with the columns id, locale, subject, html, text and layout.
+// The templates without locale have an empty locale.
+type SqlTemplateLoader struct {
+	DB         *sql.DB
+	BuildParam func(int) string
+	Table      string
+	Id         string
+	Locale     string
+	Subject    string
+	Html       string
+	Text       string
+	Layout     string
+}
+
+func NewSqlTemplateLoader(db *sql.DB, buildParam func(int) string, table string, opts ...string) *SqlTemplateLoader {
+	var id, locale, subject, html, text, layout string
+	if len(opts) > 0 {
+		id = opts[0]
+	} else {
+		id = "id"
+	}
+	if len(opts) > 1 {
+		locale = opts[1]
+	} else {
+		locale = "locale"
+	}
+	if len(opts) > 2 {
+		subject = opts[2]
+	} else {
+		subject = "subject"
+	}
+	if len(opts) > 3 {
+		html = opts[3]
+	} else {
+		html = "html"
+	}
+	if len(opts) > 4 {
+		text = opts[4]
+	} else {
+		text = "text"
+	}
+	if len(opts) > 5 {
+		layout = opts[5]
+	} else {
+		layout = "layout"
+	}
+	return &SqlTemplateLoader{DB: db, BuildParam: buildParam, Table: table, Id: id, Locale: locale, Subject: subject, Html: html, Text: text, Layout: layout}
+}
+func (l *SqlTemplateLoader) LoadTemplate(ctx context.Context, id string, locale string) (*MailTemplate, error) {
+	query := fmt.Sprintf("select %s, %s, %s, %s from %s where %s = %s and %s = %s", l.Subject, l.Html, l.Text, l.Layout, l.Table, l.Id, l.BuildParam(1), l.Locale, l.BuildParam(2))
+	var subject, html, text, layout sql.NullString
+	err := l.DB.QueryRowContext(ctx, query, id, locale).Scan(&subject, &html, &text, &layout)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &MailTemplate{Id: id, Locale: locale, Subject: subject.String, Html: html.String, Text: text.String, Layout: layout.String}, nil
+}
@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/core-go/core"
+)
+
+type PreviewRequest struct {
+	Template *MailTemplate          `yaml:"template" mapstructure:"template" json:"template,omitempty"`
+	Data     map[string]interface{} `yaml:"data" mapstructure:"data" json:"data,omitempty"`
+}
+
+// TemplateHandler previews the templates. The request body is the sample data, and an optional draft template.
+type TemplateHandler struct {
+	Renderer *TemplateRenderer
+	LogError func(context.Context, string, ...map[string]interface{})
+	Locale   string
+	Format   string
+}
+
+func NewTemplateHandler(renderer *TemplateRenderer, logError func(context.Context, string, ...map[string]interface{}), opts ...string) *TemplateHandler {
+	var locale, format string
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		locale = opts[0]
+	} else {
+		locale = "locale"
+	}
+	if len(opts) > 1 && len(opts[1]) > 0 {
+		format = opts[1]
+	} else {
+		format = "format"
+	}
+	return &TemplateHandler{Renderer: renderer, LogError: logError, Locale: locale, Format: format}
+}
+
+// Preview handles POST /{id}/preview?locale=vi&format=html. The format can be html, text or json, the default is json.
+func (h *TemplateHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	var req PreviewRequest
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "cannot decode request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	ps := r.URL.Query()
+	locale := ps.Get(h.Locale)
+	var res *RenderedMail
+	var err error
+	if req.Template != nil {
+		if len(req.Template.Locale) == 0 {
+			req.Template.Locale = locale
+		}
+		res, err = h.Renderer.Preview(r.Context(), *req.Template, req.Data)
+	} else {
+		id, er0 := core.GetRequiredString(w, r, 1)
+		if er0 != nil {
+			return
+		}
+		res, err = h.Renderer.Render(r.Context(), id, locale, req.Data)
+	}
+	if err != nil {
+		// a template error is caused by the template or the sample data, so it is returned to the editor
+		if h.LogError != nil {
+			h.LogError(r.Context(), "cannot preview template: "+err.Error())
+		}
+		if errors.Is(err, ErrTemplateNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		}
+		return
+	}
+	switch ps.Get(h.Format) {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(res.Html))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(res.Text))
+	default:
+		core.JSON(w, http.StatusOK, res)
+	}
+}
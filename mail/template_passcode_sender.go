@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TemplatePasscodeSender sends the passcode with a template of TemplateRenderer. The data of the template are
+// To, Code, ExpireAt, Minutes and Params, for example {{.Code}} expires in {{.Minutes}} minutes.
+type TemplatePasscodeSender struct {
+	MailSender MailSender
+	From       Email
+	Renderer   *TemplateRenderer
+	Template   string
+	GetLocale  func(ctx context.Context) string
+}
+
+func NewTemplatePasscodeSender(mailSender MailSender, from Email, renderer *TemplateRenderer, template string, opts ...func(ctx context.Context) string) *TemplatePasscodeSender {
+	var getLocale func(ctx context.Context) string
+	if len(opts) > 0 {
+		getLocale = opts[0]
+	}
+	return &TemplatePasscodeSender{MailSender: mailSender, From: from, Renderer: renderer, Template: template, GetLocale: getLocale}
+}
+
+func (s *TemplatePasscodeSender) Send(ctx context.Context, to string, code string, expireAt time.Time, params interface{}) error {
+	address := to
+	if p, ok := params.(string); ok && len(p) > 0 {
+		address = p
+	}
+	var locale string
+	if s.GetLocale != nil {
+		locale = s.GetLocale(ctx)
+	}
+	data := map[string]interface{}{
+		"To":       to,
+		"Code":     code,
+		"ExpireAt": expireAt,
+		"Minutes":  fmt.Sprintf("%.f", time.Until(expireAt).Minutes()),
+		"Params":   params,
+	}
+	m, err := s.Renderer.Build(ctx, s.Template, locale, data, s.From, []Email{{Address: address}}, nil)
+	if err != nil {
+		return err
+	}
+	return s.MailSender.Send(*m)
+}
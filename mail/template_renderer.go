@@ -0,0 +1,226 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/core-go/core/template"
+)
+
+const ContentTemplate = "content"
+
+var ErrTemplateNotFound = errors.New("template not found")
+
+type RenderedMail struct {
+	Id      string `yaml:"id" mapstructure:"id" json:"id,omitempty" gorm:"column:id" bson:"_id,omitempty" dynamodbav:"id,omitempty" firestore:"id,omitempty"`
+	Locale  string `yaml:"locale" mapstructure:"locale" json:"locale,omitempty" gorm:"column:locale" bson:"locale,omitempty" dynamodbav:"locale,omitempty" firestore:"locale,omitempty"`
+	Subject string `yaml:"subject" mapstructure:"subject" json:"subject,omitempty" gorm:"column:subject" bson:"subject,omitempty" dynamodbav:"subject,omitempty" firestore:"subject,omitempty"`
+	Html    string `yaml:"html" mapstructure:"html" json:"html,omitempty" gorm:"column:html" bson:"html,omitempty" dynamodbav:"html,omitempty" firestore:"html,omitempty"`
+	Text    string `yaml:"text" mapstructure:"text" json:"text,omitempty" gorm:"column:text" bson:"text,omitempty" dynamodbav:"text,omitempty" firestore:"text,omitempty"`
+}
+
+type compiledTemplate struct {
+	id      string
+	locale  string
+	subject *template.Template
+	html    *template.Template
+	text    *template.Template
+}
+
+// TemplateRenderer renders the mail templates with the bundled template engine, with named data, a shared layout and partials.
+// The values are escaped in the HTML body, and the text/plain body is generated from the HTML body if the template has no text.
+// A template is selected by locale, with fallback to the language, then the default locale, then the template without locale.
+type TemplateRenderer struct {
+	Loader        MailTemplateLoader
+	DefaultLocale string
+	Layout        string
+	Partials      []string
+	Funcs         template.FuncMap
+	Cache         bool
+	mu            sync.RWMutex
+	cache         map[string]*compiledTemplate
+}
+
+func NewTemplateRenderer(loader MailTemplateLoader, defaultLocale string, layout string, partials ...string) *TemplateRenderer {
+	return &TemplateRenderer{Loader: loader, DefaultLocale: defaultLocale, Layout: layout, Partials: partials, Funcs: template.FuncMap{}, cache: make(map[string]*compiledTemplate)}
+}
+
+// Load loads the template of the first locale found, from the most specific locale to the template without locale.
+func (r *TemplateRenderer) Load(ctx context.Context, id string, locale string) (*MailTemplate, error) {
+	for _, l := range Locales(locale, r.DefaultLocale) {
+		t, err := r.Loader.LoadTemplate(ctx, id, l)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			if len(t.Id) == 0 {
+				t.Id = id
+			}
+			t.Locale = l
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: '%s' for locale '%s'", ErrTemplateNotFound, id, locale)
+}
+
+// Clear removes the compiled templates from the cache, after the templates are changed.
+func (r *TemplateRenderer) Clear() {
+	r.mu.Lock()
+	r.cache = make(map[string]*compiledTemplate)
+	r.mu.Unlock()
+}
+
+func (r *TemplateRenderer) Render(ctx context.Context, id string, locale string, data interface{}) (*RenderedMail, error) {
+	key := id + "|" + locale
+	var c *compiledTemplate
+	if r.Cache {
+		r.mu.RLock()
+		c = r.cache[key]
+		r.mu.RUnlock()
+	}
+	if c == nil {
+		t, err := r.Load(ctx, id, locale)
+		if err != nil {
+			return nil, err
+		}
+		c, err = r.compile(ctx, t, locale)
+		if err != nil {
+			return nil, err
+		}
+		if r.Cache {
+			r.mu.Lock()
+			if r.cache == nil {
+				r.cache = make(map[string]*compiledTemplate)
+			}
+			r.cache[key] = c
+			r.mu.Unlock()
+		}
+	}
+	return c.execute(data)
+}
+
+// Preview renders a template which may not be saved yet, with the layout and the partials of the locale, so that it can be checked before saving.
+func (r *TemplateRenderer) Preview(ctx context.Context, t MailTemplate, data interface{}) (*RenderedMail, error) {
+	c, err := r.compile(ctx, &t, t.Locale)
+	if err != nil {
+		return nil, err
+	}
+	return c.execute(data)
+}
+
+// Build renders the template and builds a mail with a text/plain and a text/html body.
+func (r *TemplateRenderer) Build(ctx context.Context, id string, locale string, data interface{}, from Email, to []Email, cc *[]Email) (*Mail, error) {
+	res, err := r.Render(ctx, id, locale, data)
+	if err != nil {
+		return nil, err
+	}
+	contents := []Content{*NewContent("text/plain", res.Text)}
+	if len(res.Html) > 0 {
+		contents = append(contents, *NewContent("text/html", res.Html))
+	}
+	return NewMailInit(from, res.Subject, to, cc, contents...), nil
+}
+
+func (r *TemplateRenderer) compile(ctx context.Context, t *MailTemplate, locale string) (*compiledTemplate, error) {
+	c := &compiledTemplate{id: t.Id, locale: t.Locale}
+	var layout *MailTemplate
+	layoutId := t.Layout
+	if len(layoutId) == 0 {
+		layoutId = r.Layout
+	}
+	if len(layoutId) > 0 && layoutId != t.Id {
+		var err error
+		layout, err = r.Load(ctx, layoutId, locale)
+		if err != nil {
+			return nil, err
+		}
+	}
+	partials := make([]*MailTemplate, 0, len(r.Partials))
+	for _, p := range r.Partials {
+		pt, err := r.Load(ctx, p, locale)
+		if err != nil {
+			return nil, err
+		}
+		partials = append(partials, pt)
+	}
+	var err error
+	c.subject, err = template.New("subject").Option("print=text").Funcs(r.Funcs).Parse(t.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse subject of '%s': %w", t.Id, err)
+	}
+	if len(t.Html) > 0 {
+		var layoutHtml string
+		if layout != nil {
+			layoutHtml = layout.Html
+		}
+		c.html, err = r.parse(t.Id, "html", t.Html, layoutHtml, partials, func(p *MailTemplate) string { return p.Html })
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(t.Text) > 0 {
+		var layoutText string
+		if layout != nil {
+			layoutText = layout.Text
+		}
+		c.text, err = r.parse(t.Id, "text", t.Text, layoutText, partials, func(p *MailTemplate) string { return p.Text })
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+func (r *TemplateRenderer) parse(id string, mode string, body string, layout string, partials []*MailTemplate, get func(*MailTemplate) string) (*template.Template, error) {
+	root := template.New(id).Option("print=" + mode).Funcs(r.Funcs)
+	for i, p := range partials {
+		s := get(p)
+		if len(s) == 0 {
+			continue
+		}
+		if _, err := root.New(r.Partials[i]).Parse(s); err != nil {
+			return nil, fmt.Errorf("cannot parse %s of partial '%s': %w", mode, r.Partials[i], err)
+		}
+	}
+	if len(layout) > 0 {
+		if _, err := root.New(ContentTemplate).Parse(body); err != nil {
+			return nil, fmt.Errorf("cannot parse %s of '%s': %w", mode, id, err)
+		}
+		if _, err := root.Parse(layout); err != nil {
+			return nil, fmt.Errorf("cannot parse %s of layout: %w", mode, err)
+		}
+		return root, nil
+	}
+	if _, err := root.Parse(body); err != nil {
+		return nil, fmt.Errorf("cannot parse %s of '%s': %w", mode, id, err)
+	}
+	return root, nil
+}
+func (c *compiledTemplate) execute(data interface{}) (*RenderedMail, error) {
+	res := &RenderedMail{Id: c.id, Locale: c.locale}
+	var b bytes.Buffer
+	if err := c.subject.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	res.Subject = strings.Join(strings.Fields(b.String()), " ")
+	if c.html != nil {
+		b.Reset()
+		if err := c.html.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		res.Html = b.String()
+	}
+	if c.text != nil {
+		b.Reset()
+		if err := c.text.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		res.Text = b.String()
+	} else if len(res.Html) > 0 {
+		res.Text = HtmlToText(res.Html)
+	}
+	return res, nil
+}
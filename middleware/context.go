@@ -11,6 +11,7 @@ import (
 
 func BuildContextWithMask(next http.Handler, mask func(fieldName, s string) string) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
+		fieldConfig := getFieldConfig()
 		var ctx context.Context
 		ctx = r.Context()
 		if len(fieldConfig.Ip) > 0 {
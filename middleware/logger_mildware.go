@@ -9,6 +9,7 @@ import (
 )
 
 func InitializeFieldConfig(c LogConfig) {
+	fieldConfig := &FieldConfig{}
 	if len(c.Duration) > 0 {
 		fieldConfig.Duration = c.Duration
 	} else {
@@ -37,14 +38,27 @@ func InitializeFieldConfig(c LogConfig) {
 		fields := strings.Split(c.Skips, ",")
 		fieldConfig.Skips = fields
 	}
+	fieldConfigs.Store(fieldConfig)
+}
+
+// Reload applies the new log config to the field config. It can be subscribed to a config watcher with config.Select.
+func Reload(before LogConfig, after LogConfig) {
+	InitializeFieldConfig(after)
 }
 func Logger(c LogConfig, log func(ctx context.Context, msg string, fields map[string]interface{}), f Formatter) func(h http.Handler) http.Handler {
-	InitializeFieldConfig(c)
+	return LoggerWithConfig(func() LogConfig { return c }, log, f)
+}
+
+// LoggerWithConfig gets the log config for each request, so that the config can be reloaded at runtime.
+func LoggerWithConfig(getConfig func() LogConfig, log func(ctx context.Context, msg string, fields map[string]interface{}), f Formatter) func(h http.Handler) http.Handler {
+	InitializeFieldConfig(getConfig())
 	return func(h http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			fieldConfig := getFieldConfig()
 			if !fieldConfig.Log || InSkipList(r, fieldConfig.Skips) {
 				h.ServeHTTP(w, r)
 			} else {
+				c := getConfig()
 				dw := NewResponseWriter(w)
 				ww := NewWrapResponseWriter(dw, r.ProtoMajor)
 				startTime := time.Now()
@@ -54,6 +54,7 @@ func (l *MaskLogger) LogRequest(log func(context.Context, string, map[string]int
 }
 
 func MaskResponse(ww WrapResponseWriter, c LogConfig, t1 time.Time, response string, fields map[string]interface{}, mask func(map[string]interface{}), isJsonFormat bool) {
+	fieldConfig := getFieldConfig()
 	if len(c.Response) > 0 {
 		fields[c.Response] = response
 		responseBody := response
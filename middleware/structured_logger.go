@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,7 +21,14 @@ type StructuredLogger struct {
 	JsonFormat bool
 }
 
-var fieldConfig FieldConfig
+var fieldConfigs atomic.Value
+
+func getFieldConfig() *FieldConfig {
+	if c, ok := fieldConfigs.Load().(*FieldConfig); ok {
+		return c
+	}
+	return &FieldConfig{}
+}
 
 func NewLogger() *StructuredLogger {
 	return &StructuredLogger{}
@@ -67,6 +75,7 @@ func (l *StructuredLogger) LogRequest(log func(context.Context, string, map[stri
 }
 
 func BuildResponseBody(ww WrapResponseWriter, c LogConfig, t1 time.Time, response string, fields map[string]interface{}, isJsonFormat bool) {
+	fieldConfig := getFieldConfig()
 	if len(c.Response) > 0 {
 		if isJsonFormat {
 			responseBody := response
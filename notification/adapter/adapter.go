@@ -74,7 +74,12 @@ func (a *NotificationAdapter) Push(ctx context.Context, noti *n.Notification) (i
 	if err != nil {
 		return -1, nil
 	}
+	noti.Id = id
 	now := time.Now()
+	if noti.Time != nil {
+		now = *noti.Time
+	}
+	noti.Time = &now
 	query := fmt.Sprintf("insert into %s(%s,%s,%s,%s,%s,%s %s) values (%s,%s,%s,%s,%s,%s %s)", a.Table,
 		a.Id, a.Sender, a.Receiver, a.Message, a.Url, a.Time, a.SuffixColumn,
 		a.BuildParam(1), a.BuildParam(2), a.BuildParam(3), a.BuildParam(4), a.BuildParam(5), a.BuildParam(6), a.SuffixValue)
@@ -98,6 +103,11 @@ func (a *NotificationAdapter) PushNotifications(ctx context.Context, ns []n.Noti
 		ns[i].Id = id
 	}
 	now := time.Now()
+	for i := 0; i < l; i++ {
+		if ns[i].Time == nil {
+			ns[i].Time = &now
+		}
+	}
 	ss := make([]string, 0)
 	args := make([]interface{}, 0)
 
@@ -108,7 +118,7 @@ func (a *NotificationAdapter) PushNotifications(ctx context.Context, ns []n.Noti
 		args = append(args, ns[i].Receiver)
 		args = append(args, ns[i].Message)
 		args = append(args, ns[i].Url)
-		args = append(args, now)
+		args = append(args, *ns[i].Time)
 		s := fmt.Sprintf("(%s,%s,%s,%s,%s,%s %s)", a.BuildParam(k), a.BuildParam(k+1), a.BuildParam(k+2), a.BuildParam(k+3), a.BuildParam(k+4), a.BuildParam(k+5), a.SuffixValue)
 		ss = append(ss, s)
 		k = k + 6
@@ -4,14 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	n "github.com/core-go/core/notifications"
+	q "github.com/core-go/core/query"
 	u "github.com/core-go/core/user"
 )
 
 type NotificationAdapter struct {
 	DB         *sql.DB
 	BuildParam func(int) string
+	Driver     string
 	Table      string
 	Id         string
 	Time       string
@@ -65,7 +68,7 @@ func NewNotificationAdapter(db *sql.DB, buildParam func(int) string, getUsers fu
 	} else {
 		read = "read"
 	}
-	return &NotificationAdapter{DB: db, BuildParam: buildParam, Table: table, ReadValue: readValue, Receiver: receiver, Sender: sender, Time: time, Message: message, Url: url, Id: id, Read: read, GetUsers: getUsers}
+	return &NotificationAdapter{DB: db, BuildParam: buildParam, Driver: q.GetDriver(db), Table: table, ReadValue: readValue, Receiver: receiver, Sender: sender, Time: time, Message: message, Url: url, Id: id, Read: read, GetUsers: getUsers}
 }
 func (a *NotificationAdapter) GetNotifications(ctx context.Context, receiver string, read *bool, limit int64, nextPageToken string) ([]n.Notification, string, error) {
 	if limit <= 0 {
@@ -124,6 +127,12 @@ func (a *NotificationAdapter) GetNotifications(ctx context.Context, receiver str
 	if len(items) == 0 {
 		return items, "", nil
 	}
+	if err = a.setNotifiers(ctx, items); err != nil {
+		return items, "", err
+	}
+	return items, items[len(items)-1].Id, nil
+}
+func (a *NotificationAdapter) setNotifiers(ctx context.Context, items []n.Notification) error {
 	if a.GetUsers != nil {
 		var userIds []string
 		for _, hi := range items {
@@ -132,7 +141,7 @@ func (a *NotificationAdapter) GetNotifications(ctx context.Context, receiver str
 		ids := u.Unique(userIds)
 		users, err := a.GetUsers(ctx, ids)
 		if err != nil {
-			return items, "", err
+			return err
 		}
 		usersMap := u.ToMap(users)
 		l := len(items)
@@ -144,7 +153,50 @@ func (a *NotificationAdapter) GetNotifications(ctx context.Context, receiver str
 			}
 		}
 	}
-	return items, items[len(items)-1].Id, nil
+	return nil
+}
+
+// GetNotificationsSince returns the notifications after the last event id, from the oldest, to backfill a stream after a reconnect.
+// The notifications are ordered by (time, id), because many notifications of a batch have the same time.
+func (a *NotificationAdapter) GetNotificationsSince(ctx context.Context, receiver string, lastEventId string, limit int64) ([]n.Notification, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var last time.Time
+	query := fmt.Sprintf("select %s from %s where %s = %s and %s = %s", a.Time, a.Table, a.Id, a.BuildParam(1), a.Receiver, a.BuildParam(2))
+	if err := a.DB.QueryRowContext(ctx, query, lastEventId, receiver).Scan(&last); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	query = fmt.Sprintf("select %s, %s, %s, %s, %s, %s from %s where %s = %s and (%s > %s or (%s = %s and %s > %s)) order by %s, %s",
+		a.Id, a.Time, a.Read, a.Sender, a.Message, a.Url, a.Table, a.Receiver, a.BuildParam(1),
+		a.Time, a.BuildParam(2), a.Time, a.BuildParam(3), a.Id, a.BuildParam(4), a.Time, a.Id)
+	query = q.BuildPagingQuery(query, limit, 0, a.Driver)
+	rows, err := a.DB.QueryContext(ctx, query, receiver, last, last, lastEventId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []n.Notification
+	for rows.Next() {
+		var item n.Notification
+		if err = rows.Scan(&item.Id, &item.Time, &item.Read, &item.Sender, &item.Message, &item.Url); err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil || len(items) == 0 {
+		return items, err
+	}
+	return items, a.setNotifiers(ctx, items)
+}
+func (a *NotificationAdapter) CountUnread(ctx context.Context, receiver string) (int64, error) {
+	query := fmt.Sprintf("select count(*) from %s where %s = %s and %s is null", a.Table, a.Receiver, a.BuildParam(1), a.Read)
+	var count int64
+	err := a.DB.QueryRowContext(ctx, query, receiver).Scan(&count)
+	return count, err
 }
 func (a *NotificationAdapter) SetRead(ctx context.Context, id string, v bool) (int64, error) {
 	p := "null"
@@ -18,6 +18,8 @@ type Handler struct {
 	NextPageToken string
 	List          string
 	Next          string
+	// Changed is called after a notification is read, such as Hub.Refresh to update the unread counters
+	Changed func(ctx context.Context, receiver string) error
 }
 
 func NewNotificationsHandler(service NotificationsPort, logError func(context.Context, string, ...map[string]interface{}), opts ...string) *Handler {
@@ -96,6 +98,11 @@ func (h *Handler) SetRead(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if res > 0 {
+			if h.Changed != nil {
+				if userId, ok := GetUser(r.Context(), h.UserId); ok {
+					h.Changed(r.Context(), userId)
+				}
+			}
 			JSON(w, http.StatusOK, res)
 		} else {
 			JSON(w, http.StatusNotFound, res)
@@ -0,0 +1,356 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	EventNotification = "notification"
+	EventUnread       = "unread"
+)
+
+type Event struct {
+	Type         string        `yaml:"type" mapstructure:"type" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
+	Receiver     string        `yaml:"receiver" mapstructure:"receiver" json:"receiver,omitempty" gorm:"column:receiver" bson:"receiver,omitempty" dynamodbav:"receiver,omitempty" firestore:"receiver,omitempty"`
+	Notification *Notification `yaml:"notification" mapstructure:"notification" json:"notification,omitempty" gorm:"column:notification" bson:"notification,omitempty" dynamodbav:"notification,omitempty" firestore:"notification,omitempty"`
+	Unread       *int64        `yaml:"unread" mapstructure:"unread" json:"unread,omitempty" gorm:"column:unread" bson:"unread,omitempty" dynamodbav:"unread,omitempty" firestore:"unread,omitempty"`
+}
+
+// StreamPort is used by the hub to backfill the notifications after the last event id, and to count the unread notifications.
+type StreamPort interface {
+	GetNotificationsSince(ctx context.Context, receiver string, lastEventId string, limit int64) ([]Notification, error)
+	CountUnread(ctx context.Context, receiver string) (int64, error)
+}
+
+// Backend fans out the events to all the hubs. LocalBackend is enough for a single instance; with several instances, use MQBackend or another implementation over a shared pub/sub.
+type Backend interface {
+	Publish(ctx context.Context, event Event) error
+	Subscribe(ctx context.Context, handle func(ctx context.Context, event Event)) error
+}
+
+type LocalBackend struct {
+	mu      sync.RWMutex
+	handles []func(ctx context.Context, event Event)
+}
+
+func NewLocalBackend() *LocalBackend {
+	return &LocalBackend{}
+}
+func (b *LocalBackend) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handles := b.handles
+	b.mu.RUnlock()
+	for _, handle := range handles {
+		handle(ctx, event)
+	}
+	return nil
+}
+func (b *LocalBackend) Subscribe(ctx context.Context, handle func(ctx context.Context, event Event)) error {
+	b.mu.Lock()
+	b.handles = append(b.handles, handle)
+	b.mu.Unlock()
+	return nil
+}
+
+// MQBackend publishes the events to a topic, which every instance subscribes to; Receive must be called by the consumer of this topic on every instance.
+type MQBackend struct {
+	Send   func(ctx context.Context, data []byte, attributes map[string]string) (string, error)
+	mu     sync.RWMutex
+	handle func(ctx context.Context, event Event)
+}
+
+func NewMQBackend(publish func(ctx context.Context, data []byte, attributes map[string]string) (string, error)) *MQBackend {
+	return &MQBackend{Send: publish}
+}
+func (b *MQBackend) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = b.Send(ctx, data, map[string]string{"type": event.Type})
+	return err
+}
+func (b *MQBackend) Subscribe(ctx context.Context, handle func(ctx context.Context, event Event)) error {
+	b.mu.Lock()
+	b.handle = handle
+	b.mu.Unlock()
+	return nil
+}
+func (b *MQBackend) Receive(ctx context.Context, data []byte, attributes map[string]string) error {
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+	b.mu.RLock()
+	handle := b.handle
+	b.mu.RUnlock()
+	if handle == nil {
+		return errors.New("the hub is not subscribed")
+	}
+	handle(ctx, event)
+	return nil
+}
+
+// Client is a connection of a user; a user may have several connections, one per tab.
+type Client struct {
+	UserId string
+	Events chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+func (c *Client) Close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// Hub pushes the new notifications to the connected users, over Server-Sent Events or WebSocket.
+//   - The user id is from the context, which is set by the security middleware.
+//   - On connect, the notifications after the last event id are sent, then the unread counter.
+//   - A client, which does not read fast enough, is disconnected, and backfills on reconnect.
+type Hub struct {
+	Backend     Backend
+	Port        StreamPort
+	LogError    func(context.Context, string, ...map[string]interface{})
+	UserId      string
+	Heartbeat   time.Duration
+	Buffer      int
+	Backfill    int64
+	Retry       time.Duration
+	CheckOrigin func(r *http.Request) bool
+	mu          sync.RWMutex
+	clients     map[string]map[*Client]struct{}
+}
+
+// NewHub creates the hub; if backend is nil, a LocalBackend is used. If port is nil, there is no backfill and no unread counter. opts[0] is the key of the user id in the context.
+func NewHub(backend Backend, port StreamPort, logError func(context.Context, string, ...map[string]interface{}), opts ...string) *Hub {
+	if backend == nil {
+		backend = NewLocalBackend()
+	}
+	userId := UserId
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		userId = opts[0]
+	}
+	return &Hub{Backend: backend, Port: port, LogError: logError, UserId: userId, Heartbeat: 25 * time.Second, Buffer: 16, Backfill: 100, Retry: 3 * time.Second, clients: make(map[string]map[*Client]struct{})}
+}
+
+// Start subscribes the hub to the backend.
+func (h *Hub) Start(ctx context.Context) error {
+	return h.Backend.Subscribe(ctx, h.Dispatch)
+}
+
+// Publish sends a new notification to the connected clients of the receiver, on all instances.
+func (h *Hub) Publish(ctx context.Context, receiver string, n Notification) error {
+	return h.Backend.Publish(ctx, Event{Type: EventNotification, Receiver: receiver, Notification: &n})
+}
+
+// Refresh sends the unread counter to the connected clients of the receiver, after the notifications are read.
+func (h *Hub) Refresh(ctx context.Context, receiver string) error {
+	return h.Backend.Publish(ctx, Event{Type: EventUnread, Receiver: receiver})
+}
+
+// Dispatch delivers an event from the backend to the clients of this instance.
+func (h *Hub) Dispatch(ctx context.Context, e Event) {
+	h.mu.RLock()
+	n := len(h.clients[e.Receiver])
+	h.mu.RUnlock()
+	if n == 0 {
+		return
+	}
+	var unread *Event
+	if e.Type == EventUnread && e.Unread != nil {
+		unread = &e
+	} else if h.Port != nil {
+		unread = h.unread(ctx, e.Receiver)
+	}
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients[e.Receiver]))
+	for c := range h.clients[e.Receiver] {
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+	for _, c := range clients {
+		if e.Type == EventNotification && e.Notification != nil {
+			h.send(c, e)
+		}
+		if unread != nil {
+			h.send(c, *unread)
+		}
+	}
+}
+func (h *Hub) send(c *Client, e Event) {
+	select {
+	case c.Events <- e:
+	case <-c.done:
+	default:
+		c.Close()
+	}
+}
+func (h *Hub) unread(ctx context.Context, receiver string) *Event {
+	count, err := h.Port.CountUnread(ctx, receiver)
+	if err != nil {
+		h.logError(ctx, "cannot count the unread notifications of "+receiver+": "+err.Error())
+		return nil
+	}
+	return &Event{Type: EventUnread, Receiver: receiver, Unread: &count}
+}
+
+func (h *Hub) Register(userId string) *Client {
+	c := &Client{UserId: userId, Events: make(chan Event, h.Buffer), done: make(chan struct{})}
+	h.mu.Lock()
+	m, ok := h.clients[userId]
+	if !ok {
+		m = make(map[*Client]struct{})
+		h.clients[userId] = m
+	}
+	m[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+func (h *Hub) Unregister(c *Client) {
+	c.Close()
+	h.mu.Lock()
+	if m, ok := h.clients[c.UserId]; ok {
+		delete(m, c)
+		if len(m) == 0 {
+			delete(h.clients, c.UserId)
+		}
+	}
+	h.mu.Unlock()
+}
+
+// Connections returns the number of connections of the user on this instance.
+func (h *Hub) Connections(userId string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userId])
+}
+
+// stream writes the backfill, the unread counter, then the events of the client until the context is done or the client is closed.
+func (h *Hub) stream(ctx context.Context, c *Client, lastEventId string, write func(Event) error, ping func() error) error {
+	seen := make(map[string]bool)
+	if h.Port != nil {
+		if len(lastEventId) > 0 {
+			list, err := h.Port.GetNotificationsSince(ctx, c.UserId, lastEventId, h.Backfill)
+			if err != nil {
+				h.logError(ctx, "cannot get the notifications of "+c.UserId+": "+err.Error())
+			}
+			for i := range list {
+				seen[list[i].Id] = true
+				if err = write(Event{Type: EventNotification, Receiver: c.UserId, Notification: &list[i]}); err != nil {
+					return err
+				}
+			}
+		}
+		if unread := h.unread(ctx, c.UserId); unread != nil {
+			if err := write(*unread); err != nil {
+				return err
+			}
+		}
+	}
+	var tick <-chan time.Time
+	if h.Heartbeat > 0 {
+		ticker := time.NewTicker(h.Heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.done:
+			return nil
+		case e := <-c.Events:
+			if e.Notification != nil && seen[e.Notification.Id] {
+				continue
+			}
+			if err := write(e); err != nil {
+				return err
+			}
+		case <-tick:
+			if err := ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ServeSSE streams the events as Server-Sent Events: "notification" with the id of the notification, and "unread" with the counter.
+// The last event id is from the Last-Event-ID header, which is sent by EventSource on reconnect, or from the lastEventId query parameter.
+func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	userId, ok := RequireUser(r.Context(), w, h.UserId)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if len(lastEventId) == 0 {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+	c := h.Register(userId)
+	defer h.Unregister(c)
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if h.Retry > 0 {
+		fmt.Fprintf(w, "retry: %d\n\n", h.Retry.Milliseconds())
+	}
+	flusher.Flush()
+	write := func(e Event) error {
+		if err := WriteSSE(w, e); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	ping := func() error {
+		if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	h.stream(r.Context(), c, lastEventId, write, ping)
+}
+
+// WriteSSE writes an event in the format of Server-Sent Events.
+func WriteSSE(w http.ResponseWriter, e Event) error {
+	var data interface{}
+	if e.Type == EventNotification {
+		data = e.Notification
+	} else {
+		data = map[string]interface{}{"unread": e.Unread}
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if e.Type == EventNotification && e.Notification != nil && len(e.Notification.Id) > 0 {
+		_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.Notification.Id, e.Type, b)
+	} else {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b)
+	}
+	return err
+}
+func (h *Hub) logError(ctx context.Context, msg string) {
+	if h.LogError != nil {
+		h.LogError(ctx, msg)
+	}
+}
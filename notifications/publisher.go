@@ -0,0 +1,51 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/core-go/core/notification"
+)
+
+// Publisher saves the notifications by the port, then publishes them to the hub, so that it can replace the NotificationPort of the notification package.
+// If the notifications are saved in a transaction, publish them after the commit instead, because the backfill reads only the committed notifications.
+type Publisher struct {
+	Port     notification.NotificationPort
+	Hub      *Hub
+	LogError func(context.Context, string, ...map[string]interface{})
+}
+
+func NewPublisher(port notification.NotificationPort, hub *Hub, logError func(context.Context, string, ...map[string]interface{})) *Publisher {
+	return &Publisher{Port: port, Hub: hub, LogError: logError}
+}
+func (p *Publisher) Push(ctx context.Context, noti *notification.Notification) (int64, error) {
+	res, err := p.Port.Push(ctx, noti)
+	if err != nil || res <= 0 {
+		return res, err
+	}
+	p.publish(ctx, *noti)
+	return res, nil
+}
+func (p *Publisher) PushNotifications(ctx context.Context, notifications []notification.Notification) (int64, error) {
+	res, err := p.Port.PushNotifications(ctx, notifications)
+	if err != nil || res <= 0 {
+		return res, err
+	}
+	for _, noti := range notifications {
+		p.publish(ctx, noti)
+	}
+	return res, nil
+}
+
+// publish sends the time, which the port stored, so a live event has the same time as the same notification in a backfill.
+func (p *Publisher) publish(ctx context.Context, noti notification.Notification) {
+	t := noti.Time
+	if t == nil {
+		now := time.Now()
+		t = &now
+	}
+	n := Notification{Id: noti.Id, Sender: noti.Sender, Url: noti.Url, Message: noti.Message, Time: t}
+	if err := p.Hub.Publish(ctx, noti.Receiver, n); err != nil && p.LogError != nil {
+		p.LogError(ctx, "cannot publish the notification to "+noti.Receiver+": "+err.Error())
+	}
+}
@@ -0,0 +1,212 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	wsText  = 1
+	wsClose = 8
+	wsPing  = 9
+	wsPong  = 10
+	wsGuid  = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// the client only sends the control frames, so its frames are small
+	wsMaxPayload = 4096
+)
+
+// ServeWebSocket streams the events as JSON text frames over a WebSocket (RFC 6455). The server does not expect any message from the client, except the control frames.
+// The last event id is from the lastEventId query parameter.
+func (h *Hub) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	userId, ok := RequireUser(r.Context(), w, h.UserId)
+	if !ok {
+		return
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || !headerContains(r.Header, "Connection", "upgrade") {
+		http.Error(w, "websocket upgrade is required", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if len(key) == 0 {
+		http.Error(w, "Sec-WebSocket-Key is required", http.StatusBadRequest)
+		return
+	}
+	checkOrigin := h.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = SameOrigin
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "origin is not allowed", http.StatusForbidden)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket is not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		h.logError(r.Context(), "cannot hijack the connection: "+err.Error())
+		return
+	}
+	defer conn.Close()
+	sum := sha1.Sum([]byte(key + wsGuid))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err = rw.Flush(); err != nil {
+		return
+	}
+	ws := &wsConn{conn: conn, reader: rw.Reader}
+	// the request context is not canceled after hijacking, the read loop cancels it when the client closes
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	c := h.Register(userId)
+	defer h.Unregister(c)
+	go func() {
+		ws.readLoop()
+		cancel()
+	}()
+	write := func(e Event) error {
+		b, er1 := json.Marshal(e)
+		if er1 != nil {
+			return er1
+		}
+		return ws.write(wsText, b)
+	}
+	ping := func() error {
+		return ws.write(wsPing, nil)
+	}
+	if err = h.stream(ctx, c, r.URL.Query().Get("lastEventId"), write, ping); err == nil {
+		ws.write(wsClose, []byte{3, 233}) // 1001 going away
+	}
+}
+
+// SameOrigin allows the requests without Origin, and the requests whose Origin has the same host as the request.
+func SameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+func headerContains(header http.Header, name string, value string) bool {
+	for _, v := range header.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	mu     sync.Mutex
+}
+
+func (c *wsConn) write(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	n := len(payload)
+	switch {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = append(header, byte(n>>8), byte(n))
+	default:
+		header[1] = 127
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(n))
+		header = append(header, b...)
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readLoop answers the ping and the close frames, and returns when the connection is closed.
+func (c *wsConn) readLoop() {
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsPing:
+			if c.write(wsPong, payload) != nil {
+				return
+			}
+		case wsClose:
+			c.write(wsClose, payload)
+			return
+		}
+	}
+}
+func (c *wsConn) readFrame() (byte, []byte, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(c.reader, h[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := h[0] & 0x0F
+	masked := h[1]&0x80 != 0
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.reader, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.reader, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if !masked {
+		return 0, nil, errors.New("the frames of the client must be masked")
+	}
+	if n > wsMaxPayload {
+		return 0, nil, errors.New("the frame is too large")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
@@ -0,0 +1,66 @@
+package passcode
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidChallenge = errors.New("sign in challenge is invalid or expired")
+
+// Challenger issues the challenges of the pending sign ins. A challenge is issued by the server after the first factor, such as the password,
+// and binds the second factor to the user of the first factor, so the id of the user is never taken from the client.
+// A challenge is the user id, the expiry and a nonce, signed by HMAC-SHA256; it expires in 5 minutes by default.
+type Challenger struct {
+	Secret  []byte
+	Expires time.Duration
+}
+
+func NewChallenger(secret string, opts ...time.Duration) *Challenger {
+	expires := 5 * time.Minute
+	if len(opts) > 0 && opts[0] > 0 {
+		expires = opts[0]
+	}
+	return &Challenger{Secret: []byte(secret), Expires: expires}
+}
+
+// Issue creates a challenge for a user, who passed the first factor; it should be returned to the client, which sends it with the second factor.
+func (c *Challenger) Issue(id string) (string, error) {
+	if len(c.Secret) == 0 {
+		return "", errors.New("secret is required to issue a challenge")
+	}
+	payload := make([]byte, 8+16, 8+16+len(id))
+	binary.BigEndian.PutUint64(payload[:8], uint64(time.Now().Add(c.Expires).Unix()))
+	if _, err := rand.Read(payload[8:24]); err != nil {
+		return "", err
+	}
+	payload = append(payload, id...)
+	p := base64.RawURLEncoding.EncodeToString(payload)
+	return p + "." + c.sign(p), nil
+}
+
+// Verify returns the user id of a challenge, or ErrInvalidChallenge if the challenge is not signed by the secret, or is expired.
+func (c *Challenger) Verify(challenge string) (string, error) {
+	i := strings.LastIndex(challenge, ".")
+	if i <= 0 || len(c.Secret) == 0 || !hmac.Equal([]byte(challenge[i+1:]), []byte(c.sign(challenge[:i]))) {
+		return "", ErrInvalidChallenge
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(challenge[:i])
+	if err != nil || len(payload) <= 24 {
+		return "", ErrInvalidChallenge
+	}
+	if time.Now().Unix() > int64(binary.BigEndian.Uint64(payload[:8])) {
+		return "", ErrInvalidChallenge
+	}
+	return string(payload[24:]), nil
+}
+func (c *Challenger) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.Secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
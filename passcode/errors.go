@@ -0,0 +1,34 @@
+package passcode
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidCode   = errors.New("invalid passcode")
+	ErrExpiredCode   = errors.New("passcode is expired or not found")
+	ErrLocked        = errors.New("too many failed attempts")
+	ErrResendTooSoon = errors.New("passcode was sent recently")
+	ErrNotEnrolled   = errors.New("two-factor authentication is not enabled")
+	ErrEnrolled      = errors.New("two-factor authentication is already enabled")
+)
+
+// VerifyError wraps ErrInvalidCode, ErrLocked or ErrResendTooSoon with the remaining attempts, or with the time to wait before the next request.
+type VerifyError struct {
+	Err        error
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+func (e *VerifyError) Error() string {
+	return e.Err.Error()
+}
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+func IsLocked(err error) bool {
+	var e *VerifyError
+	return errors.As(err, &e) && e.Err == ErrLocked
+}
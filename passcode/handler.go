@@ -0,0 +1,227 @@
+package passcode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/core-go/core"
+)
+
+type VerifyRequest struct {
+	Id      string `yaml:"id" mapstructure:"id" json:"id,omitempty" gorm:"column:id" bson:"id,omitempty" dynamodbav:"id,omitempty" firestore:"id,omitempty"`
+	Code    string `yaml:"code" mapstructure:"code" json:"code,omitempty" gorm:"column:code" bson:"code,omitempty" dynamodbav:"code,omitempty" firestore:"code,omitempty"`
+	Account string `yaml:"account" mapstructure:"account" json:"account,omitempty" gorm:"column:account" bson:"account,omitempty" dynamodbav:"account,omitempty" firestore:"account,omitempty"`
+	// Challenge is issued by Challenger after the first factor, and identifies the pending sign in.
+	Challenge string `yaml:"challenge" mapstructure:"challenge" json:"challenge,omitempty" gorm:"-" bson:"-" dynamodbav:"-" firestore:"-"`
+}
+
+// Handler exposes the passcode and the TOTP verification to the sign in flow.
+//   - Send, Verify, VerifyTOTP and Recover get the id from the challenge of the pending sign in, which is issued by Challenger after the first factor; an id in the request body is rejected.
+//   - Enroll, Confirm, RegenerateRecoveryCodes and Disable get the id of the signed in user from the context.
+//
+// GetContact returns the address to send the passcode to, so that the address is never given by the client.
+// Success is called after a successful verification, to issue the token; if it is nil, the status 200 is returned.
+// If the csrf protection is enabled, Success should be wrapped by csrf.Handler.SignIn, so the csrf token is rotated on sign in.
+type Handler struct {
+	Verifier   *Verifier
+	MFA        *MFAService
+	Challenger *Challenger
+	GetContact func(ctx context.Context, id string) (string, interface{}, error)
+	Success    func(w http.ResponseWriter, r *http.Request, id string)
+	LogError   func(context.Context, string, ...map[string]interface{})
+	UserId     string
+}
+
+func NewHandler(verifier *Verifier, mfa *MFAService, challenger *Challenger, getContact func(ctx context.Context, id string) (string, interface{}, error), success func(w http.ResponseWriter, r *http.Request, id string), logError func(context.Context, string, ...map[string]interface{}), opts ...string) *Handler {
+	userId := "userId"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		userId = opts[0]
+	}
+	return &Handler{Verifier: verifier, MFA: mfa, Challenger: challenger, GetContact: getContact, Success: success, LogError: logError, UserId: userId}
+}
+
+func (h *Handler) Send(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decode(w, r, false)
+	if !ok {
+		return
+	}
+	to, params, err := h.GetContact(r.Context(), req.Id)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	if len(to) == 0 {
+		http.Error(w, "cannot find the contact", http.StatusNotFound)
+		return
+	}
+	expireAt, err := h.Verifier.Send(r.Context(), req.Id, to, params)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	core.JSON(w, http.StatusOK, map[string]interface{}{"expiredAt": expireAt})
+}
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decode(w, r, true)
+	if !ok {
+		return
+	}
+	if err := h.Verifier.Verify(r.Context(), req.Id, req.Code); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	h.success(w, r, req.Id)
+}
+func (h *Handler) VerifyTOTP(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decode(w, r, true)
+	if !ok {
+		return
+	}
+	if err := h.MFA.Verify(r.Context(), req.Id, req.Code); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	h.success(w, r, req.Id)
+}
+func (h *Handler) Recover(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decode(w, r, true)
+	if !ok {
+		return
+	}
+	remaining, err := h.MFA.Recover(r.Context(), req.Id, req.Code)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	w.Header().Set("X-Recovery-Codes-Remaining", strconv.Itoa(remaining))
+	h.success(w, r, req.Id)
+}
+func (h *Handler) Enroll(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+	var req VerifyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "cannot decode request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	account := req.Account
+	if len(account) == 0 {
+		account = id
+	}
+	res, err := h.MFA.Enroll(r.Context(), id, account)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	core.JSON(w, http.StatusOK, res)
+}
+func (h *Handler) Confirm(w http.ResponseWriter, r *http.Request) {
+	h.withUser(w, r, func(ctx context.Context, id string, code string) (interface{}, error) {
+		codes, err := h.MFA.Confirm(ctx, id, code)
+		return map[string]interface{}{"recoveryCodes": codes}, err
+	})
+}
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	h.withUser(w, r, func(ctx context.Context, id string, code string) (interface{}, error) {
+		codes, err := h.MFA.RegenerateRecoveryCodes(ctx, id, code)
+		return map[string]interface{}{"recoveryCodes": codes}, err
+	})
+}
+func (h *Handler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.withUser(w, r, func(ctx context.Context, id string, code string) (interface{}, error) {
+		return true, h.MFA.Disable(ctx, id, code)
+	})
+}
+
+func (h *Handler) withUser(w http.ResponseWriter, r *http.Request, f func(ctx context.Context, id string, code string) (interface{}, error)) {
+	id, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Code) == 0 {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+	res, err := f(r.Context(), id, req.Code)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	core.JSON(w, http.StatusOK, res)
+}
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if u, ok := r.Context().Value(h.UserId).(string); ok && len(u) > 0 {
+		return u, true
+	}
+	http.Error(w, "cannot get current user", http.StatusForbidden)
+	return "", false
+}
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, code bool) (*VerifyRequest, bool) {
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "cannot decode request: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if len(req.Id) > 0 {
+		http.Error(w, "id must not be in the request", http.StatusBadRequest)
+		return nil, false
+	}
+	if len(req.Challenge) == 0 || (code && len(req.Code) == 0) {
+		http.Error(w, "challenge and code are required", http.StatusBadRequest)
+		return nil, false
+	}
+	if h.Challenger == nil {
+		http.Error(w, ErrInvalidChallenge.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	id, err := h.Challenger.Verify(req.Challenge)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	req.Id = id
+	return &req, true
+}
+func (h *Handler) success(w http.ResponseWriter, r *http.Request, id string) {
+	if h.Success != nil {
+		h.Success(w, r, id)
+		return
+	}
+	core.JSON(w, http.StatusOK, true)
+}
+func (h *Handler) respondError(w http.ResponseWriter, r *http.Request, err error) {
+	var e *VerifyError
+	if errors.As(err, &e) {
+		switch e.Err {
+		case ErrLocked, ErrResendTooSoon:
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(e.RetryAfter.Seconds()+0.999), 10))
+			core.JSON(w, http.StatusTooManyRequests, map[string]interface{}{"message": e.Error(), "retryAfter": int64(e.RetryAfter.Seconds() + 0.999)})
+		default:
+			core.JSON(w, http.StatusUnauthorized, map[string]interface{}{"message": e.Error(), "remaining": e.Remaining})
+		}
+		return
+	}
+	switch err {
+	case ErrExpiredCode:
+		core.JSON(w, http.StatusUnauthorized, map[string]interface{}{"message": err.Error()})
+	case ErrNotEnrolled:
+		core.JSON(w, http.StatusForbidden, map[string]interface{}{"message": err.Error()})
+	case ErrEnrolled:
+		core.JSON(w, http.StatusConflict, map[string]interface{}{"message": err.Error()})
+	default:
+		if h.LogError != nil {
+			h.LogError(r.Context(), err.Error())
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package passcode
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Lockout counts the failed attempts of an id in a PasscodeService, under the key Prefix+id.
+// The counter expires after Window without failure. After MaxAttempts failures, the id is locked for Duration.
+type Lockout struct {
+	Service     PasscodeService
+	MaxAttempts int
+	Window      time.Duration
+	Duration    time.Duration
+	Prefix      string
+	Now         func() time.Time
+}
+
+func NewLockout(service PasscodeService, maxAttempts int, window time.Duration, duration time.Duration, opts ...string) *Lockout {
+	prefix := "attempts:"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		prefix = opts[0]
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+	if duration <= 0 {
+		duration = 15 * time.Minute
+	}
+	return &Lockout{Service: service, MaxAttempts: maxAttempts, Window: window, Duration: duration, Prefix: prefix, Now: time.Now}
+}
+
+func (l *Lockout) load(ctx context.Context, id string) (int, time.Time, error) {
+	s, expireAt, err := l.Service.Load(ctx, l.Prefix+id)
+	if err != nil || len(s) == 0 || !expireAt.After(l.Now()) {
+		return 0, expireAt, err
+	}
+	n, _ := strconv.Atoi(s)
+	return n, expireAt, nil
+}
+
+// Check returns an error wrapping ErrLocked if the id is locked.
+func (l *Lockout) Check(ctx context.Context, id string) error {
+	n, expireAt, err := l.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n >= l.MaxAttempts {
+		return &VerifyError{Err: ErrLocked, RetryAfter: expireAt.Sub(l.Now())}
+	}
+	return nil
+}
+
+// Fail counts a failed attempt. It returns an error wrapping ErrLocked if the id becomes locked, or wrapping ErrInvalidCode with the remaining attempts.
+// The read and the write are not atomic, so that some concurrent attempts may be counted once.
+func (l *Lockout) Fail(ctx context.Context, id string) error {
+	n, _, err := l.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	n++
+	now := l.Now()
+	expireAt := now.Add(l.Window)
+	if n >= l.MaxAttempts {
+		expireAt = now.Add(l.Duration)
+	}
+	if _, err = l.Service.Save(ctx, l.Prefix+id, strconv.Itoa(n), expireAt); err != nil {
+		return err
+	}
+	if n >= l.MaxAttempts {
+		return &VerifyError{Err: ErrLocked, RetryAfter: l.Duration}
+	}
+	return &VerifyError{Err: ErrInvalidCode, Remaining: l.MaxAttempts - n}
+}
+func (l *Lockout) Reset(ctx context.Context, id string) error {
+	_, err := l.Service.Delete(ctx, l.Prefix+id)
+	return err
+}
@@ -0,0 +1,246 @@
+package passcode
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// TOTPKey is the second factor of a user. The secret may be encrypted by the SecretCipher, and the recovery codes are hashed.
+type TOTPKey struct {
+	Id            string     `yaml:"id" mapstructure:"id" json:"id,omitempty" gorm:"column:id;primary_key" bson:"_id,omitempty" dynamodbav:"id,omitempty" firestore:"-"`
+	Secret        string     `yaml:"secret" mapstructure:"secret" json:"-" gorm:"column:secret" bson:"secret,omitempty" dynamodbav:"secret,omitempty" firestore:"secret,omitempty"`
+	Enabled       bool       `yaml:"enabled" mapstructure:"enabled" json:"enabled,omitempty" gorm:"column:enabled" bson:"enabled,omitempty" dynamodbav:"enabled,omitempty" firestore:"enabled,omitempty"`
+	LastStep      int64      `yaml:"last_step" mapstructure:"last_step" json:"-" gorm:"column:laststep" bson:"lastStep,omitempty" dynamodbav:"lastStep,omitempty" firestore:"lastStep,omitempty"`
+	RecoveryCodes []string   `yaml:"recovery_codes" mapstructure:"recovery_codes" json:"-" gorm:"column:recoverycodes" bson:"recoveryCodes,omitempty" dynamodbav:"recoveryCodes,omitempty" firestore:"recoveryCodes,omitempty"`
+	CreatedAt     *time.Time `yaml:"created_at" mapstructure:"created_at" json:"createdAt,omitempty" gorm:"column:createdat" bson:"createdAt,omitempty" dynamodbav:"createdAt,omitempty" firestore:"createdAt,omitempty"`
+}
+
+type TOTPPort interface {
+	Load(ctx context.Context, id string) (*TOTPKey, error)
+	Save(ctx context.Context, key *TOTPKey) (int64, error)
+	Delete(ctx context.Context, id string) (int64, error)
+}
+
+// SecretCipher encrypts the TOTP secrets at rest, such as cipher.GCMDecrypter.
+type SecretCipher interface {
+	Encrypt(plainText string) (string, error)
+	Decrypt(cipherText string) (string, error)
+}
+
+type Enrollment struct {
+	Secret string `yaml:"secret" mapstructure:"secret" json:"secret,omitempty" gorm:"column:secret" bson:"secret,omitempty" dynamodbav:"secret,omitempty" firestore:"secret,omitempty"`
+	Uri    string `yaml:"uri" mapstructure:"uri" json:"uri,omitempty" gorm:"column:uri" bson:"uri,omitempty" dynamodbav:"uri,omitempty" firestore:"uri,omitempty"`
+}
+
+// MFAService enrols the users to TOTP, verifies the TOTP codes and the one-time recovery codes. Failed attempts are counted by the Lockout.
+// The recovery codes are random, so they are stored as HMAC-SHA256 digests with the RecoveryKey, or as SHA-256 digests if it is empty,
+// and not with a slow hash, which would be run against every stored code on each attempt.
+type MFAService struct {
+	Repository    TOTPPort
+	TOTP          *TOTP
+	RecoveryKey   []byte
+	Cipher        SecretCipher
+	Lockout       *Lockout
+	RecoveryCount int
+	Now           func() time.Time
+}
+
+func NewMFAService(repository TOTPPort, totp *TOTP, recoveryKey []byte, lockout *Lockout, opts ...SecretCipher) *MFAService {
+	var c SecretCipher
+	if len(opts) > 0 {
+		c = opts[0]
+	}
+	return &MFAService{Repository: repository, TOTP: totp, RecoveryKey: recoveryKey, Cipher: c, Lockout: lockout, RecoveryCount: 10, Now: time.Now}
+}
+
+// Enroll generates a new secret, which is not enabled until it is confirmed by a code of the authenticator app.
+// It fails if the user has enabled TOTP already: Disable must be called first.
+func (s *MFAService) Enroll(ctx context.Context, id string, account string) (*Enrollment, error) {
+	key, err := s.Repository.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil && key.Enabled {
+		return nil, ErrEnrolled
+	}
+	secret, err := s.TOTP.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	stored := secret
+	if s.Cipher != nil {
+		if stored, err = s.Cipher.Encrypt(secret); err != nil {
+			return nil, err
+		}
+	}
+	now := s.Now()
+	if _, err = s.Repository.Save(ctx, &TOTPKey{Id: id, Secret: stored, CreatedAt: &now}); err != nil {
+		return nil, err
+	}
+	return &Enrollment{Secret: secret, Uri: s.TOTP.ProvisioningURI(account, secret)}, nil
+}
+
+// Confirm enables TOTP if the code is valid, and returns the recovery codes, which are shown once to the user.
+func (s *MFAService) Confirm(ctx context.Context, id string, code string) ([]string, error) {
+	key, err := s.verify(ctx, id, code, false)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	key.Enabled = true
+	key.RecoveryCodes = hashes
+	if _, err = s.Repository.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a TOTP code of an enabled user, during sign in.
+func (s *MFAService) Verify(ctx context.Context, id string, code string) error {
+	_, err := s.verify(ctx, id, code, true)
+	return err
+}
+func (s *MFAService) verify(ctx context.Context, id string, code string, enabled bool) (*TOTPKey, error) {
+	if err := s.Lockout.Check(ctx, id); err != nil {
+		return nil, err
+	}
+	key, err := s.Repository.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.Enabled != enabled {
+		return nil, ErrNotEnrolled
+	}
+	secret := key.Secret
+	if s.Cipher != nil {
+		if secret, err = s.Cipher.Decrypt(secret); err != nil {
+			return nil, err
+		}
+	}
+	step, ok, err := s.TOTP.Validate(secret, code, s.Now(), key.LastStep)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.Lockout.Fail(ctx, id)
+	}
+	key.LastStep = step
+	if _, err = s.Repository.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, s.Lockout.Reset(ctx, id)
+}
+
+// Recover signs in with a recovery code, which is removed so that it can be used once.
+func (s *MFAService) Recover(ctx context.Context, id string, code string) (int, error) {
+	if err := s.Lockout.Check(ctx, id); err != nil {
+		return 0, err
+	}
+	key, err := s.Repository.Load(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if key == nil || !key.Enabled {
+		return 0, ErrNotEnrolled
+	}
+	digest := []byte(s.digest(NormalizeRecoveryCode(code)))
+	for i, hashed := range key.RecoveryCodes {
+		if subtle.ConstantTimeCompare(digest, []byte(hashed)) == 1 {
+			codes := make([]string, 0, len(key.RecoveryCodes)-1)
+			codes = append(codes, key.RecoveryCodes[:i]...)
+			key.RecoveryCodes = append(codes, key.RecoveryCodes[i+1:]...)
+			if _, err = s.Repository.Save(ctx, key); err != nil {
+				return 0, err
+			}
+			return len(key.RecoveryCodes), s.Lockout.Reset(ctx, id)
+		}
+	}
+	return 0, s.Lockout.Fail(ctx, id)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after the user has verified a TOTP code.
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, id string, code string) ([]string, error) {
+	key, err := s.verify(ctx, id, code, true)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	key.RecoveryCodes = hashes
+	if _, err = s.Repository.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable removes the TOTP of the user after the user has verified a TOTP code.
+func (s *MFAService) Disable(ctx context.Context, id string, code string) error {
+	if _, err := s.verify(ctx, id, code, true); err != nil {
+		return err
+	}
+	_, err := s.Repository.Delete(ctx, id)
+	return err
+}
+func (s *MFAService) IsEnabled(ctx context.Context, id string) (bool, error) {
+	key, err := s.Repository.Load(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return key != nil && key.Enabled, nil
+}
+
+func (s *MFAService) generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := GenerateRecoveryCodes(s.RecoveryCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = s.digest(NormalizeRecoveryCode(c))
+	}
+	return codes, hashes, nil
+}
+func (s *MFAService) digest(code string) string {
+	if len(s.RecoveryKey) == 0 {
+		sum := sha256.Sum256([]byte(code))
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, s.RecoveryKey)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCodes generates n codes formatted as xxxxx-xxxxx, without the characters which are confused, such as 0, o, 1, l and i.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	max := big.NewInt(int64(len(recoveryAlphabet)))
+	for i := 0; i < n; i++ {
+		b := make([]byte, 10)
+		for j := range b {
+			k, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, err
+			}
+			b[j] = recoveryAlphabet[k.Int64()]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+	}
+	return codes, nil
+}
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -1,7 +1,9 @@
 package passcode
 
 import (
+	crand "crypto/rand"
 	"math"
+	"math/big"
 	"math/rand"
 	"strconv"
 )
@@ -23,3 +25,17 @@ func Generate(length int) string {
 	max := int(math.Pow(float64(10), float64(length))) - 1
 	return padLeft(strconv.Itoa(rand.Intn(max)), length, "0")
 }
+
+// GenerateSecure generates a numeric code with crypto/rand, for the codes which are used to sign in.
+func GenerateSecure(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(10)
+	for i := 0; i < length; i++ {
+		n, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + n.Int64())
+	}
+	return string(b), nil
+}
@@ -0,0 +1,152 @@
+package passcode
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SHA1   = "SHA1"
+	SHA256 = "SHA256"
+	SHA512 = "SHA512"
+)
+
+// TOTPConfig is the configuration of RFC 6238 TOTP. The default is 6 digits, a period of 30 seconds, SHA1 and a skew of 1 period,
+// which are supported by all authenticator apps.
+type TOTPConfig struct {
+	Issuer     string `yaml:"issuer" mapstructure:"issuer" json:"issuer,omitempty" gorm:"column:issuer" bson:"issuer,omitempty" dynamodbav:"issuer,omitempty" firestore:"issuer,omitempty"`
+	Digits     int    `yaml:"digits" mapstructure:"digits" json:"digits,omitempty" gorm:"column:digits" bson:"digits,omitempty" dynamodbav:"digits,omitempty" firestore:"digits,omitempty"`
+	Period     int    `yaml:"period" mapstructure:"period" json:"period,omitempty" gorm:"column:period" bson:"period,omitempty" dynamodbav:"period,omitempty" firestore:"period,omitempty"`
+	Algorithm  string `yaml:"algorithm" mapstructure:"algorithm" json:"algorithm,omitempty" gorm:"column:algorithm" bson:"algorithm,omitempty" dynamodbav:"algorithm,omitempty" firestore:"algorithm,omitempty"`
+	Skew       int    `yaml:"skew" mapstructure:"skew" json:"skew,omitempty" gorm:"column:skew" bson:"skew,omitempty" dynamodbav:"skew,omitempty" firestore:"skew,omitempty"`
+	SecretSize int    `yaml:"secret_size" mapstructure:"secret_size" json:"secretSize,omitempty" gorm:"column:secretsize" bson:"secretSize,omitempty" dynamodbav:"secretSize,omitempty" firestore:"secretSize,omitempty"`
+}
+
+type TOTP struct {
+	Issuer     string
+	Digits     int
+	Period     int
+	Algorithm  string
+	Skew       int
+	SecretSize int
+}
+
+func NewTOTP(c TOTPConfig) *TOTP {
+	t := &TOTP{Issuer: c.Issuer, Digits: c.Digits, Period: c.Period, Algorithm: strings.ToUpper(c.Algorithm), Skew: c.Skew, SecretSize: c.SecretSize}
+	if t.Digits <= 0 {
+		t.Digits = 6
+	}
+	if t.Period <= 0 {
+		t.Period = 30
+	}
+	if len(t.Algorithm) == 0 {
+		t.Algorithm = SHA1
+	}
+	if t.Skew < 0 {
+		t.Skew = 0
+	} else if t.Skew == 0 {
+		t.Skew = 1
+	}
+	if t.SecretSize <= 0 {
+		t.SecretSize = 20
+	}
+	return t
+}
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a random secret, encoded in base32 without padding.
+func (t *TOTP) GenerateSecret() (string, error) {
+	b := make([]byte, t.SecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// ProvisioningURI returns the otpauth:// URI, which is shown as a QR code to be scanned by an authenticator app.
+func (t *TOTP) ProvisioningURI(account string, secret string) string {
+	label := url.PathEscape(account)
+	if len(t.Issuer) > 0 {
+		label = url.PathEscape(t.Issuer) + ":" + label
+	}
+	v := url.Values{}
+	v.Set("secret", secret)
+	if len(t.Issuer) > 0 {
+		v.Set("issuer", t.Issuer)
+	}
+	v.Set("algorithm", t.Algorithm)
+	v.Set("digits", strconv.Itoa(t.Digits))
+	v.Set("period", strconv.Itoa(t.Period))
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// Step returns the time step of the time.
+func (t *TOTP) Step(at time.Time) int64 {
+	return at.Unix() / int64(t.Period)
+}
+
+// Code returns the code of the time step, as defined by RFC 4226 and RFC 6238.
+func (t *TOTP) Code(secret string, step int64) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(strings.TrimRight(strings.Replace(secret, " ", "", -1), "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+	var h func() hash.Hash
+	switch t.Algorithm {
+	case SHA256:
+		h = sha256.New
+	case SHA512:
+		h = sha512.New
+	default:
+		h = sha1.New
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(h, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := int64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	mod := int64(1)
+	for i := 0; i < t.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", t.Digits, value%mod), nil
+}
+
+// Validate checks the code against the time steps within the skew, in constant time. It returns the matched step, which must be greater than
+// the last used step, so that a code cannot be used twice.
+func (t *TOTP) Validate(secret string, code string, at time.Time, lastStep int64) (int64, bool, error) {
+	code = strings.Replace(code, " ", "", -1)
+	if len(code) != t.Digits {
+		return 0, false, nil
+	}
+	current := t.Step(at)
+	var matched int64
+	found := false
+	for i := -t.Skew; i <= t.Skew; i++ {
+		step := current + int64(i)
+		expected, err := t.Code(secret, step)
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 && step > lastStep && !found {
+			matched = step
+			found = true
+		}
+	}
+	return matched, found, nil
+}
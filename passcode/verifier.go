@@ -0,0 +1,133 @@
+package passcode
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/core-go/core/crypto"
+)
+
+type VerifierConfig struct {
+	Length         int            `yaml:"length" mapstructure:"length" json:"length,omitempty" gorm:"column:length" bson:"length,omitempty" dynamodbav:"length,omitempty" firestore:"length,omitempty"`
+	Expires        *time.Duration `yaml:"expires" mapstructure:"expires" json:"expires,omitempty" gorm:"column:expires" bson:"expires,omitempty" dynamodbav:"expires,omitempty" firestore:"expires,omitempty"`
+	ResendInterval *time.Duration `yaml:"resend_interval" mapstructure:"resend_interval" json:"resendInterval,omitempty" gorm:"column:resendinterval" bson:"resendInterval,omitempty" dynamodbav:"resendInterval,omitempty" firestore:"resendInterval,omitempty"`
+	MaxAttempts    int            `yaml:"max_attempts" mapstructure:"max_attempts" json:"maxAttempts,omitempty" gorm:"column:maxattempts" bson:"maxAttempts,omitempty" dynamodbav:"maxAttempts,omitempty" firestore:"maxAttempts,omitempty"`
+	Window         *time.Duration `yaml:"window" mapstructure:"window" json:"window,omitempty" gorm:"column:window" bson:"window,omitempty" dynamodbav:"window,omitempty" firestore:"window,omitempty"`
+	Lockout        *time.Duration `yaml:"lockout" mapstructure:"lockout" json:"lockout,omitempty" gorm:"column:lockout" bson:"lockout,omitempty" dynamodbav:"lockout,omitempty" firestore:"lockout,omitempty"`
+}
+
+// Verifier sends and verifies the passcodes. The passcode is saved in the PasscodeService as the time it was sent and the hash of the code,
+// so that the code is never stored in plain text. Failed attempts are counted by the Lockout.
+type Verifier struct {
+	Service        PasscodeService
+	Sender         Sender
+	Comparator     crypto.StringComparator
+	Lockout        *Lockout
+	Length         int
+	Expires        time.Duration
+	ResendInterval time.Duration
+	Generate       func(length int) (string, error)
+	Now            func() time.Time
+}
+
+func NewVerifier(service PasscodeService, sender Sender, comparator crypto.StringComparator, c VerifierConfig) *Verifier {
+	v := &Verifier{Service: service, Sender: sender, Comparator: comparator, Length: c.Length, Generate: GenerateSecure, Now: time.Now}
+	if v.Length <= 0 {
+		v.Length = 6
+	}
+	v.Expires = toDuration(c.Expires, 5*time.Minute)
+	v.ResendInterval = toDuration(c.ResendInterval, time.Minute)
+	v.Lockout = NewLockout(service, c.MaxAttempts, toDuration(c.Window, 0), toDuration(c.Lockout, 0))
+	return v
+}
+func toDuration(d *time.Duration, v time.Duration) time.Duration {
+	if d != nil && *d > 0 {
+		return *d
+	}
+	return v
+}
+
+func encode(sentAt time.Time, hashed string) string {
+	return strconv.FormatInt(sentAt.Unix(), 10) + "$" + hashed
+}
+func decode(s string) (time.Time, string) {
+	i := strings.Index(s, "$")
+	if i < 0 {
+		return time.Time{}, s
+	}
+	sec, err := strconv.ParseInt(s[:i], 10, 64)
+	if err != nil {
+		return time.Time{}, s
+	}
+	return time.Unix(sec, 0), s[i+1:]
+}
+
+// Send generates a new passcode for the id, replaces the previous one, and sends it to the address.
+// It returns an error wrapping ErrLocked if the id is locked, or ErrResendTooSoon if the previous passcode was sent within the resend interval.
+func (v *Verifier) Send(ctx context.Context, id string, to string, params interface{}) (time.Time, error) {
+	if err := v.Lockout.Check(ctx, id); err != nil {
+		return time.Time{}, err
+	}
+	now := v.Now()
+	s, expireAt, err := v.Service.Load(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(s) > 0 && expireAt.After(now) {
+		sentAt, _ := decode(s)
+		if wait := sentAt.Add(v.ResendInterval).Sub(now); wait > 0 {
+			return time.Time{}, &VerifyError{Err: ErrResendTooSoon, RetryAfter: wait}
+		}
+	}
+	code, err := v.Generate(v.Length)
+	if err != nil {
+		return time.Time{}, err
+	}
+	hashed, err := v.Comparator.Hash(code)
+	if err != nil {
+		return time.Time{}, err
+	}
+	expireAt = now.Add(v.Expires)
+	if _, err = v.Service.Save(ctx, id, encode(now, hashed), expireAt); err != nil {
+		return time.Time{}, err
+	}
+	if err = v.Sender.Send(ctx, to, code, expireAt, params); err != nil {
+		return time.Time{}, err
+	}
+	return expireAt, nil
+}
+
+// Verify checks the code. If it is valid, the passcode is deleted so that it cannot be used again, and the failed attempts are reset.
+// If it is not, the failed attempt is counted, and the passcode is deleted when the id becomes locked.
+func (v *Verifier) Verify(ctx context.Context, id string, code string) error {
+	if err := v.Lockout.Check(ctx, id); err != nil {
+		return err
+	}
+	s, expireAt, err := v.Service.Load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(s) == 0 || !expireAt.After(v.Now()) {
+		return ErrExpiredCode
+	}
+	_, hashed := decode(s)
+	valid, err := v.Comparator.Compare(code, hashed)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		er1 := v.Lockout.Fail(ctx, id)
+		if IsLocked(er1) {
+			if _, er2 := v.Service.Delete(ctx, id); er2 != nil {
+				return er2
+			}
+		}
+		return er1
+	}
+	if _, err = v.Service.Delete(ctx, id); err != nil {
+		return err
+	}
+	return v.Lockout.Reset(ctx, id)
+}
@@ -0,0 +1,20 @@
+package query
+
+import "fmt"
+
+// BuildPagingQuery appends the paging clause of the driver to a query, which must have an order by: "offset ... rows fetch next ... rows only" for oracle and mssql, "limit ... offset ..." for the others.
+func BuildPagingQuery(sql string, limit int64, offset int64, opts ...string) string {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		return sql
+	}
+	if len(opts) > 0 && (opts[0] == DriverOracle || opts[0] == DriverMssql) {
+		return sql + fmt.Sprintf(" offset %d rows fetch next %d rows only", offset, limit)
+	}
+	if offset == 0 {
+		return sql + fmt.Sprintf(" limit %d", limit)
+	}
+	return sql + fmt.Sprintf(" limit %d offset %d", limit, offset)
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+func capacity(l Limit) float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Limit)
+}
+
+// takeToken refills the bucket from the elapsed time, then takes one token if there is any.
+func takeToken(l Limit, tokens float64, last time.Time, now time.Time) (float64, Result) {
+	c := capacity(l)
+	rate := float64(l.Limit) / float64(l.Period)
+	if !last.IsZero() {
+		elapsed := now.Sub(last)
+		if elapsed > 0 {
+			tokens = math.Min(c, tokens+float64(elapsed)*rate)
+		}
+	}
+	res := Result{Limit: int64(c)}
+	if tokens >= 1 {
+		tokens = tokens - 1
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((1 - tokens) / rate))
+	}
+	res.Remaining = int64(math.Floor(tokens))
+	res.Reset = time.Duration(math.Ceil((c - tokens) / rate))
+	return tokens, res
+}
+
+// countWindow weights the count of the previous window by the part of it still covered by the sliding window.
+func countWindow(l Limit, previous int64, current int64, elapsed time.Duration) Result {
+	res := Result{Limit: l.Limit, Reset: l.Period - elapsed}
+	weight := float64(l.Period-elapsed) / float64(l.Period)
+	count := float64(previous)*weight + float64(current)
+	if count+1 > float64(l.Limit) {
+		if current+1 > l.Limit || previous == 0 {
+			res.RetryAfter = l.Period - elapsed
+		} else {
+			free := float64(l.Limit-current-1) / float64(previous)
+			res.RetryAfter = time.Duration(float64(l.Period)*(1-free)) - elapsed
+			if res.RetryAfter <= 0 {
+				res.RetryAfter = time.Millisecond
+			}
+		}
+		res.Remaining = 0
+		return res
+	}
+	res.Allowed = true
+	res.Remaining = l.Limit - int64(math.Ceil(count+1))
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import "time"
+
+const (
+	TokenBucket   = "token_bucket"
+	SlidingWindow = "sliding_window"
+
+	KeyIp     = "ip"
+	KeyUser   = "user"
+	KeyApiKey = "api_key"
+	KeyRoute  = "route"
+)
+
+type LimitConfig struct {
+	Algorithm string         `yaml:"algorithm" mapstructure:"algorithm" json:"algorithm,omitempty" gorm:"column:algorithm" bson:"algorithm,omitempty" dynamodbav:"algorithm,omitempty" firestore:"algorithm,omitempty"`
+	Limit     int64          `yaml:"limit" mapstructure:"limit" json:"limit,omitempty" gorm:"column:limit" bson:"limit,omitempty" dynamodbav:"limit,omitempty" firestore:"limit,omitempty"`
+	Period    *time.Duration `yaml:"period" mapstructure:"period" json:"period,omitempty" gorm:"column:period" bson:"period,omitempty" dynamodbav:"period,omitempty" firestore:"period,omitempty"`
+	Burst     int64          `yaml:"burst" mapstructure:"burst" json:"burst,omitempty" gorm:"column:burst" bson:"burst,omitempty" dynamodbav:"burst,omitempty" firestore:"burst,omitempty"`
+	Key       string         `yaml:"key" mapstructure:"key" json:"key,omitempty" gorm:"column:key" bson:"key,omitempty" dynamodbav:"key,omitempty" firestore:"key,omitempty"`
+	Skip      bool           `yaml:"skip" mapstructure:"skip" json:"skip,omitempty" gorm:"column:skip" bson:"skip,omitempty" dynamodbav:"skip,omitempty" firestore:"skip,omitempty"`
+}
+
+type Config struct {
+	Default LimitConfig            `yaml:"default" mapstructure:"default" json:"default,omitempty" gorm:"column:default" bson:"default,omitempty" dynamodbav:"default,omitempty" firestore:"default,omitempty"`
+	Prefix  string                 `yaml:"prefix" mapstructure:"prefix" json:"prefix,omitempty" gorm:"column:prefix" bson:"prefix,omitempty" dynamodbav:"prefix,omitempty" firestore:"prefix,omitempty"`
+	ApiKey  string                 `yaml:"api_key" mapstructure:"api_key" json:"apiKey,omitempty" gorm:"column:apikey" bson:"apiKey,omitempty" dynamodbav:"apiKey,omitempty" firestore:"apiKey,omitempty"`
+	User    string                 `yaml:"user" mapstructure:"user" json:"user,omitempty" gorm:"column:user" bson:"user,omitempty" dynamodbav:"user,omitempty" firestore:"user,omitempty"`
+	Routes  map[string]LimitConfig `yaml:"routes" mapstructure:"routes" json:"routes,omitempty" gorm:"column:routes" bson:"routes,omitempty" dynamodbav:"routes,omitempty" firestore:"routes,omitempty"`
+	Actions map[string]LimitConfig `yaml:"actions" mapstructure:"actions" json:"actions,omitempty" gorm:"column:actions" bson:"actions,omitempty" dynamodbav:"actions,omitempty" firestore:"actions,omitempty"`
+}
+
+type Limit struct {
+	Algorithm string
+	Limit     int64
+	Period    time.Duration
+	Burst     int64
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
@@ -0,0 +1,44 @@
+package echo
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/core-go/core/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	Limiter  *ratelimit.Limiter
+	LogError func(context.Context, string, ...map[string]interface{})
+	FailOpen bool
+}
+
+func NewHandler(limiter *ratelimit.Limiter, logError func(context.Context, string, ...map[string]interface{}), failOpen bool) *Handler {
+	return &Handler{Limiter: limiter, LogError: logError, FailOpen: failOpen}
+}
+
+func (h *Handler) Handle() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			r := ctx.Request()
+			res, applied, err := h.Limiter.Allow(r)
+			if err != nil {
+				if h.LogError != nil {
+					h.LogError(r.Context(), err.Error())
+				}
+				if h.FailOpen {
+					return next(ctx)
+				}
+				return ctx.JSON(http.StatusInternalServerError, "Internal Server Error")
+			}
+			if applied {
+				ratelimit.SetHeaders(ctx.Response().Header(), res)
+				if !res.Allowed {
+					return ctx.JSON(http.StatusTooManyRequests, "Too Many Requests")
+				}
+			}
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package gin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/core-go/core/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	Limiter  *ratelimit.Limiter
+	LogError func(context.Context, string, ...map[string]interface{})
+	FailOpen bool
+}
+
+func NewHandler(limiter *ratelimit.Limiter, logError func(context.Context, string, ...map[string]interface{}), failOpen bool) *Handler {
+	return &Handler{Limiter: limiter, LogError: logError, FailOpen: failOpen}
+}
+
+func (h *Handler) Handle() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		r := ctx.Request
+		res, applied, err := h.Limiter.Allow(r)
+		if err != nil {
+			if h.LogError != nil {
+				h.LogError(r.Context(), err.Error())
+			}
+			if h.FailOpen {
+				ctx.Next()
+			} else {
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, "Internal Server Error")
+			}
+			return
+		}
+		if applied {
+			ratelimit.SetHeaders(ctx.Writer.Header(), res)
+			if !res.Allowed {
+				ctx.AbortWithStatusJSON(http.StatusTooManyRequests, "Too Many Requests")
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
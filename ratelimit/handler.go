@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+)
+
+type Handler struct {
+	Limiter  *Limiter
+	LogError func(context.Context, string, ...map[string]interface{})
+	FailOpen bool
+}
+
+func NewHandler(limiter *Limiter, logError func(context.Context, string, ...map[string]interface{}), failOpen bool) *Handler {
+	return &Handler{Limiter: limiter, LogError: logError, FailOpen: failOpen}
+}
+
+func (h *Handler) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, applied, err := h.Limiter.Allow(r)
+		if err != nil {
+			if h.LogError != nil {
+				h.LogError(r.Context(), err.Error())
+			}
+			if h.FailOpen {
+				next.ServeHTTP(w, r)
+			} else {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			return
+		}
+		if applied {
+			SetHeaders(w.Header(), res)
+			if !res.Allowed {
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
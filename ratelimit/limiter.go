@@ -0,0 +1,222 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/core-go/core"
+	"github.com/core-go/core/authorization"
+)
+
+type Store interface {
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+type route struct {
+	Method string
+	Path   string
+	Prefix bool
+	Name   string
+	Config LimitConfig
+}
+
+type Limiter struct {
+	Config
+	Store         Store
+	Authorization string
+	GetAction     func(r *http.Request) string
+	GetKey        func(r *http.Request, key string) string
+	routes        []route
+}
+
+func NewLimiter(c Config, store Store, opts ...string) *Limiter {
+	var authorization string
+	if len(opts) > 0 {
+		authorization = opts[0]
+	}
+	if len(c.ApiKey) == 0 {
+		c.ApiKey = "X-API-Key"
+	}
+	if len(c.User) == 0 {
+		c.User = "userId"
+	}
+	l := &Limiter{Config: c, Store: store, Authorization: authorization}
+	l.GetKey = l.getKey
+	for pattern, rc := range c.Routes {
+		l.Route(pattern, rc)
+	}
+	return l
+}
+
+// Route sets the limit of a route, in the form "/path", "/prefix/*" or "POST /path".
+func (l *Limiter) Route(pattern string, c LimitConfig) *Limiter {
+	pattern = strings.TrimSpace(pattern)
+	rt := route{Name: pattern, Config: c}
+	if i := strings.Index(pattern, " "); i > 0 {
+		rt.Method = strings.ToUpper(pattern[:i])
+		pattern = strings.TrimSpace(pattern[i+1:])
+	}
+	if strings.HasSuffix(pattern, "*") {
+		rt.Prefix = true
+		pattern = pattern[:len(pattern)-1]
+	}
+	rt.Path = strings.ToLower(pattern)
+	l.routes = append(l.routes, rt)
+	return l
+}
+
+// UseAction resolves the action of the request from the action config, so that the limits can be set per action.
+func (l *Limiter) UseAction(action *core.ActionConfig) *Limiter {
+	a := core.InitAction(action)
+	l.GetAction = func(r *http.Request) string {
+		return ToAction(r, a)
+	}
+	return l
+}
+
+// Resolve returns the name and the limit config of the request. Exact routes come first, then the longest prefix route, then the action, then the default.
+func (l *Limiter) Resolve(r *http.Request) (string, LimitConfig, bool) {
+	path := strings.ToLower(r.URL.Path)
+	var matched *route
+	for i := range l.routes {
+		rt := &l.routes[i]
+		if len(rt.Method) > 0 && rt.Method != r.Method {
+			continue
+		}
+		if !rt.Prefix {
+			if rt.Path == path {
+				return rt.Name, rt.Config, true
+			}
+		} else if strings.HasPrefix(path, rt.Path) && (matched == nil || len(rt.Path) > len(matched.Path)) {
+			matched = rt
+		}
+	}
+	if matched != nil {
+		return matched.Name, matched.Config, true
+	}
+	if l.GetAction != nil && len(l.Actions) > 0 {
+		action := l.GetAction(r)
+		if c, ok := l.Actions[action]; ok {
+			return action, c, true
+		}
+	}
+	if l.Default.Limit > 0 {
+		return "", l.Default, true
+	}
+	return "", LimitConfig{}, false
+}
+
+func (l *Limiter) Allow(r *http.Request) (Result, bool, error) {
+	name, c, ok := l.Resolve(r)
+	if !ok || c.Skip || c.Limit <= 0 {
+		return Result{Allowed: true}, false, nil
+	}
+	lm := ToLimit(c, l.Default)
+	keyType := c.Key
+	if len(keyType) == 0 {
+		keyType = l.Default.Key
+	}
+	if len(keyType) == 0 {
+		keyType = KeyIp
+	}
+	keys := strings.Split(keyType, ",")
+	parts := make([]string, 0, len(keys)+1)
+	parts = append(parts, name)
+	for _, k := range keys {
+		parts = append(parts, l.GetKey(r, strings.TrimSpace(k)))
+	}
+	res, err := l.Store.Take(r.Context(), l.Prefix+strings.Join(parts, ":"), lm)
+	return res, true, err
+}
+
+func (l *Limiter) getKey(r *http.Request, key string) string {
+	switch key {
+	case KeyUser:
+		user := FromContext(r, l.Authorization, l.User)
+		if len(user) > 0 {
+			return user
+		}
+		return authorization.GetRemoteIp(r)
+	case KeyApiKey:
+		apiKey := r.Header.Get(l.ApiKey)
+		if len(apiKey) > 0 {
+			return apiKey
+		}
+		return authorization.GetRemoteIp(r)
+	case KeyRoute:
+		return r.Method + " " + r.URL.Path
+	default:
+		return authorization.GetRemoteIp(r)
+	}
+}
+
+func ToLimit(c LimitConfig, d LimitConfig) Limit {
+	l := Limit{Algorithm: c.Algorithm, Limit: c.Limit, Burst: c.Burst}
+	if len(l.Algorithm) == 0 {
+		l.Algorithm = d.Algorithm
+	}
+	if len(l.Algorithm) == 0 {
+		l.Algorithm = TokenBucket
+	}
+	if c.Period != nil && *c.Period > 0 {
+		l.Period = *c.Period
+	} else if d.Period != nil && *d.Period > 0 {
+		l.Period = *d.Period
+	} else {
+		l.Period = time.Minute
+	}
+	return l
+}
+func ToAction(r *http.Request, action core.ActionConfig) string {
+	switch r.Method {
+	case http.MethodGet:
+		if strings.HasSuffix(r.URL.Path, "/search") || len(r.URL.RawQuery) > 0 {
+			return *action.Search
+		}
+		return *action.Load
+	case http.MethodPost:
+		if strings.HasSuffix(r.URL.Path, "/search") {
+			return *action.Search
+		}
+		return action.Create
+	case http.MethodPut:
+		return action.Update
+	case http.MethodPatch:
+		return action.Patch
+	case http.MethodDelete:
+		return action.Delete
+	default:
+		return strings.ToLower(r.Method)
+	}
+}
+func FromContext(r *http.Request, authorization string, key string) string {
+	var u interface{}
+	if len(authorization) > 0 {
+		if data, ok := r.Context().Value(authorization).(map[string]interface{}); ok {
+			u = data[key]
+		}
+	} else {
+		u = r.Context().Value(key)
+	}
+	if v, ok := u.(string); ok {
+		return v
+	}
+	return ""
+}
+
+// SetHeaders writes the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset headers, and Retry-After if the request is rejected.
+func SetHeaders(h http.Header, res Result) {
+	h.Set("RateLimit-Limit", strconv.FormatInt(res.Limit, 10))
+	h.Set("RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+	h.Set("RateLimit-Reset", strconv.FormatInt(Seconds(res.Reset), 10))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.FormatInt(Seconds(res.RetryAfter), 10))
+	}
+}
+func Seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
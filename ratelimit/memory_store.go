@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	Tokens   float64
+	Last     time.Time
+	Window   int64
+	Current  int64
+	Previous int64
+	Expire   time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	sweep   time.Time
+}
+
+func NewMemoryStore(opts ...func() time.Time) *MemoryStore {
+	now := time.Now
+	if len(opts) > 0 && opts[0] != nil {
+		now = opts[0]
+	}
+	return &MemoryStore{buckets: make(map[string]*bucket), now: now}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, l Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.removeExpired(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{Tokens: capacity(l)}
+		s.buckets[key] = b
+	}
+	var res Result
+	if l.Algorithm == SlidingWindow {
+		window := now.UnixNano() / int64(l.Period)
+		if b.Window != window {
+			if b.Window == window-1 {
+				b.Previous = b.Current
+			} else {
+				b.Previous = 0
+			}
+			b.Current = 0
+			b.Window = window
+		}
+		elapsed := time.Duration(now.UnixNano() - window*int64(l.Period))
+		res = countWindow(l, b.Previous, b.Current, elapsed)
+		if res.Allowed {
+			b.Current++
+		}
+		b.Expire = now.Add(2*l.Period - elapsed)
+	} else {
+		b.Tokens, res = takeToken(l, b.Tokens, b.Last, now)
+		b.Last = now
+		b.Expire = now.Add(res.Reset)
+	}
+	return res, nil
+}
+
+func (s *MemoryStore) removeExpired(now time.Time) {
+	if now.Before(s.sweep) {
+		return
+	}
+	for k, b := range s.buckets {
+		if now.After(b.Expire) {
+			delete(s.buckets, k)
+		}
+	}
+	s.sweep = now.Add(time.Minute)
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type Evaluator interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// The scripts use the redis clock, so that all pods share the same time. The keys of one limit share the same hash tag for redis cluster.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+else
+  local elapsed = now - ts
+  if elapsed > 0 then
+    tokens = math.min(capacity, tokens + elapsed * rate)
+  end
+end
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+local reset = math.ceil((capacity - tokens) / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(reset / 1000) + 1000)
+return {allowed, math.floor(tokens), reset, retry}
+`
+
+const slidingWindowScript = `
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local window = math.floor(now / period)
+local elapsed = now - window * period
+local currentKey = KEYS[1] .. ':' .. window
+local previousKey = KEYS[1] .. ':' .. (window - 1)
+local current = tonumber(redis.call('GET', currentKey) or '0')
+local previous = tonumber(redis.call('GET', previousKey) or '0')
+local count = previous * (period - elapsed) / period + current
+if count + 1 > limit then
+  local retry = period - elapsed
+  if current + 1 <= limit and previous > 0 then
+    retry = math.max(1000, math.floor(period * (1 - (limit - current - 1) / previous)) - elapsed)
+  end
+  return {0, 0, period - elapsed, retry}
+end
+redis.call('INCR', currentKey)
+redis.call('PEXPIRE', currentKey, math.ceil(period * 2 / 1000))
+local remaining = limit - math.ceil(count + 1)
+if remaining < 0 then
+  remaining = 0
+end
+return {1, remaining, period - elapsed, 0}
+`
+
+type RedisStore struct {
+	Evaluator Evaluator
+	Prefix    string
+}
+
+func NewRedisStore(evaluator Evaluator, opts ...string) *RedisStore {
+	prefix := "ratelimit:"
+	if len(opts) > 0 {
+		prefix = opts[0]
+	}
+	return &RedisStore{Evaluator: evaluator, Prefix: prefix}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, l Limit) (Result, error) {
+	k := "{" + s.Prefix + key + "}"
+	micro := l.Period.Microseconds()
+	var v interface{}
+	var err error
+	if l.Algorithm == SlidingWindow {
+		v, err = s.Evaluator.Eval(ctx, slidingWindowScript, []string{k}, l.Limit, micro)
+	} else {
+		rate := strconv.FormatFloat(float64(l.Limit)/float64(micro), 'g', -1, 64)
+		v, err = s.Evaluator.Eval(ctx, tokenBucketScript, []string{k}, rate, int64(capacity(l)))
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	values, ok := v.([]interface{})
+	if !ok || len(values) < 4 {
+		return Result{}, errors.New("invalid result of rate limit script")
+	}
+	nums := make([]int64, 4)
+	for i := 0; i < 4; i++ {
+		n, ok := values[i].(int64)
+		if !ok {
+			return Result{}, fmt.Errorf("invalid value at %d of rate limit script", i)
+		}
+		nums[i] = n
+	}
+	res := Result{Allowed: nums[0] == 1, Remaining: nums[1], Reset: time.Duration(nums[2]) * time.Microsecond, RetryAfter: time.Duration(nums[3]) * time.Microsecond}
+	if l.Algorithm == SlidingWindow {
+		res.Limit = l.Limit
+	} else {
+		res.Limit = int64(capacity(l))
+	}
+	return res, nil
+}
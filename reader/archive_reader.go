@@ -0,0 +1,96 @@
+package reader
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/core-go/core/io/zip"
+)
+
+// ArchiveReader reads the files of an archive, such as a zip, a tar, a tar.gz or a gz file, as one import: the records of the files are passed to next one by one, and io.EOF is passed once, after the last file.
+//   - Open creates the reader of a file of the archive, such as func(name string, r io.Reader) func(func(string, error, int) error) error { return NewFileReaderFrom(r, name).Read }.
+//   - Filter selects the files by the name; if it is nil, all the files are read.
+//   - ReadFile passes the name of the file of each record, for the file name of the error handlers of Importer; the line numbers are the line numbers in the file.
+type ArchiveReader[S string | []string] struct {
+	FileName string
+	Reader   io.Reader
+	Open     func(fileName string, r io.Reader) func(next func(record S, err error, numLine int) error) error
+	Filter   func(fileName string) bool
+}
+
+func NewArchiveReader[S string | []string](buildFileName func() string, open func(string, io.Reader) func(func(S, error, int) error) error, opts ...func(string) bool) (*ArchiveReader[S], error) {
+	fileName := buildFileName()
+	if len(strings.TrimSpace(fileName)) == 0 {
+		return nil, errors.New("file name cannot be empty")
+	}
+	var filter func(string) bool
+	if len(opts) > 0 {
+		filter = opts[0]
+	}
+	return &ArchiveReader[S]{FileName: fileName, Open: open, Filter: filter}, nil
+}
+
+// NewArchiveReaderFrom creates a reader of r, such as an uploaded file; fileName is the name of the archive.
+func NewArchiveReaderFrom[S string | []string](r io.Reader, fileName string, open func(string, io.Reader) func(func(S, error, int) error) error, opts ...func(string) bool) *ArchiveReader[S] {
+	var filter func(string) bool
+	if len(opts) > 0 {
+		filter = opts[0]
+	}
+	return &ArchiveReader[S]{FileName: fileName, Reader: r, Open: open, Filter: filter}
+}
+
+// Extensions returns a filter of the files by the extensions, such as Extensions(".csv", ".txt"); the hidden files, such as the files of __MACOSX, are skipped.
+func Extensions(extensions ...string) func(string) bool {
+	return func(fileName string) bool {
+		base := path.Base(fileName)
+		if strings.HasPrefix(base, ".") || strings.HasPrefix(fileName, "__MACOSX/") {
+			return false
+		}
+		ext := strings.ToLower(path.Ext(fileName))
+		for _, e := range extensions {
+			if strings.ToLower(e) == ext {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (a *ArchiveReader[S]) Read(next func(record S, err error, numLine int) error) error {
+	return a.ReadFile(func(record S, err error, numLine int, fileName string) error {
+		return next(record, err, numLine)
+	})
+}
+func (a *ArchiveReader[S]) ReadFile(next func(record S, err error, numLine int, fileName string) error) error {
+	var empty S
+	r := a.Reader
+	if r == nil {
+		file, err := os.Open(a.FileName)
+		if err != nil {
+			err = errors.New("cannot open file")
+			next(empty, err, 0, a.FileName)
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	err := zip.Walk(a.FileName, r, func(fileName string, fr io.Reader) error {
+		if a.Filter != nil && !a.Filter(fileName) {
+			return nil
+		}
+		return a.Open(fileName, fr)(func(record S, err error, numLine int) error {
+			if err == io.EOF {
+				return nil
+			}
+			return next(record, err, numLine, fileName)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	next(empty, io.EOF, 0, a.FileName)
+	return nil
+}
@@ -4,13 +4,14 @@ import (
 	"encoding/csv"
 	"errors"
 	"io"
-	"os"
 	"strings"
 )
 
+// CSVReader reads the records of a CSV file, or of Reader if it is set, such as an uploaded file; a gzip content is decompressed.
 type CSVReader struct {
 	Comma    rune
 	FileName string
+	Reader   io.Reader
 }
 
 func NewCSVReader(comma rune, buildFileName func() string) (*CSVReader, error) {
@@ -21,15 +22,19 @@ func NewCSVReader(comma rune, buildFileName func() string) (*CSVReader, error) {
 	return &CSVReader{Comma: comma, FileName: fileName}, nil
 }
 
+// NewCSVReaderFrom creates a reader of r; fileName is the name of the content, such as the name of an uploaded file or of a member of an archive.
+func NewCSVReaderFrom(comma rune, r io.Reader, fileName string) *CSVReader {
+	return &CSVReader{Comma: comma, FileName: fileName, Reader: r}
+}
+
 func (fr *CSVReader) Read(next func(record []string, err error, numLine int) error) error {
-	file, err := os.Open(fr.FileName)
+	file, closeFile, err := open(fr.FileName, fr.Reader)
 	if err != nil {
-		err = errors.New("cannot open file")
 		next(nil, err, 0)
 		return err
 	}
 
-	defer file.Close()
+	defer closeFile()
 
 	reader := csv.NewReader(file)
 	reader.Comma = fr.Comma
@@ -0,0 +1,515 @@
+package reader
+
+import (
+	archive "archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/core-go/core/io/zip"
+)
+
+// ExcelReader reads the rows of a sheet of an XLSX workbook one by one, without loading the sheet into memory, and transforms the rows to T by the header row.
+//   - Sheet is the name of the sheet; if it is empty, the first sheet is read.
+//   - HeaderRow is the row number of the header; the rows before it are skipped. If it is 0, there is no header and the columns are in the order of the fields, like CSVTransformer.
+//   - The column of a field is the tag `excel`, or the json name, or the field name; the header is compared case-insensitively.
+//   - The cells of dates are formatted with DateFormat, which is RFC3339 by default; the numbers are formatted without exponent.
+//
+// Read has the signature of the Read of Importer, and Transform has the signature of the Transform of Importer.
+type ExcelReader[T any] struct {
+	FileName   string
+	ReaderAt   io.ReaderAt
+	Size       int64
+	Sheet      string
+	HeaderRow  int
+	DateFormat string
+	Header     []string
+	formatCols map[int]Delimiter
+	columns    []int
+}
+
+func NewExcelReader[T any](buildFileName func() string, opts ...string) (*ExcelReader[T], error) {
+	fileName := buildFileName()
+	if len(strings.TrimSpace(fileName)) == 0 {
+		return nil, errors.New("file name cannot be empty")
+	}
+	var sheet string
+	if len(opts) > 0 {
+		sheet = opts[0]
+	}
+	var t T
+	modelType := reflect.TypeOf(t)
+	formatCols, err := GetIndexesByTag(modelType, "format")
+	if err != nil {
+		return nil, err
+	}
+	return &ExcelReader[T]{FileName: fileName, Sheet: sheet, HeaderRow: 1, DateFormat: time.RFC3339, formatCols: formatCols}, nil
+}
+
+// NewExcelReaderFrom creates a reader of r, such as an uploaded file or a member of an archive; an XLSX workbook needs random access, so r is buffered in memory if it is not an io.ReaderAt and an io.Seeker.
+func NewExcelReaderFrom[T any](r io.Reader, fileName string, opts ...string) (*ExcelReader[T], error) {
+	reader, err := NewExcelReader[T](func() string { return fileName }, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if ra, size, ok := zip.ReaderAt(r); ok {
+		reader.ReaderAt, reader.Size = ra, size
+		return reader, nil
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader.ReaderAt, reader.Size = bytes.NewReader(data), int64(len(data))
+	return reader, nil
+}
+
+type xlsxSheet struct {
+	Name string `xml:"name,attr"`
+	Id   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+type xlsxWorkbook struct {
+	WorkbookPr struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []xlsxSheet `xml:"sheets>sheet"`
+}
+type xlsxRelationship struct {
+	Id     string `xml:"Id,attr"`
+	Target string `xml:"Target,attr"`
+}
+type xlsxCell struct {
+	R  string `xml:"r,attr"`
+	T  string `xml:"t,attr"`
+	S  int    `xml:"s,attr"`
+	V  string `xml:"v"`
+	Is struct {
+		T string `xml:"t"`
+		R []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+func (r *ExcelReader[T]) Read(next func(record []string, err error, numLine int) error) error {
+	var z *archive.Reader
+	if r.ReaderAt != nil {
+		zr, err := archive.NewReader(r.ReaderAt, r.Size)
+		if err != nil {
+			next(nil, err, 0)
+			return err
+		}
+		z = zr
+	} else {
+		zr, err := archive.OpenReader(r.FileName)
+		if err != nil {
+			err = errors.New("cannot open file")
+			next(nil, err, 0)
+			return err
+		}
+		defer zr.Close()
+		z = &zr.Reader
+	}
+	files := make(map[string]*archive.File)
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+	var workbook xlsxWorkbook
+	if err := decodeZipFile(files, "xl/workbook.xml", &workbook); err != nil {
+		return err
+	}
+	var rels struct {
+		Relationships []xlsxRelationship `xml:"Relationship"`
+	}
+	if err := decodeZipFile(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return err
+	}
+	sheetFile, err := findSheet(workbook, rels.Relationships, r.Sheet)
+	if err != nil {
+		return err
+	}
+	sharedStrings, err := readSharedStrings(files)
+	if err != nil {
+		return err
+	}
+	dateStyles, err := readDateStyles(files)
+	if err != nil {
+		return err
+	}
+	f, ok := files[sheetFile]
+	if !ok {
+		return fmt.Errorf("sheet file '%s' is not found", sheetFile)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	layout := r.DateFormat
+	if len(layout) == 0 {
+		layout = time.RFC3339
+	}
+	decoder := xml.NewDecoder(rc)
+	var row []string
+	rowNumber := 0
+	inRow := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch e := token.(type) {
+		case xml.StartElement:
+			switch e.Name.Local {
+			case "row":
+				inRow = true
+				row = row[:0]
+				rowNumber++
+				for _, a := range e.Attr {
+					if a.Name.Local == "r" {
+						if n, err := strconv.Atoi(a.Value); err == nil {
+							rowNumber = n
+						}
+					}
+				}
+			case "c":
+				if !inRow {
+					continue
+				}
+				var c xlsxCell
+				if err := decoder.DecodeElement(&c, &e); err != nil {
+					return err
+				}
+				index := len(row)
+				if len(c.R) > 0 {
+					index = columnIndex(c.R)
+				}
+				for len(row) <= index {
+					row = append(row, "")
+				}
+				row[index] = cellText(c, sharedStrings, dateStyles, workbook.WorkbookPr.Date1904, layout)
+			}
+		case xml.EndElement:
+			if e.Name.Local != "row" {
+				continue
+			}
+			inRow = false
+			if isEmptyRow(row) || rowNumber < r.HeaderRow {
+				continue
+			}
+			if rowNumber == r.HeaderRow {
+				r.setHeader(row)
+				continue
+			}
+			record := make([]string, len(row))
+			copy(record, row)
+			if err := next(record, nil, rowNumber); err != nil {
+				return err
+			}
+		}
+	}
+	next(nil, io.EOF, rowNumber+1)
+	return nil
+}
+
+// Transform transforms a row to T; the dates are parsed by the format of the tag `format`, or by DateFormat, or as the serial numbers of Excel.
+func (r *ExcelReader[T]) Transform(ctx context.Context, record []string) (T, error) {
+	var res T
+	s := reflect.Indirect(reflect.ValueOf(&res))
+	t := s.Type()
+	for i := 0; i < t.NumField(); i++ {
+		format, ok := r.formatCols[i]
+		if !ok || !s.Field(i).CanSet() {
+			continue
+		}
+		column := i
+		if r.columns != nil {
+			column = r.columns[i]
+		}
+		if column < 0 || column >= len(record) || len(record[column]) == 0 {
+			continue
+		}
+		text := record[column]
+		f := s.Field(i)
+		var err error
+		if format.TypeName == "time.Time" || format.TypeName == "*time.Time" {
+			err = r.setTime(f, text, format.Format)
+		} else {
+			err = format.Handle(f, text, format.Format, format.Scale)
+		}
+		if err != nil {
+			return res, fmt.Errorf("field %s: %w", t.Field(i).Name, err)
+		}
+	}
+	return res, nil
+}
+func (r *ExcelReader[T]) setTime(f reflect.Value, text string, format string) error {
+	layouts := []string{format, r.DateFormat, time.RFC3339}
+	for _, layout := range layouts {
+		if len(layout) == 0 {
+			continue
+		}
+		if d, err := time.Parse(layout, text); err == nil {
+			return setTimeValue(f, d)
+		}
+	}
+	if v, err := strconv.ParseFloat(text, 64); err == nil {
+		return setTimeValue(f, excelTimeToTime(v, false))
+	}
+	return fmt.Errorf("cannot parse '%s' as a time", text)
+}
+func (r *ExcelReader[T]) setHeader(row []string) {
+	r.Header = make([]string, len(row))
+	copy(r.Header, row)
+	var t T
+	modelType := reflect.TypeOf(t)
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	columns := make([]int, modelType.NumField())
+	for i := range columns {
+		columns[i] = -1
+		field := modelType.Field(i)
+		names := []string{field.Tag.Get("excel"), strings.Split(field.Tag.Get("json"), ",")[0], field.Name}
+		for _, name := range names {
+			if len(name) == 0 || name == "-" {
+				continue
+			}
+			if k := indexOfHeader(row, name); k >= 0 {
+				columns[i] = k
+				break
+			}
+		}
+	}
+	r.columns = columns
+}
+
+func setTimeValue(f reflect.Value, d time.Time) error {
+	if f.Kind() == reflect.Ptr {
+		f.Set(reflect.ValueOf(&d))
+	} else {
+		f.Set(reflect.ValueOf(d))
+	}
+	return nil
+}
+func indexOfHeader(header []string, name string) int {
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i
+		}
+	}
+	return -1
+}
+func isEmptyRow(row []string) bool {
+	for _, s := range row {
+		if len(strings.TrimSpace(s)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+func cellText(c xlsxCell, sharedStrings []string, dateStyles map[int]bool, date1904 bool, layout string) string {
+	switch c.T {
+	case "s":
+		i, err := strconv.Atoi(c.V)
+		if err != nil || i < 0 || i >= len(sharedStrings) {
+			return ""
+		}
+		return sharedStrings[i]
+	case "inlineStr":
+		if len(c.Is.R) > 0 {
+			var b strings.Builder
+			for _, r := range c.Is.R {
+				b.WriteString(r.T)
+			}
+			return b.String()
+		}
+		return c.Is.T
+	case "b":
+		if c.V == "1" {
+			return "true"
+		}
+		return "false"
+	case "str", "e", "d":
+		return c.V
+	}
+	if len(c.V) == 0 {
+		return ""
+	}
+	v, err := strconv.ParseFloat(c.V, 64)
+	if err != nil {
+		return c.V
+	}
+	if dateStyles[c.S] {
+		return excelTimeToTime(v, date1904).Format(layout)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// excelTimeToTime converts the serial number of a time in Excel to a time in UTC; date1904 is for the workbooks in the 1904 date system.
+// The serial numbers until 60 are before 1900-03-01, when Excel counts 1900-02-29, which does not exist.
+func excelTimeToTime(v float64, date1904 bool) time.Time {
+	var base time.Time
+	if date1904 {
+		base = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+	} else {
+		base = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+		if v < 61 {
+			base = base.AddDate(0, 0, 1)
+		}
+	}
+	days := math.Floor(v)
+	// round to milliseconds, because the fractions of a day are not exact in float64
+	ms := math.Round((v - days) * float64(24*time.Hour/time.Millisecond))
+	return base.AddDate(0, 0, int(days)).Add(time.Duration(ms) * time.Millisecond)
+}
+
+// columnIndex returns the index of the column of a cell reference, such as 27 for AB12.
+func columnIndex(ref string) int {
+	n := 0
+	for _, ch := range ref {
+		if ch >= 'A' && ch <= 'Z' {
+			n = n*26 + int(ch-'A'+1)
+		} else if ch >= 'a' && ch <= 'z' {
+			n = n*26 + int(ch-'a'+1)
+		} else {
+			break
+		}
+	}
+	return n - 1
+}
+func decodeZipFile(files map[string]*archive.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("'%s' is not found in the workbook", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+func findSheet(workbook xlsxWorkbook, rels []xlsxRelationship, name string) (string, error) {
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("the workbook has no sheet")
+	}
+	sheet := workbook.Sheets[0]
+	if len(name) > 0 {
+		found := false
+		for _, s := range workbook.Sheets {
+			if strings.EqualFold(s.Name, name) {
+				sheet, found = s, true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("sheet '%s' is not found", name)
+		}
+	}
+	for _, rel := range rels {
+		if rel.Id == sheet.Id {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return "", fmt.Errorf("sheet '%s' is not found", sheet.Name)
+}
+func readSharedStrings(files map[string]*archive.File) ([]string, error) {
+	if _, ok := files["xl/sharedStrings.xml"]; !ok {
+		return nil, nil
+	}
+	var sst struct {
+		SI []struct {
+			T string `xml:"t"`
+			R []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := decodeZipFile(files, "xl/sharedStrings.xml", &sst); err != nil {
+		return nil, err
+	}
+	list := make([]string, len(sst.SI))
+	for i, si := range sst.SI {
+		if len(si.R) > 0 {
+			var b strings.Builder
+			for _, r := range si.R {
+				b.WriteString(r.T)
+			}
+			list[i] = b.String()
+		} else {
+			list[i] = si.T
+		}
+	}
+	return list, nil
+}
+
+// readDateStyles returns the indexes of the cell styles, whose number formats are dates: the built-in formats 14 to 22 and 45 to 47, and the custom formats with the date or the time parts.
+func readDateStyles(files map[string]*archive.File) (map[int]bool, error) {
+	styles := make(map[int]bool)
+	if _, ok := files["xl/styles.xml"]; !ok {
+		return styles, nil
+	}
+	var ss struct {
+		NumFmts []struct {
+			Id   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtId int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeZipFile(files, "xl/styles.xml", &ss); err != nil {
+		return nil, err
+	}
+	custom := make(map[int]bool)
+	for _, f := range ss.NumFmts {
+		custom[f.Id] = isDateFormat(f.Code)
+	}
+	for i, xf := range ss.CellXfs {
+		id := xf.NumFmtId
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) || custom[id] {
+			styles[i] = true
+		}
+	}
+	return styles, nil
+}
+func isDateFormat(code string) bool {
+	inQuote := false
+	inBracket := false
+	for i := 0; i < len(code); i++ {
+		ch := code[i]
+		switch {
+		case ch == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case ch == '\\':
+			i++
+		case ch == '[':
+			inBracket = true
+		case ch == ']':
+			inBracket = false
+		case inBracket:
+		case strings.IndexByte("yYdDhHsS", ch) >= 0:
+			return true
+		case ch == 'm' || ch == 'M':
+			return true
+		}
+	}
+	return false
+}
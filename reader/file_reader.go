@@ -6,10 +6,14 @@ import (
 	"io"
 	"os"
 	"strings"
+
+	"github.com/core-go/core/io/zip"
 )
 
+// FileReader reads the lines of a file, or of Reader if it is set, such as an uploaded file; a gzip content is decompressed.
 type FileReader struct {
 	FileName string
+	Reader   io.Reader
 }
 
 func NewFileReader(buildFileName func() string) (*FileReader, error) {
@@ -20,15 +24,19 @@ func NewFileReader(buildFileName func() string) (*FileReader, error) {
 	return &FileReader{FileName: fileName}, nil
 }
 
+// NewFileReaderFrom creates a reader of r; fileName is the name of the content, such as the name of an uploaded file or of a member of an archive.
+func NewFileReaderFrom(r io.Reader, fileName string) *FileReader {
+	return &FileReader{FileName: fileName, Reader: r}
+}
+
 func (fr *FileReader) Read(next func(lines string, err error, numLine int) error) error {
-	file, err := os.Open(fr.FileName)
+	file, closeFile, err := open(fr.FileName, fr.Reader)
 	if err != nil {
-		err = errors.New("cannot open file")
 		next("", err, 0)
 		return err
 	}
 
-	defer file.Close()
+	defer closeFile()
 
 	scanner := bufio.NewScanner(file)
 	i := 1
@@ -42,6 +50,28 @@ func (fr *FileReader) Read(next func(lines string, err error, numLine int) error
 		}
 		i++
 	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
 	next("", io.EOF, i)
 	return nil
 }
+
+// open opens the file if r is nil, and decompresses r if it is a gzip stream.
+func open(fileName string, r io.Reader) (io.Reader, func() error, error) {
+	closeFile := func() error { return nil }
+	if r == nil {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return nil, nil, errors.New("cannot open file")
+		}
+		r = file
+		closeFile = file.Close
+	}
+	d, err := zip.Decompress(r)
+	if err != nil {
+		closeFile()
+		return nil, nil, err
+	}
+	return d, closeFile, nil
+}
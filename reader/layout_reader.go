@@ -0,0 +1,88 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/core-go/core/layout"
+)
+
+// LayoutTransformer transforms a line to T by a layout.Layout, which is loaded from YAML or JSON, instead of the format tags; T is a struct or a map[string]interface{}.
+type LayoutTransformer[T any] struct {
+	Layout *layout.Layout
+}
+
+func NewLayoutTransformer[T any](l *layout.Layout) *LayoutTransformer[T] {
+	return &LayoutTransformer[T]{Layout: l}
+}
+
+func (t *LayoutTransformer[T]) Transform(ctx context.Context, line string) (T, error) {
+	var res T
+	_, err := t.Layout.DecodeTo(line, &res)
+	return res, err
+}
+
+// LayoutReader reads the lines of Source, and passes only the detail records to next: the headers are passed to OnHeader, and the trailers are checked by the counts and the sums of the detail records, see layout.Checker.
+//   - If the layout has a trailer, and the file has no trailer, Read returns an error at the end of the file.
+//   - An error of a header or a trailer stops the import, because the file is not complete or not valid; an error of a detail record is passed to Transform.
+type LayoutReader struct {
+	Layout   *layout.Layout
+	Source   func(next func(line string, err error, numLine int) error) error
+	OnHeader func(values map[string]interface{}) error
+	Checker  *layout.Checker
+}
+
+func NewLayoutReader(l *layout.Layout, source func(func(string, error, int) error) error, opts ...func(map[string]interface{}) error) *LayoutReader {
+	var onHeader func(map[string]interface{}) error
+	if len(opts) > 0 {
+		onHeader = opts[0]
+	}
+	return &LayoutReader{Layout: l, Source: source, OnHeader: onHeader, Checker: layout.NewChecker(l)}
+}
+
+func (r *LayoutReader) Read(next func(line string, err error, numLine int) error) error {
+	hasTrailer := false
+	for _, record := range r.Layout.Records {
+		if record.Kind == layout.Trailer {
+			hasTrailer = true
+		}
+	}
+	trailers := 0
+	return r.Source(func(line string, err error, numLine int) error {
+		if err == io.EOF {
+			if hasTrailer && trailers == 0 {
+				return errors.New("the trailer is missing")
+			}
+			return next(line, err, numLine)
+		}
+		if err != nil {
+			return next(line, err, numLine)
+		}
+		record, values, err := r.Layout.Decode(line)
+		if record == nil || record.Kind == layout.Detail {
+			if err == nil {
+				r.Checker.Add(record, values)
+			}
+			return next(line, nil, numLine)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", numLine, err)
+		}
+		if record.Kind == layout.Header {
+			if r.OnHeader != nil {
+				if err = r.OnHeader(values); err != nil {
+					return fmt.Errorf("line %d: %w", numLine, err)
+				}
+			}
+			return nil
+		}
+		trailers++
+		if err = r.Checker.Check(record, values); err != nil {
+			return fmt.Errorf("line %d: %w", numLine, err)
+		}
+		r.Checker.Reset()
+		return nil
+	})
+}
@@ -0,0 +1,139 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NDJSONReader reads a JSON lines file, or Reader if it is set, which has a JSON object per line, and decodes a line to T; a gzip content is decompressed.
+//   - Read has the signature of the Read of Importer; the empty lines are skipped, and a line can be up to MaxLineSize bytes, 16 MB by default.
+//   - Transform decodes a line; if DisallowUnknownFields is true, an unknown field is an error.
+//   - Decode reads and decodes the lines, and reports the errors with the line numbers.
+type NDJSONReader[T any] struct {
+	FileName              string
+	Reader                io.Reader
+	MaxLineSize           int
+	DisallowUnknownFields bool
+}
+
+func NewNDJSONReader[T any](buildFileName func() string, opts ...bool) (*NDJSONReader[T], error) {
+	fileName := buildFileName()
+	if len(strings.TrimSpace(fileName)) == 0 {
+		return nil, errors.New("file name cannot be empty")
+	}
+	disallowUnknownFields := false
+	if len(opts) > 0 {
+		disallowUnknownFields = opts[0]
+	}
+	return &NDJSONReader[T]{FileName: fileName, MaxLineSize: 16 * 1024 * 1024, DisallowUnknownFields: disallowUnknownFields}, nil
+}
+
+// NewNDJSONReaderFrom creates a reader of r; fileName is the name of the content, such as the name of an uploaded file or of a member of an archive.
+func NewNDJSONReaderFrom[T any](r io.Reader, fileName string, opts ...bool) *NDJSONReader[T] {
+	disallowUnknownFields := false
+	if len(opts) > 0 {
+		disallowUnknownFields = opts[0]
+	}
+	return &NDJSONReader[T]{FileName: fileName, Reader: r, MaxLineSize: 16 * 1024 * 1024, DisallowUnknownFields: disallowUnknownFields}
+}
+
+func (r *NDJSONReader[T]) Read(next func(line string, err error, numLine int) error) error {
+	file, closeFile, err := open(r.FileName, r.Reader)
+	if err != nil {
+		next("", err, 0)
+		return err
+	}
+	defer closeFile()
+	scanner := bufio.NewScanner(file)
+	size := r.MaxLineSize
+	if size <= 0 {
+		size = 16 * 1024 * 1024
+	}
+	scanner.Buffer(make([]byte, 0, 64*1024), size)
+	i := 1
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) != 0 {
+			if err := next(line, nil, i); err != nil {
+				return err
+			}
+		}
+		i++
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("line %d: %w", i, err)
+	}
+	next("", io.EOF, i)
+	return nil
+}
+func (r *NDJSONReader[T]) Transform(ctx context.Context, line string) (T, error) {
+	var res T
+	decoder := json.NewDecoder(strings.NewReader(line))
+	if r.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&res); err != nil {
+		return res, err
+	}
+	if decoder.More() {
+		return res, errors.New("invalid character after the JSON object")
+	}
+	return res, nil
+}
+
+// Decode reads and decodes the lines; an error to decode a line is passed to next with the line number, and Decode continues if next returns nil.
+func (r *NDJSONReader[T]) Decode(ctx context.Context, next func(model T, err error, numLine int) error) error {
+	return r.Read(func(line string, err error, numLine int) error {
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		model, err := r.Transform(ctx, line)
+		if err != nil {
+			return next(model, &LineError{Line: numLine, Column: column(line, err), Err: err}, numLine)
+		}
+		return next(model, nil, numLine)
+	})
+}
+
+// LineError is an error of a line; Column is the position of the error in the line, from 1, or 0 if it is unknown.
+type LineError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *LineError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+func column(line string, err error) int {
+	var offset int64
+	var se *json.SyntaxError
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &se) {
+		offset = se.Offset
+	} else if errors.As(err, &te) {
+		offset = te.Offset
+	} else {
+		return 0
+	}
+	if offset <= 0 || offset > int64(len(line)) {
+		return 0
+	}
+	return len(bytes.Runes([]byte(line[:offset])))
+}
@@ -64,3 +64,7 @@ func (c *RedisAdapter) Count(ctx context.Context) (int64, error) {
 func (c *RedisAdapter) Size(ctx context.Context) (int64, error) {
 	return Size(c.Pool)
 }
+
+func (c *RedisAdapter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return Eval(c.Pool, script, keys, args...)
+}
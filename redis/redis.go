@@ -342,3 +342,14 @@ func Size(pool *redis.Pool) (int64, error) {
 	}
 	return 0, nil
 }
+
+func Eval(pool *redis.Pool, script string, keys []string, args ...interface{}) (interface{}, error) {
+	conn := pool.Get()
+	defer conn.Close()
+	params := make([]interface{}, 0, len(keys)+len(args))
+	for _, k := range keys {
+		params = append(params, k)
+	}
+	params = append(params, args...)
+	return redis.NewScript(len(keys), script).Do(conn, params...)
+}
@@ -67,3 +67,7 @@ func (c *RedisAdapter) Count(ctx context.Context) (int64, error) {
 func (c *RedisAdapter) Size(ctx context.Context) (int64, error) {
 	return Size(ctx, c.Client)
 }
+
+func (c *RedisAdapter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return redis.NewScript(script).Run(ctx, c.Client, keys, args...).Result()
+}
@@ -0,0 +1,455 @@
+package rule
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	sv "github.com/core-go/core"
+)
+
+const (
+	After          = "after"
+	AfterOrEqual   = "after_or_equal"
+	Before         = "before"
+	BeforeOrEqual  = "before_or_equal"
+	EqField        = "eq_field"
+	NeField        = "ne_field"
+	RequiredIf     = "required_if"
+	RequiredUnless = "required_unless"
+	RequiredWith   = "required_with"
+	Without        = "required_without"
+	Sum            = "sum"
+
+	method = "method"
+	patch  = "patch"
+)
+
+type fieldRule struct {
+	index  int
+	name   string
+	rule   string
+	other  []int
+	path   string
+	values []string
+	sumOf  []int
+}
+
+// Validator checks the rules of the tag `rule`, which compare a field with other fields of the same struct, then the checks, which are added by Add.
+//
+//	EndDate  time.Time `json:"endDate" rule:"after=StartDate"`
+//	Reason   *string   `json:"reason" rule:"required_if=Status:Approved|Rejected"`
+//	Total    float64   `json:"total" rule:"sum=Lines.Amount"`
+//
+// The rules are after, after_or_equal, before, before_or_equal, eq_field, ne_field, required_if, required_unless, required_with, required_without and sum.
+// The comparisons are skipped if a field is empty, so that they are used with the tag required of the validator.
+// The fields of the errors are in the format of the v10 validator, such as "endDate" or "lines[1].amount"; the code is the rule, such as "after" or "sum", and the param is its argument, such as "StartDate".
+type Validator[T any] struct {
+	validate func(ctx context.Context, model T) ([]sv.ErrorMessage, error)
+	checks   []func(ctx context.Context, model T) ([]sv.ErrorMessage, error)
+	cache    sync.Map
+}
+
+// NewValidator creates a validator of the rules of T; options[0] is the validation to run first, such as Validate of the v10 validator.
+func NewValidator[T any](options ...func(context.Context, T) ([]sv.ErrorMessage, error)) (*Validator[T], error) {
+	v := &Validator[T]{}
+	if len(options) > 0 {
+		v.validate = options[0]
+	}
+	var t T
+	modelType := reflect.TypeOf(t)
+	if modelType == nil {
+		return nil, fmt.Errorf("T must be a struct or a pointer to a struct")
+	}
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("T must be a struct or a pointer to a struct")
+	}
+	if _, err := v.rules(modelType); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Add adds the checks, such as the database checks of unique or Reference, which run in parallel after the rules.
+func (v *Validator[T]) Add(checks ...func(context.Context, T) ([]sv.ErrorMessage, error)) *Validator[T] {
+	v.checks = append(v.checks, checks...)
+	return v
+}
+func (v *Validator[T]) Validate(ctx context.Context, model T) ([]sv.ErrorMessage, error) {
+	errs := make([]sv.ErrorMessage, 0)
+	if v.validate != nil {
+		res, err := v.validate(ctx, model)
+		if err != nil {
+			return res, err
+		}
+		errs = append(errs, res...)
+	}
+	isPatch := false
+	if m, ok := ctx.Value(method).(string); ok && m == patch {
+		isPatch = true
+	}
+	res, err := v.check(reflect.ValueOf(model), "", isPatch)
+	if err != nil {
+		return errs, err
+	}
+	errs = append(errs, res...)
+	if len(v.checks) > 0 {
+		res, err = Parallel(v.checks...)(ctx, model)
+		if err != nil {
+			return errs, err
+		}
+		errs = append(errs, res...)
+	}
+	return errs, nil
+}
+func (v *Validator[T]) check(value reflect.Value, prefix string, isPatch bool) ([]sv.ErrorMessage, error) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct || value.Type() == timeType {
+		return nil, nil
+	}
+	rules, err := v.rules(value.Type())
+	if err != nil {
+		return nil, err
+	}
+	var errs []sv.ErrorMessage
+	for _, r := range rules {
+		if e := evaluate(value, r, prefix, isPatch); e != nil {
+			errs = append(errs, *e)
+		}
+	}
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		f := value.Field(i)
+		name := prefix + lcFirstChar(t.Field(i).Name)
+		if f.Kind() == reflect.Slice || f.Kind() == reflect.Array {
+			for k := 0; k < f.Len(); k++ {
+				res, err := v.check(f.Index(k), fmt.Sprintf("%s[%d].", name, k), isPatch)
+				if err != nil {
+					return errs, err
+				}
+				errs = append(errs, res...)
+			}
+		} else {
+			res, err := v.check(f, name+".", isPatch)
+			if err != nil {
+				return errs, err
+			}
+			errs = append(errs, res...)
+		}
+	}
+	return errs, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (v *Validator[T]) rules(t reflect.Type) ([]fieldRule, error) {
+	if r, ok := v.cache.Load(t); ok {
+		return r.([]fieldRule), nil
+	}
+	r, err := parse(t)
+	if err != nil {
+		return nil, err
+	}
+	v.cache.Store(t, r)
+	return r, nil
+}
+
+// parse parses the tags `rule` of the struct type.
+func parse(t reflect.Type) ([]fieldRule, error) {
+	var rules []fieldRule
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("rule")
+		if !ok || len(tag) == 0 {
+			continue
+		}
+		for _, s := range strings.Split(tag, ",") {
+			s = strings.TrimSpace(s)
+			if len(s) == 0 {
+				continue
+			}
+			name, param := s, ""
+			if k := strings.IndexByte(s, '='); k >= 0 {
+				name, param = s[:k], s[k+1:]
+			}
+			r := fieldRule{index: i, name: lcFirstChar(field.Name), rule: name}
+			switch name {
+			case After, AfterOrEqual, Before, BeforeOrEqual, EqField, NeField, RequiredWith, Without:
+				f, ok := t.FieldByName(param)
+				if !ok || len(f.Index) != 1 {
+					return nil, fmt.Errorf("rule %s of %s.%s: field '%s' is not found", name, t.Name(), field.Name, param)
+				}
+				r.other = f.Index
+				r.path = lcFirstChar(param)
+			case RequiredIf, RequiredUnless:
+				k := strings.IndexByte(param, ':')
+				if k < 0 {
+					return nil, fmt.Errorf("rule %s of %s.%s must be in the format Field:value1|value2", name, t.Name(), field.Name)
+				}
+				f, ok := t.FieldByName(param[:k])
+				if !ok || len(f.Index) != 1 {
+					return nil, fmt.Errorf("rule %s of %s.%s: field '%s' is not found", name, t.Name(), field.Name, param[:k])
+				}
+				r.other = f.Index
+				r.path = lcFirstChar(param[:k]) + ":" + param[k+1:]
+				r.values = strings.Split(param[k+1:], "|")
+			case Sum:
+				parts := strings.Split(param, ".")
+				if len(parts) != 2 {
+					return nil, fmt.Errorf("rule sum of %s.%s must be in the format Slice.Field", t.Name(), field.Name)
+				}
+				f, ok := t.FieldByName(parts[0])
+				if !ok || len(f.Index) != 1 || (f.Type.Kind() != reflect.Slice && f.Type.Kind() != reflect.Array) {
+					return nil, fmt.Errorf("rule sum of %s.%s: slice '%s' is not found", t.Name(), field.Name, parts[0])
+				}
+				et := f.Type.Elem()
+				if et.Kind() == reflect.Ptr {
+					et = et.Elem()
+				}
+				if et.Kind() != reflect.Struct {
+					return nil, fmt.Errorf("rule sum of %s.%s: '%s' is not a slice of struct", t.Name(), field.Name, parts[0])
+				}
+				ef, ok := et.FieldByName(parts[1])
+				if !ok || len(ef.Index) != 1 {
+					return nil, fmt.Errorf("rule sum of %s.%s: field '%s' is not found", t.Name(), field.Name, param)
+				}
+				r.other = f.Index
+				r.sumOf = ef.Index
+				r.path = lcFirstChar(parts[0]) + "." + lcFirstChar(parts[1])
+			default:
+				return nil, fmt.Errorf("rule '%s' of %s.%s is not supported", name, t.Name(), field.Name)
+			}
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func evaluate(value reflect.Value, r fieldRule, prefix string, isPatch bool) *sv.ErrorMessage {
+	f := value.Field(r.index)
+	field := prefix + r.name
+	switch r.rule {
+	case RequiredIf, RequiredUnless, RequiredWith, Without:
+		if isPatch || !isEmpty(f) {
+			return nil
+		}
+		o := value.FieldByIndex(r.other)
+		var need bool
+		switch r.rule {
+		case RequiredIf:
+			need = contains(r.values, text(o))
+		case RequiredUnless:
+			need = !contains(r.values, text(o))
+		case RequiredWith:
+			need = !isEmpty(o)
+		default:
+			need = isEmpty(o)
+		}
+		if need {
+			param := r.path
+			if len(r.values) > 0 {
+				param = param + ":" + strings.Join(r.values, "|")
+			}
+			return &sv.ErrorMessage{Field: field, Code: r.rule, Param: param, Message: fmt.Sprintf("%s is required", field)}
+		}
+		return nil
+	case Sum:
+		// a total of 0 is checked too; only a nil pointer or a value, which is not a number, is skipped
+		total, ok := number(f)
+		if !ok {
+			return nil
+		}
+		list := value.FieldByIndex(r.other)
+		var sum float64
+		for k := 0; k < list.Len(); k++ {
+			item := reflect.Indirect(list.Index(k))
+			if !item.IsValid() {
+				continue
+			}
+			if n, ok := number(item.FieldByIndex(r.sumOf)); ok {
+				sum += n
+			}
+		}
+		if !equalNumber(total, sum) {
+			return &sv.ErrorMessage{Field: field, Code: Sum, Param: r.path, Message: fmt.Sprintf("%s must be equal to the sum of %s", field, r.path)}
+		}
+		return nil
+	}
+	o := value.FieldByIndex(r.other)
+	if isEmpty(f) || isEmpty(o) {
+		return nil
+	}
+	c, ok := compare(f, o)
+	if !ok {
+		return nil
+	}
+	var valid bool
+	var text string
+	switch r.rule {
+	case After:
+		valid, text = c > 0, "after"
+	case AfterOrEqual:
+		valid, text = c >= 0, "after or equal to"
+	case Before:
+		valid, text = c < 0, "before"
+	case BeforeOrEqual:
+		valid, text = c <= 0, "before or equal to"
+	case EqField:
+		valid, text = c == 0, "equal to"
+	default:
+		valid, text = c != 0, "different from"
+	}
+	if valid {
+		return nil
+	}
+	return &sv.ErrorMessage{Field: field, Code: r.rule, Param: r.path, Message: fmt.Sprintf("%s must be %s %s", field, text, r.path)}
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t.IsZero()
+		}
+		return false
+	}
+	return v.IsZero()
+}
+func text(v reflect.Value) string {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+func number(v reflect.Value) (float64, bool) {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// equalNumber compares the numbers with a tolerance, because the sum of the float numbers, such as 0.1 + 0.2, is not exact.
+func equalNumber(a, b float64) bool {
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	m := a
+	if m < 0 {
+		m = -m
+	}
+	if m < 1 {
+		m = 1
+	}
+	return d <= 1e-9*m
+}
+func compare(a, b reflect.Value) (int, bool) {
+	a = reflect.Indirect(a)
+	b = reflect.Indirect(b)
+	if ta, ok := a.Interface().(time.Time); ok {
+		tb, ok := b.Interface().(time.Time)
+		if !ok {
+			return 0, false
+		}
+		if ta.After(tb) {
+			return 1, true
+		} else if ta.Before(tb) {
+			return -1, true
+		}
+		return 0, true
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), true
+	}
+	na, ok1 := number(a)
+	nb, ok2 := number(b)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	if na > nb {
+		return 1, true
+	} else if na < nb {
+		return -1, true
+	}
+	return 0, true
+}
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+func lcFirstChar(s string) string {
+	if len(s) > 0 {
+		runes := []rune(s)
+		runes[0] = unicode.ToLower(runes[0])
+		return string(runes)
+	}
+	return s
+}
+
+// Parallel runs the checks concurrently, such as the database checks, and returns the errors in the order of the checks.
+func Parallel[T any](checks ...func(context.Context, T) ([]sv.ErrorMessage, error)) func(context.Context, T) ([]sv.ErrorMessage, error) {
+	return func(ctx context.Context, model T) ([]sv.ErrorMessage, error) {
+		results := make([][]sv.ErrorMessage, len(checks))
+		errs := make([]error, len(checks))
+		var wg sync.WaitGroup
+		for i, check := range checks {
+			wg.Add(1)
+			go func(i int, check func(context.Context, T) ([]sv.ErrorMessage, error)) {
+				defer wg.Done()
+				results[i], errs[i] = check(ctx, model)
+			}(i, check)
+		}
+		wg.Wait()
+		list := make([]sv.ErrorMessage, 0)
+		for i := range checks {
+			if errs[i] != nil {
+				return list, errs[i]
+			}
+			list = append(list, results[i]...)
+		}
+		return list, nil
+	}
+}
+
+// Chain runs the checks one by one, and stops at the first check which returns errors, so that the next checks, such as the database checks, run only with a valid model.
+func Chain[T any](checks ...func(context.Context, T) ([]sv.ErrorMessage, error)) func(context.Context, T) ([]sv.ErrorMessage, error) {
+	return func(ctx context.Context, model T) ([]sv.ErrorMessage, error) {
+		for _, check := range checks {
+			res, err := check(ctx, model)
+			if err != nil || len(res) > 0 {
+				return res, err
+			}
+		}
+		return make([]sv.ErrorMessage, 0), nil
+	}
+}
@@ -0,0 +1,100 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type block struct {
+	mu   sync.Mutex
+	next int64
+	end  int64
+}
+
+// Allocator hands out the values of the sequences from memory, with the hi/lo allocation: it reserves a block of values per round trip, by NextN of SequenceAdapter.
+//   - It is safe for concurrent use; the goroutines share the block of a sequence, and the sequences do not block each other.
+//   - The values of a block, which are not used when the process stops, are lost, so the sequence has gaps, but the values are never duplicated across instances.
+//   - The values are increasing in an instance, but not across instances.
+type Allocator struct {
+	Reserve func(ctx context.Context, name string, n int64) (int64, error)
+	Size    int64
+	Sizes   map[string]int64
+	mu      sync.Mutex
+	blocks  map[string]*block
+}
+
+// NewAllocator creates an allocator with a block size for all the sequences; set Sizes for a block size per sequence.
+func NewAllocator(reserve func(ctx context.Context, name string, n int64) (int64, error), size int64) *Allocator {
+	if size <= 0 {
+		size = 50
+	}
+	return &Allocator{Reserve: reserve, Size: size, Sizes: make(map[string]int64), blocks: make(map[string]*block)}
+}
+func (a *Allocator) Next(ctx context.Context, name string) (int64, error) {
+	return a.NextN(ctx, name, 1)
+}
+
+// NextN returns the first of n consecutive values. If the current block has less than n values, the rest of the block is dropped and a new block of at least n values is reserved.
+func (a *Allocator) NextN(ctx context.Context, name string, n int64) (int64, error) {
+	if n <= 0 {
+		return -1, fmt.Errorf("n must be positive")
+	}
+	b := a.block(name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.end-b.next < n {
+		size := a.size(name)
+		if size < n {
+			size = n
+		}
+		first, err := a.Reserve(ctx, name, size)
+		if err != nil {
+			return -1, err
+		}
+		b.next = first
+		b.end = first + size
+	}
+	v := b.next
+	b.next += n
+	return v, nil
+}
+
+// Reset drops the blocks in memory, such as after the sequence is reset in the database.
+func (a *Allocator) Reset(names ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(names) == 0 {
+		a.blocks = make(map[string]*block)
+		return
+	}
+	for _, name := range names {
+		delete(a.blocks, name)
+	}
+}
+
+// Remaining returns the number of values in the block of the sequence.
+func (a *Allocator) Remaining(name string) int64 {
+	b := a.block(name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.end - b.next
+}
+func (a *Allocator) block(name string) *block {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b, ok := a.blocks[name]
+	if !ok {
+		b = &block{}
+		a.blocks[name] = b
+	}
+	return b
+}
+func (a *Allocator) size(name string) int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s, ok := a.Sizes[name]; ok && s > 0 {
+		return s
+	}
+	return a.Size
+}
@@ -45,22 +45,42 @@ func NewSequenceAdapter(db *sql.DB, buildParam func(int) string, options ...stri
 	}
 }
 func (s *SequenceAdapter) Next(ctx context.Context, seqName string) (int64, error) {
-	seq, err := s.next(ctx, seqName)
-	if err != nil {
-		return seq, err
+	return s.NextN(ctx, seqName, 1)
+}
+
+// NextN reserves n consecutive values of the sequence in one round trip, and returns the first one.
+// The update compares the current value, so that two instances never reserve the same values; if another instance updated the sequence first, it retries.
+func (s *SequenceAdapter) NextN(ctx context.Context, seqName string, n int64) (int64, error) {
+	if n <= 0 {
+		return -1, fmt.Errorf("n must be positive")
 	}
 	for {
-		if seq == -2 {
-			seq, err := s.next(ctx, seqName)
-			if err != nil {
-				return seq, err
-			}
-		} else {
+		seq, err := s.next(ctx, seqName, n)
+		if err != nil {
+			return seq, err
+		}
+		if seq != -2 {
 			return seq, nil
 		}
+		if err = ctx.Err(); err != nil {
+			return -1, err
+		}
 	}
 }
-func (s *SequenceAdapter) next(ctx context.Context, seqName string) (int64, error) {
+
+// NextAll reserves the values of several sequences, the keys are the names and the values are the numbers of values to reserve. It returns the first value of each sequence.
+func (s *SequenceAdapter) NextAll(ctx context.Context, names map[string]int64) (map[string]int64, error) {
+	res := make(map[string]int64, len(names))
+	for name, n := range names {
+		seq, err := s.NextN(ctx, name, n)
+		if err != nil {
+			return res, err
+		}
+		res[name] = seq
+	}
+	return res, nil
+}
+func (s *SequenceAdapter) next(ctx context.Context, seqName string, n int64) (int64, error) {
 	query := fmt.Sprintf(`select %s from %s where %s = %s`, s.Sequence, s.Tables, s.Table, s.BuildParam(1))
 	rows, err := s.DB.QueryContext(ctx, query, seqName)
 	if err != nil {
@@ -72,7 +92,8 @@ func (s *SequenceAdapter) next(ctx context.Context, seqName string) (int64, erro
 		if err := rows.Scan(&seq); err != nil {
 			return -1, err
 		}
-		updateSql := fmt.Sprintf(`update %s set %s = %s + 1 where %s = %s and %s = %d`, s.Tables, s.Sequence, s.Sequence, s.Table, s.BuildParam(1), s.Sequence, seq)
+		rows.Close()
+		updateSql := fmt.Sprintf(`update %s set %s = %s + %d where %s = %s and %s = %d`, s.Tables, s.Sequence, s.Sequence, n, s.Table, s.BuildParam(1), s.Sequence, seq)
 		res, err := s.DB.ExecContext(ctx, updateSql, seqName)
 		if err != nil {
 			return -1, err
@@ -86,11 +107,11 @@ func (s *SequenceAdapter) next(ctx context.Context, seqName string) (int64, erro
 		}
 		return seq, nil
 	} else {
-		insertSql := fmt.Sprintf(`insert into %s (%s, %s) values (%s, 2)`, s.Tables, s.Table, s.Sequence, s.BuildParam(1))
+		rows.Close()
+		insertSql := fmt.Sprintf(`insert into %s (%s, %s) values (%s, %d)`, s.Tables, s.Table, s.Sequence, s.BuildParam(1), n+1)
 		_, err = s.DB.ExecContext(ctx, insertSql, seqName)
 		if err != nil {
-			x := strings.ToLower(err.Error())
-			if strings.Index(x, "unique constraint") >= 0 || strings.Index(x, "Violation of PRIMARY KEY constraint") >= 0 {
+			if IsDuplicate(err) {
 				return -2, nil
 			}
 			return -1, err
@@ -98,6 +119,13 @@ func (s *SequenceAdapter) next(ctx context.Context, seqName string) (int64, erro
 		return 1, nil
 	}
 }
+
+// IsDuplicate checks the errors of the unique constraints of postgres, mysql, mssql, oracle and sqlite.
+func IsDuplicate(err error) bool {
+	x := strings.ToLower(err.Error())
+	return strings.Contains(x, "unique constraint") || strings.Contains(x, "duplicate key") || strings.Contains(x, "duplicate entry") ||
+		strings.Contains(x, "violation of primary key constraint") || strings.Contains(x, "ora-00001")
+}
 func (s *SequenceAdapter) Reset(ctx context.Context, id string) (int64, error) {
 	updateSql := fmt.Sprintf(`update %s set %s = 1 where %s = %s`, s.Tables, s.Sequence, s.Table, s.BuildParam(1))
 	res, err := s.DB.ExecContext(ctx, updateSql, id)
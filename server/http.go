@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
@@ -8,21 +9,15 @@ import (
 	"strconv"
 )
 
+// StartServer starts the server, and shuts it down gracefully on SIGTERM or SIGINT.
 func StartServer(cfg ServerConfig, handler http.Handler, options ...*tls.Config) {
 	log.Println(ServerInfo(cfg))
-	srv := CreateServer(cfg, handler, options...)
-	if cfg.Secure && len(cfg.Key) > 0 && len(cfg.Cert) > 0 {
-		err := srv.ListenAndServeTLS(cfg.Cert, cfg.Key)
-		if err != nil {
-			fmt.Println(err.Error())
-			panic(err)
-		}
-	} else {
-		err := srv.ListenAndServe()
-		if err != nil {
-			fmt.Println(err.Error())
-			panic(err)
-		}
+	l := NewLifecycleByConfig(cfg, nil)
+	l.AddServer(cfg, handler, options...)
+	err := l.Run(context.Background())
+	if err != nil {
+		fmt.Println(err.Error())
+		panic(err)
 	}
 }
 func Addr(port *int64) string {
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Component struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+type Listener struct {
+	Name   string
+	Server *http.Server
+	Cert   string
+	Key    string
+}
+
+type Readiness interface {
+	SetReady(ready bool)
+}
+
+// Lifecycle starts the registered components in order, then the listeners, and waits for SIGTERM or SIGINT.
+// On shutdown, it flips the readiness to DOWN, waits for the drain delay, shuts down the listeners within the timeout,
+// then stops the components in reverse order.
+type Lifecycle struct {
+	Listeners  []Listener
+	Components []Component
+	Readiness  Readiness
+	Timeout    time.Duration
+	DrainDelay time.Duration
+	Signals    []os.Signal
+	Log        func(msg string)
+	mu         sync.Mutex
+	started    int
+	serving    bool
+	stopped    bool
+}
+
+func NewLifecycle(timeout time.Duration, drainDelay time.Duration, readiness Readiness) *Lifecycle {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Lifecycle{Timeout: timeout, DrainDelay: drainDelay, Readiness: readiness, Signals: []os.Signal{syscall.SIGTERM, os.Interrupt}, Log: func(msg string) { log.Println(msg) }}
+}
+func NewLifecycleByConfig(cfg ServerConfig, readiness Readiness) *Lifecycle {
+	var timeout, drainDelay time.Duration
+	if cfg.ShutdownTimeout != nil {
+		timeout = *cfg.ShutdownTimeout
+	}
+	if cfg.DrainDelay != nil {
+		drainDelay = *cfg.DrainDelay
+	}
+	return NewLifecycle(timeout, drainDelay, readiness)
+}
+
+// AddServer creates a server from the config and adds it as a listener. It can be called many times, for example for the public api and the internal admin port.
+func (l *Lifecycle) AddServer(cfg ServerConfig, handler http.Handler, options ...*tls.Config) *http.Server {
+	srv := CreateServer(cfg, handler, options...)
+	listener := Listener{Name: cfg.Name, Server: srv}
+	if cfg.Secure && len(cfg.Key) > 0 && len(cfg.Cert) > 0 {
+		listener.Cert = cfg.Cert
+		listener.Key = cfg.Key
+	}
+	l.Listeners = append(l.Listeners, listener)
+	return srv
+}
+func (l *Lifecycle) AddListener(name string, srv *http.Server, opts ...string) {
+	listener := Listener{Name: name, Server: srv}
+	if len(opts) > 1 {
+		listener.Cert = opts[0]
+		listener.Key = opts[1]
+	}
+	l.Listeners = append(l.Listeners, listener)
+}
+
+// Register adds a component. Components are started in the order of registration, and stopped in reverse order.
+func (l *Lifecycle) Register(name string, start func(ctx context.Context) error, stop func(ctx context.Context) error) {
+	l.Components = append(l.Components, Component{Name: name, Start: start, Stop: stop})
+}
+
+// OnStop adds a component which has nothing to start, such as a database pool to close or a logger to flush.
+func (l *Lifecycle) OnStop(name string, stop func(ctx context.Context) error) {
+	l.Register(name, nil, stop)
+}
+
+// Run starts everything, then blocks until a signal is received, the context is done or a listener fails, and shuts down gracefully.
+func (l *Lifecycle) Run(ctx context.Context) error {
+	if err := l.Start(ctx); err != nil {
+		l.Shutdown(context.Background())
+		return err
+	}
+	sig := make(chan os.Signal, 1)
+	if len(l.Signals) > 0 {
+		signal.Notify(sig, l.Signals...)
+		defer signal.Stop(sig)
+	}
+	errs := make(chan error, len(l.Listeners))
+	l.mu.Lock()
+	l.serving = true
+	l.mu.Unlock()
+	for _, listener := range l.Listeners {
+		go l.serve(listener, errs)
+	}
+	if l.Readiness != nil {
+		l.Readiness.SetReady(true)
+	}
+	var serveErr error
+	select {
+	case s := <-sig:
+		l.log("Received signal " + s.String() + ", shutting down")
+	case <-ctx.Done():
+		l.log("Context is done, shutting down")
+	case serveErr = <-errs:
+		l.log("Listener failed: " + serveErr.Error())
+	}
+	err := l.Shutdown(context.Background())
+	if serveErr != nil {
+		return serveErr
+	}
+	return err
+}
+
+// Start starts the components in order. If one fails, the components which were started are stopped by Shutdown.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, c := range l.Components {
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				return fmt.Errorf("cannot start %s: %w", c.Name, err)
+			}
+		}
+		l.started = i + 1
+	}
+	return nil
+}
+func (l *Lifecycle) serve(listener Listener, errs chan<- error) {
+	srv := listener.Server
+	l.log(fmt.Sprintf("Start listener %s at %s", listener.Name, srv.Addr))
+	var err error
+	if len(listener.Cert) > 0 && len(listener.Key) > 0 {
+		err = srv.ListenAndServeTLS(listener.Cert, listener.Key)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs <- fmt.Errorf("%s: %w", listener.Name, err)
+	}
+}
+
+// Shutdown flips the readiness to DOWN, waits for the drain delay, shuts down all listeners, then stops the started components in reverse order.
+// It can be called only once, the next calls do nothing.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return nil
+	}
+	l.stopped = true
+	started, serving := l.started, l.serving
+	l.mu.Unlock()
+
+	if l.Readiness != nil {
+		l.Readiness.SetReady(false)
+	}
+	if l.DrainDelay > 0 && serving {
+		time.Sleep(l.DrainDelay)
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.Timeout)
+	defer cancel()
+	var errs []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, listener := range l.Listeners {
+		wg.Add(1)
+		go func(listener Listener) {
+			defer wg.Done()
+			if err := listener.Server.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, listener.Name+": "+err.Error())
+				mu.Unlock()
+			}
+		}(listener)
+	}
+	wg.Wait()
+	for i := started - 1; i >= 0; i-- {
+		c := l.Components[i]
+		if c.Stop == nil {
+			continue
+		}
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, c.Name+": "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New("shutdown error: " + strings.Join(errs, "; "))
+	}
+	l.log("Shutdown completed")
+	return nil
+}
+func (l *Lifecycle) log(msg string) {
+	if l.Log != nil {
+		l.Log(msg)
+	}
+}
@@ -16,6 +16,8 @@ type ServerConfig struct {
 	ReadHeaderTimeout *time.Duration `yaml:"read_header_timeout" mapstructure:"read_header_timeout" json:"readHeaderTimeout,omitempty" gorm:"column:readheadertimeout" bson:"readHeaderTimeout,omitempty" dynamodbav:"readHeaderTimeout,omitempty" firestore:"readHeaderTimeout,omitempty"`
 	IdleTimeout       *time.Duration `yaml:"idle_timeout" mapstructure:"idle_timeout" json:"idleTimeout,omitempty" gorm:"column:idletimeout" bson:"idleTimeout,omitempty" dynamodbav:"idleTimeout,omitempty" firestore:"idleTimeout,omitempty"`
 	MaxHeaderBytes    *int           `yaml:"max_header_bytes" mapstructure:"max_header_bytes" json:"maxHeaderBytes,omitempty" gorm:"column:maxheaderbytes" bson:"maxHeaderBytes,omitempty" dynamodbav:"maxHeaderBytes,omitempty" firestore:"maxHeaderBytes,omitempty"`
+	ShutdownTimeout   *time.Duration `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout" json:"shutdownTimeout,omitempty" gorm:"column:shutdowntimeout" bson:"shutdownTimeout,omitempty" dynamodbav:"shutdownTimeout,omitempty" firestore:"shutdownTimeout,omitempty"`
+	DrainDelay        *time.Duration `yaml:"drain_delay" mapstructure:"drain_delay" json:"drainDelay,omitempty" gorm:"column:draindelay" bson:"drainDelay,omitempty" dynamodbav:"drainDelay,omitempty" firestore:"drainDelay,omitempty"`
 	Cert              string         `yaml:"cert" mapstructure:"cert" json:"cert,omitempty" gorm:"column:cert" bson:"cert,omitempty" dynamodbav:"cert,omitempty" firestore:"cert,omitempty"`
 	Key               string         `yaml:"key" mapstructure:"key" json:"key,omitempty" gorm:"column:key" bson:"key,omitempty" dynamodbav:"key,omitempty" firestore:"key,omitempty"`
 }
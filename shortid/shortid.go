@@ -2,20 +2,45 @@ package shortid
 
 import (
 	"context"
+	"sync"
+	"time"
+
 	"github.com/teris-io/shortid"
 )
 
 var sid *shortid.Shortid
+var mu sync.Mutex
+
+// Initialize sets the worker (0 to 31) and the seed of the generator. Each instance must have its own worker, otherwise two instances may generate the same id in the same millisecond.
+func Initialize(worker uint8, seed uint64) error {
+	s, err := shortid.New(worker, shortid.DefaultABC, seed)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	sid = s
+	mu.Unlock()
+	return nil
+}
 
+// ShortId generates an id; if Initialize was not called, the worker is 1 and the seed is the current time.
 func ShortId() (string, error) {
-	if sid == nil {
-		s, err := shortid.New(1, shortid.DefaultABC, 2342)
+	mu.Lock()
+	s := sid
+	mu.Unlock()
+	if s == nil {
+		s2, err := shortid.New(1, shortid.DefaultABC, uint64(time.Now().UnixNano()))
 		if err != nil {
 			return "", err
 		}
-		sid = s
+		mu.Lock()
+		if sid == nil {
+			sid = s2
+		}
+		s = sid
+		mu.Unlock()
 	}
-	return sid.Generate()
+	return s.Generate()
 }
 func Generate(ctx context.Context) (string, error) {
 	return ShortId()
@@ -0,0 +1,320 @@
+// Package smtptest provides a tiny SMTP server, which records the received messages, to test the mail senders end to end without network access.
+package smtptest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	From     string
+	To       []string
+	Username string
+	Data     []byte
+	Received time.Time
+}
+
+// Parse parses the data of the message.
+func (m Message) Parse() (*mail.Message, error) {
+	return mail.ReadMessage(bytes.NewReader(m.Data))
+}
+
+// Server accepts any sender, any recipient and any credentials with AUTH PLAIN or AUTH LOGIN. It does not support STARTTLS.
+type Server struct {
+	Addr     string
+	Hostname string
+	// Reject returns an error for the recipient to reply 550, to test the failures.
+	Reject   func(to string) error
+	listener net.Listener
+	mu       sync.Mutex
+	messages []Message
+	wg       sync.WaitGroup
+	conns    map[net.Conn]struct{}
+	closed   bool
+	received chan struct{}
+}
+
+func NewServer() *Server {
+	return &Server{Hostname: "localhost", conns: make(map[net.Conn]struct{}), received: make(chan struct{}, 1)}
+}
+
+// Start listens on a random port of 127.0.0.1, or on Addr if it is set.
+func (s *Server) Start() error {
+	addr := s.Addr
+	if len(addr) == 0 {
+		addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.listener = ln
+	s.Addr = ln.Addr().String()
+	s.wg.Add(1)
+	go s.serve()
+	return nil
+}
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr)
+	return host
+}
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.Addr)
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// Close stops listening, closes the connections and waits for them.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	s.wg.Wait()
+	return err
+}
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := make([]Message, len(s.messages))
+	copy(messages, s.messages)
+	return messages
+}
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.messages = nil
+	s.mu.Unlock()
+}
+
+// Wait waits until at least n messages are received. It returns false after the timeout.
+func (s *Server) Wait(n int, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		s.mu.Lock()
+		count := len(s.messages)
+		s.mu.Unlock()
+		if count >= n {
+			return true
+		}
+		select {
+		case <-s.received:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+			c.Close()
+		}()
+	}
+}
+
+type session struct {
+	from     string
+	to       []string
+	username string
+}
+
+func (s *Server) handle(c net.Conn) {
+	tc := textproto.NewConn(c)
+	reply := func(code int, msg string) bool {
+		return tc.PrintfLine("%d %s", code, msg) == nil
+	}
+	if !reply(220, s.Hostname+" ESMTP smtptest") {
+		return
+	}
+	var ss session
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tc.PrintfLine("250-%s greets %s", s.Hostname, arg)
+			tc.PrintfLine("250-8BITMIME")
+			tc.PrintfLine("250-AUTH PLAIN LOGIN")
+			reply(250, "SMTPUTF8")
+		case "HELO":
+			reply(250, s.Hostname)
+		case "AUTH":
+			username, err := auth(tc, arg)
+			if err != nil {
+				reply(501, err.Error())
+				continue
+			}
+			ss.username = username
+			reply(235, "2.7.0 Authentication successful")
+		case "MAIL":
+			from, ok := address(arg, "FROM:")
+			if !ok {
+				reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+				continue
+			}
+			ss.from = from
+			ss.to = nil
+			reply(250, "2.1.0 OK")
+		case "RCPT":
+			to, ok := address(arg, "TO:")
+			if !ok {
+				reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+				continue
+			}
+			if len(ss.from) == 0 {
+				reply(503, "5.5.1 MAIL first")
+				continue
+			}
+			if s.Reject != nil {
+				if err := s.Reject(to); err != nil {
+					reply(550, "5.1.1 "+err.Error())
+					continue
+				}
+			}
+			ss.to = append(ss.to, to)
+			reply(250, "2.1.5 OK")
+		case "DATA":
+			if len(ss.to) == 0 {
+				reply(503, "5.5.1 RCPT first")
+				continue
+			}
+			reply(354, "End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(tc.DotReader())
+			if err != nil {
+				return
+			}
+			s.add(Message{From: ss.from, To: ss.to, Username: ss.username, Data: data, Received: time.Now()})
+			ss.from, ss.to = "", nil
+			reply(250, "2.0.0 OK: queued")
+		case "RSET":
+			ss.from, ss.to = "", nil
+			reply(250, "2.0.0 OK")
+		case "NOOP":
+			reply(250, "2.0.0 OK")
+		case "QUIT":
+			reply(221, "2.0.0 Bye")
+			return
+		default:
+			reply(502, "5.5.2 Command not recognized")
+		}
+	}
+}
+func (s *Server) add(m Message) {
+	s.mu.Lock()
+	s.messages = append(s.messages, m)
+	s.mu.Unlock()
+	select {
+	case s.received <- struct{}{}:
+	default:
+	}
+}
+
+func address(arg string, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	a := strings.TrimSpace(arg[len(prefix):])
+	if i := strings.IndexByte(a, ' '); i >= 0 {
+		a = a[:i] // skip the parameters, such as BODY=8BITMIME
+	}
+	if !strings.HasPrefix(a, "<") || !strings.HasSuffix(a, ">") {
+		return "", false
+	}
+	return a[1 : len(a)-1], true
+}
+func auth(tc *textproto.Conn, arg string) (string, error) {
+	parts := strings.Fields(arg)
+	if len(parts) == 0 {
+		return "", errors.New("5.5.4 Syntax: AUTH mechanism")
+	}
+	readResponse := func(challenge string) (string, error) {
+		if err := tc.PrintfLine("334 %s", challenge); err != nil {
+			return "", err
+		}
+		line, err := tc.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		b, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return "", errors.New("5.5.2 Invalid base64")
+		}
+		return string(b), nil
+	}
+	switch strings.ToUpper(parts[0]) {
+	case "PLAIN":
+		var resp string
+		if len(parts) > 1 {
+			b, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return "", errors.New("5.5.2 Invalid base64")
+			}
+			resp = string(b)
+		} else {
+			var err error
+			if resp, err = readResponse(""); err != nil {
+				return "", err
+			}
+		}
+		fields := strings.Split(resp, "\x00")
+		if len(fields) != 3 {
+			return "", errors.New("5.5.2 Invalid PLAIN response")
+		}
+		return fields[1], nil
+	case "LOGIN":
+		username, err := readResponse(base64.StdEncoding.EncodeToString([]byte("Username:")))
+		if err != nil {
+			return "", err
+		}
+		if _, err = readResponse(base64.StdEncoding.EncodeToString([]byte("Password:"))); err != nil {
+			return "", err
+		}
+		return username, nil
+	}
+	return "", errors.New("5.5.4 Unsupported mechanism")
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalConfig is the config of a LocalStorage.
+//   - BaseUrl is the url, where the LocalStorage is served as an http.Handler, such as https://example.com/files.
+//   - SecretKey signs the urls; if Public is true, the files are downloaded without a signed url.
+//   - Disposition is the Content-Disposition of the downloads, inline or attachment, if it is not in a signed url.
+//   - MaxSize is the max size of a file, which is uploaded to a signed url.
+type LocalConfig struct {
+	Directory   string `yaml:"directory" mapstructure:"directory" json:"directory,omitempty" gorm:"column:directory" bson:"directory,omitempty" dynamodbav:"directory,omitempty" firestore:"directory,omitempty"`
+	BaseUrl     string `yaml:"base_url" mapstructure:"base_url" json:"baseUrl,omitempty" gorm:"column:baseurl" bson:"baseUrl,omitempty" dynamodbav:"baseUrl,omitempty" firestore:"baseUrl,omitempty"`
+	SecretKey   string `yaml:"secret_key" mapstructure:"secret_key" json:"secretKey,omitempty" gorm:"column:secretkey" bson:"secretKey,omitempty" dynamodbav:"secretKey,omitempty" firestore:"secretKey,omitempty"`
+	Public      bool   `yaml:"public" mapstructure:"public" json:"public,omitempty" gorm:"column:public" bson:"public,omitempty" dynamodbav:"public,omitempty" firestore:"public,omitempty"`
+	Disposition string `yaml:"disposition" mapstructure:"disposition" json:"disposition,omitempty" gorm:"column:disposition" bson:"disposition,omitempty" dynamodbav:"disposition,omitempty" firestore:"disposition,omitempty"`
+	MaxSize     int64  `yaml:"max_size" mapstructure:"max_size" json:"maxSize,omitempty" gorm:"column:maxsize" bson:"maxSize,omitempty" dynamodbav:"maxSize,omitempty" firestore:"maxSize,omitempty"`
+}
+
+// LocalStorage stores the files in a directory of the local disk, and serves them by ServeHTTP:
+//   - GET and HEAD download a file by a signed url, or by its url if Public is true.
+//   - PUT uploads a file to a signed url of SignUpload.
+type LocalStorage struct {
+	Config LocalConfig
+	Layout Layout
+	prefix string
+}
+
+// NewLocalStorage creates a storage; opts is the layout, which is FlatLayout by default.
+func NewLocalStorage(config LocalConfig, opts ...Layout) (*LocalStorage, error) {
+	if err := os.MkdirAll(config.Directory, os.ModePerm); err != nil {
+		return nil, err
+	}
+	config.BaseUrl = strings.TrimSuffix(config.BaseUrl, "/")
+	u, err := url.Parse(config.BaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	layout := FlatLayout
+	if len(opts) > 0 && opts[0] != nil {
+		layout = opts[0]
+	}
+	return &LocalStorage{Config: config, Layout: layout, prefix: strings.TrimSuffix(u.Path, "/") + "/"}, nil
+}
+
+func (s *LocalStorage) Upload(ctx context.Context, directory string, filename string, data io.Reader, contentType string) (string, error) {
+	key, err := CleanKey(s.Layout(directory, filename))
+	if err != nil {
+		return "", err
+	}
+	if _, err = s.write(key, data, -1); err != nil {
+		return "", err
+	}
+	return s.url(key), nil
+}
+func (s *LocalStorage) Delete(ctx context.Context, id string) (bool, error) {
+	key, ok := s.Key(id)
+	if !ok {
+		return false, ErrInvalidKey
+	}
+	key, err := CleanKey(key)
+	if err != nil {
+		return false, err
+	}
+	if err = os.Remove(s.path(key)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+func (s *LocalStorage) Key(url string) (string, bool) {
+	return keyOf(s.Config.BaseUrl, url)
+}
+
+// SignDownload signs a url to download a file before expires; opts is the Content-Disposition.
+func (s *LocalStorage) SignDownload(ctx context.Context, key string, expires time.Duration, opts ...string) (string, error) {
+	disposition := ""
+	if len(opts) > 0 {
+		disposition = opts[0]
+	}
+	return s.sign(http.MethodGet, key, expires, "disposition", disposition)
+}
+
+// SignUpload signs a url to upload a file by PUT before expires; opts is the content type.
+func (s *LocalStorage) SignUpload(ctx context.Context, key string, expires time.Duration, opts ...string) (string, error) {
+	contentType := ""
+	if len(opts) > 0 {
+		contentType = opts[0]
+	}
+	return s.sign(http.MethodPut, key, expires, "type", contentType)
+}
+
+func (s *LocalStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, s.prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	key, err := CleanKey(strings.TrimPrefix(r.URL.Path, s.prefix))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		disposition := query.Get("disposition")
+		if len(query.Get("signature")) > 0 || !s.Config.Public {
+			if !s.verify(http.MethodGet, key, query, disposition) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Cache-Control", "private, no-store")
+		}
+		s.serve(w, r, key, disposition)
+	case http.MethodPut:
+		contentType := query.Get("type")
+		if !s.verify(http.MethodPut, key, query, contentType) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if len(contentType) > 0 && r.Header.Get("Content-Type") != contentType {
+			http.Error(w, "Content-Type must be "+contentType, http.StatusBadRequest)
+			return
+		}
+		if s.Config.MaxSize > 0 && r.ContentLength > s.Config.MaxSize {
+			http.Error(w, fmt.Sprintf("Limit maxsize: %d bytes", s.Config.MaxSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if _, err = s.write(key, r.Body, s.Config.MaxSize); err != nil {
+			if errors.Is(err, errTooLarge) {
+				http.Error(w, fmt.Sprintf("Limit maxsize: %d bytes", s.Config.MaxSize), http.StatusRequestEntityTooLarge)
+			} else {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *LocalStorage) serve(w http.ResponseWriter, r *http.Request, key string, disposition string) {
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	if len(disposition) == 0 {
+		disposition = ContentDisposition(s.Config.Disposition, path.Base(key))
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, path.Base(key), info.ModTime(), file)
+}
+
+var errTooLarge = errors.New("file is too large")
+
+// write writes a file to a temporary file, then renames it, so a file is not read while it is written; max -1 is no limit.
+func (s *LocalStorage) write(key string, data io.Reader, max int64) (int64, error) {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return 0, err
+	}
+	file, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+	src := data
+	if max > 0 {
+		src = io.LimitReader(data, max+1)
+	}
+	n, err := io.Copy(file, src)
+	if err == nil && max > 0 && n > max {
+		err = errTooLarge
+	}
+	if er1 := file.Close(); er1 != nil && err == nil {
+		err = er1
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, os.Rename(file.Name(), p)
+}
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.Config.Directory, filepath.FromSlash(key))
+}
+func (s *LocalStorage) url(key string) string {
+	return s.Config.BaseUrl + "/" + escapePath(key)
+}
+
+func (s *LocalStorage) sign(method string, key string, expires time.Duration, name string, value string) (string, error) {
+	if len(s.Config.SecretKey) == 0 {
+		return "", errors.New("secret key is required to sign a url")
+	}
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	exp := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", exp)
+	if len(value) > 0 {
+		query.Set(name, value)
+	}
+	query.Set("signature", s.signature(method, key, exp, value))
+	return s.url(key) + "?" + query.Encode(), nil
+}
+func (s *LocalStorage) verify(method string, key string, query url.Values, value string) bool {
+	if len(s.Config.SecretKey) == 0 {
+		return false
+	}
+	exp := query.Get("expires")
+	t, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > t {
+		return false
+	}
+	expected := s.signature(method, key, exp, value)
+	return hmac.Equal([]byte(expected), []byte(query.Get("signature")))
+}
+func (s *LocalStorage) signature(method string, key string, expires string, value string) string {
+	mac := hmac.New(sha256.New, []byte(s.Config.SecretKey))
+	mac.Write([]byte(method + "\n" + key + "\n" + expires + "\n" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
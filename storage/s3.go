@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const minPartSize = 5 * 1024 * 1024
+
+// S3Config is the config of an S3Storage.
+//   - Endpoint is the url of the server, such as https://s3.us-east-1.amazonaws.com or http://localhost:9000 for MinIO.
+//   - PathStyle addresses a bucket by the path, such as http://localhost:9000/bucket/key, which MinIO uses; otherwise, the bucket is in the host, such as https://bucket.s3.amazonaws.com/key.
+//   - PublicUrl is the url of the files, which Upload returns, if the bucket is public or is served by a CDN; otherwise, it is the url of the objects.
+//   - Disposition is the Content-Disposition of the objects, inline or attachment; PartSize is the size of the parts of a multipart upload, which is 8 MB by default.
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint" mapstructure:"endpoint" json:"endpoint,omitempty" gorm:"column:endpoint" bson:"endpoint,omitempty" dynamodbav:"endpoint,omitempty" firestore:"endpoint,omitempty"`
+	Region          string `yaml:"region" mapstructure:"region" json:"region,omitempty" gorm:"column:region" bson:"region,omitempty" dynamodbav:"region,omitempty" firestore:"region,omitempty"`
+	Bucket          string `yaml:"bucket" mapstructure:"bucket" json:"bucket,omitempty" gorm:"column:bucket" bson:"bucket,omitempty" dynamodbav:"bucket,omitempty" firestore:"bucket,omitempty"`
+	AccessKeyId     string `yaml:"access_key_id" mapstructure:"access_key_id" json:"accessKeyId,omitempty" gorm:"column:accesskeyid" bson:"accessKeyId,omitempty" dynamodbav:"accessKeyId,omitempty" firestore:"accessKeyId,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key" mapstructure:"secret_access_key" json:"secretAccessKey,omitempty" gorm:"column:secretaccesskey" bson:"secretAccessKey,omitempty" dynamodbav:"secretAccessKey,omitempty" firestore:"secretAccessKey,omitempty"`
+	PathStyle       bool   `yaml:"path_style" mapstructure:"path_style" json:"pathStyle,omitempty" gorm:"column:pathstyle" bson:"pathStyle,omitempty" dynamodbav:"pathStyle,omitempty" firestore:"pathStyle,omitempty"`
+	PublicUrl       string `yaml:"public_url" mapstructure:"public_url" json:"publicUrl,omitempty" gorm:"column:publicurl" bson:"publicUrl,omitempty" dynamodbav:"publicUrl,omitempty" firestore:"publicUrl,omitempty"`
+	Disposition     string `yaml:"disposition" mapstructure:"disposition" json:"disposition,omitempty" gorm:"column:disposition" bson:"disposition,omitempty" dynamodbav:"disposition,omitempty" firestore:"disposition,omitempty"`
+	PartSize        int64  `yaml:"part_size" mapstructure:"part_size" json:"partSize,omitempty" gorm:"column:partsize" bson:"partSize,omitempty" dynamodbav:"partSize,omitempty" firestore:"partSize,omitempty"`
+}
+
+// S3Error is an error response of an S3 server.
+type S3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("s3: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// S3Storage stores the files in a bucket by the S3 protocol, without an SDK, so it works with AWS S3 and the S3-compatible servers, such as MinIO.
+// A file is uploaded by a PutObject if it is less than PartSize, or by a multipart upload, so a stream of an unknown size is not loaded into memory.
+type S3Storage struct {
+	Config   S3Config
+	Layout   Layout
+	Client   *http.Client
+	endpoint *url.URL
+	signer   *signer
+	now      func() time.Time
+}
+
+// NewS3Storage creates a storage; opts is the layout, which is FlatLayout by default.
+func NewS3Storage(config S3Config, opts ...Layout) (*S3Storage, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoint.Scheme) == 0 || len(endpoint.Host) == 0 {
+		return nil, fmt.Errorf("invalid endpoint '%s'", config.Endpoint)
+	}
+	if len(config.Bucket) == 0 {
+		return nil, errors.New("bucket is required")
+	}
+	if len(config.Region) == 0 {
+		config.Region = "us-east-1"
+	}
+	if config.PartSize < minPartSize {
+		config.PartSize = 8 * 1024 * 1024
+	}
+	config.PublicUrl = strings.TrimSuffix(config.PublicUrl, "/")
+	layout := FlatLayout
+	if len(opts) > 0 && opts[0] != nil {
+		layout = opts[0]
+	}
+	return &S3Storage{Config: config, Layout: layout, Client: http.DefaultClient, endpoint: endpoint,
+		signer: &signer{accessKeyId: config.AccessKeyId, secretAccessKey: config.SecretAccessKey, region: config.Region, service: "s3"}, now: time.Now}, nil
+}
+
+func (s *S3Storage) Upload(ctx context.Context, directory string, filename string, data io.Reader, contentType string) (string, error) {
+	key, err := CleanKey(s.Layout(directory, filename))
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{}
+	if len(contentType) > 0 {
+		header.Set("Content-Type", contentType)
+	}
+	if len(s.Config.Disposition) > 0 {
+		header.Set("Content-Disposition", ContentDisposition(s.Config.Disposition, filename))
+	}
+	part := make([]byte, s.Config.PartSize)
+	n, err := io.ReadFull(data, part)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		_, err = s.do(ctx, http.MethodPut, key, nil, header, part[:n])
+		if err != nil {
+			return "", err
+		}
+		return s.url(key), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if err = s.multipart(ctx, key, header, part, data); err != nil {
+		return "", err
+	}
+	return s.url(key), nil
+}
+
+// multipart uploads a file by parts; the upload is aborted if a part fails, so the parts are not kept by the server.
+func (s *S3Storage) multipart(ctx context.Context, key string, header http.Header, part []byte, data io.Reader) error {
+	res, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadId string `xml:"UploadId"`
+	}
+	if err = xml.Unmarshal(res, &initiated); err != nil {
+		return err
+	}
+	uploadId := initiated.UploadId
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var parts []completedPart
+	n := len(part)
+	for number := 1; n > 0; number++ {
+		etag, err := s.uploadPart(ctx, key, uploadId, number, part[:n])
+		if err != nil {
+			s.abort(key, uploadId)
+			return err
+		}
+		parts = append(parts, completedPart{PartNumber: number, ETag: etag})
+		n, err = io.ReadFull(data, part)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			s.abort(key, uploadId)
+			return err
+		}
+	}
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		s.abort(key, uploadId)
+		return err
+	}
+	// the server can reply an error in a response of status 200, after it started to complete the upload
+	res, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadId}}, nil, body)
+	if err == nil {
+		err = parseError(http.StatusOK, res)
+	}
+	if err != nil {
+		s.abort(key, uploadId)
+	}
+	return err
+}
+func (s *S3Storage) uploadPart(ctx context.Context, key string, uploadId string, number int, data []byte) (string, error) {
+	req, err := s.request(ctx, http.MethodPut, key, url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadId}}, nil, data)
+	if err != nil {
+		return "", err
+	}
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode >= 300 {
+		return "", toError(res.StatusCode, body)
+	}
+	return res.Header.Get("ETag"), nil
+}
+func (s *S3Storage) abort(key string, uploadId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadId}}, nil, nil)
+}
+
+// Delete deletes an object by its key or its url. S3 replies 204 to a DELETE, even if the object does not exist,
+// so the object is checked by a HEAD first, and Delete returns false if it does not exist.
+func (s *S3Storage) Delete(ctx context.Context, id string) (bool, error) {
+	key, ok := s.Key(id)
+	if !ok {
+		return false, ErrInvalidKey
+	}
+	key, err := CleanKey(key)
+	if err != nil {
+		return false, err
+	}
+	if _, err = s.do(ctx, http.MethodHead, key, nil, nil, nil); err != nil {
+		return false, notFound(err)
+	}
+	if _, err = s.do(ctx, http.MethodDelete, key, nil, nil, nil); err != nil {
+		return false, notFound(err)
+	}
+	return true, nil
+}
+
+// notFound returns nil if err is the status 404, so a missing object is not an error.
+func notFound(err error) error {
+	var e *S3Error
+	if errors.As(err, &e) && e.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Key returns the key of a file by its url, which is the url of Upload.
+func (s *S3Storage) Key(url string) (string, bool) {
+	if len(s.Config.PublicUrl) > 0 && strings.HasPrefix(url, s.Config.PublicUrl+"/") {
+		return keyOf(s.Config.PublicUrl, url)
+	}
+	return keyOf(s.objectUrl("", nil).String(), url)
+}
+
+// SignDownload presigns a GET url; opts is the Content-Disposition of the response, which S3 returns by response-content-disposition.
+func (s *S3Storage) SignDownload(ctx context.Context, key string, expires time.Duration, opts ...string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		query.Set("response-content-disposition", opts[0])
+	}
+	return s.signer.presign(http.MethodGet, s.objectUrl(key, query), nil, expires, s.now()), nil
+}
+
+// SignUpload presigns a PUT url; opts is the content type, which is signed, so the client must send it as Content-Type.
+func (s *S3Storage) SignUpload(ctx context.Context, key string, expires time.Duration, opts ...string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	var headers map[string]string
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		headers = map[string]string{"content-type": opts[0]}
+	}
+	return s.signer.presign(http.MethodPut, s.objectUrl(key, nil), headers, expires, s.now()), nil
+}
+
+func (s *S3Storage) url(key string) string {
+	if len(s.Config.PublicUrl) > 0 {
+		return s.Config.PublicUrl + "/" + escapePath(key)
+	}
+	return s.objectUrl(key, nil).String()
+}
+
+// objectUrl returns the url of an object, in the path style or in the virtual-hosted style.
+func (s *S3Storage) objectUrl(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	p := strings.TrimSuffix(u.Path, "/")
+	if s.Config.PathStyle {
+		p = p + "/" + s.Config.Bucket
+	} else {
+		u.Host = s.Config.Bucket + "." + u.Host
+	}
+	u.Path = p + "/" + key
+	u.RawPath = canonicalPath(&url.URL{Path: u.Path})
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+func (s *S3Storage) request(ctx context.Context, method string, key string, query url.Values, header http.Header, body []byte) (*http.Request, error) {
+	u := s.objectUrl(key, query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.ContentLength = int64(len(body))
+	s.signer.sign(req, hashHex(body), s.now())
+	return req, nil
+}
+func (s *S3Storage) do(ctx context.Context, method string, key string, query url.Values, header http.Header, body []byte) ([]byte, error) {
+	req, err := s.request(ctx, method, key, query, header, body)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 {
+		return nil, toError(res.StatusCode, data)
+	}
+	return data, nil
+}
+
+func toError(statusCode int, body []byte) error {
+	if err := parseError(statusCode, body); err != nil {
+		return err
+	}
+	return &S3Error{StatusCode: statusCode, Code: http.StatusText(statusCode)}
+}
+func parseError(statusCode int, body []byte) error {
+	if !bytes.Contains(body, []byte("<Error>")) {
+		return nil
+	}
+	e := &S3Error{StatusCode: statusCode}
+	if err := xml.Unmarshal(body, e); err != nil {
+		return err
+	}
+	return e
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	algorithm       = "AWS4-HMAC-SHA256"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+)
+
+// signer signs the requests by AWS Signature Version 4, which is supported by S3 and the S3-compatible servers, such as MinIO.
+type signer struct {
+	accessKeyId     string
+	secretAccessKey string
+	region          string
+	service         string
+}
+
+// sign signs a request by the Authorization header; payloadHash is the hex SHA-256 of the body.
+func (s *signer) sign(req *http.Request, payloadHash string, t time.Time) {
+	amzDate := t.UTC().Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	names := []string{"host"}
+	for name := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" || name == "content-md5" || name == "content-disposition" || name == "range" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, name := range names {
+		value := req.Host
+		if len(value) == 0 {
+			value = req.URL.Host
+		}
+		if name != "host" {
+			value = strings.Join(req.Header.Values(name), ",")
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonical := strings.Join([]string{req.Method, canonicalPath(req.URL), canonicalQuery(req.URL.Query()), headers.String(), signedHeaders, payloadHash}, "\n")
+	scope := s.scope(t)
+	signature := s.signature(t, algorithm+"\n"+amzDate+"\n"+scope+"\n"+hashHex([]byte(canonical)))
+	req.Header.Set("Authorization", algorithm+" Credential="+s.accessKeyId+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// presign returns a url, which is signed by the query parameters; headers are the headers, which a client must send with the url, such as content-type, besides host.
+func (s *signer) presign(method string, u *url.URL, headers map[string]string, expires time.Duration, t time.Time) string {
+	amzDate := t.UTC().Format(amzDateFormat)
+	names := []string{"host"}
+	values := map[string]string{"host": u.Host}
+	for name, value := range headers {
+		name = strings.ToLower(name)
+		names = append(names, name)
+		values[name] = strings.TrimSpace(value)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", algorithm)
+	query.Set("X-Amz-Credential", s.accessKeyId+"/"+s.scope(t))
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+	canonical := strings.Join([]string{method, canonicalPath(u), canonicalQuery(query), canonicalHeaders.String(), signedHeaders, unsignedPayload}, "\n")
+	signature := s.signature(t, algorithm+"\n"+amzDate+"\n"+s.scope(t)+"\n"+hashHex([]byte(canonical)))
+	return u.Scheme + "://" + u.Host + canonicalPath(u) + "?" + canonicalQuery(query) + "&X-Amz-Signature=" + signature
+}
+
+func (s *signer) scope(t time.Time) string {
+	return t.UTC().Format("20060102") + "/" + s.region + "/" + s.service + "/aws4_request"
+}
+func (s *signer) signature(t time.Time, stringToSign string) string {
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), t.UTC().Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalPath is the path of a url, which is encoded by RFC 3986, except the slashes.
+func canonicalPath(u *url.URL) string {
+	p := u.EscapedPath()
+	if len(p) == 0 {
+		return "/"
+	}
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery sorts the parameters by the names, and encodes them by RFC 3986.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var ps []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			ps = append(ps, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(ps, "&")
+}
+func uriEncode(s string) string {
+	const hexChars = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(hexChars[c>>4])
+			b.WriteByte(hexChars[c&15])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Inline     = "inline"
+	Attachment = "attachment"
+)
+
+var ErrInvalidKey = errors.New("invalid key")
+
+// Storage stores the files; it is the StoragePort of the upload and uploads packages, and ByteStorage adapts it to the StoragePort of the up package.
+//   - Upload stores a file at the key of Layout, and returns its url; Delete deletes a file by its key, or by its url.
+//   - Key returns the key of a file by its url, so a file, which is stored in a layout of many directories, is deleted by its url.
+type Storage interface {
+	Upload(ctx context.Context, directory string, filename string, data io.Reader, contentType string) (string, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	Key(url string) (string, bool)
+}
+
+// Signer creates the time-limited urls, to download or upload a file without the credentials.
+//   - opts of SignDownload is the Content-Disposition of the response, such as ContentDisposition(Attachment, "report.pdf").
+//   - opts of SignUpload is the content type, which the client must send.
+type Signer interface {
+	SignDownload(ctx context.Context, key string, expires time.Duration, opts ...string) (string, error)
+	SignUpload(ctx context.Context, key string, expires time.Duration, opts ...string) (string, error)
+}
+
+// Layout is the strategy of the directories of the files; it returns the key of a file, which is a slash-separated path.
+type Layout func(directory string, filename string) string
+
+// FlatLayout stores a file at directory/filename; it is the default layout.
+func FlatLayout(directory string, filename string) string {
+	return join(directory, filename)
+}
+
+// DateLayout stores a file in a directory of the date of the upload, such as directory/2024/01/31/filename.
+func DateLayout(directory string, filename string) string {
+	return join(directory, time.Now().UTC().Format("2006/01/02"), filename)
+}
+
+// HashLayout stores a file in 2 levels of directories by the hash of its name, such as directory/3f/a2/filename, so a directory does not have too many files.
+func HashLayout(directory string, filename string) string {
+	sum := sha1.Sum([]byte(filename))
+	h := hex.EncodeToString(sum[:2])
+	return join(directory, h[:2], h[2:], filename)
+}
+
+func join(elems ...string) string {
+	var ps []string
+	for _, e := range elems {
+		if e = strings.Trim(e, "/"); len(e) > 0 {
+			ps = append(ps, e)
+		}
+	}
+	return strings.Join(ps, "/")
+}
+
+// CleanKey validates a key, so it cannot be outside of the directory or the bucket.
+func CleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if len(key) == 0 || strings.Contains(key, "\\") || strings.Contains(key, "\x00") {
+		return "", ErrInvalidKey
+	}
+	for _, s := range strings.Split(key, "/") {
+		if len(s) == 0 || s == "." || s == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
+
+// ContentDisposition formats a Content-Disposition, such as `attachment; filename="report.pdf"`; a non-ASCII filename is encoded by RFC 2231.
+func ContentDisposition(disposition string, filename string) string {
+	if len(disposition) == 0 {
+		disposition = Attachment
+	}
+	if len(filename) == 0 {
+		return disposition
+	}
+	if s := mime.FormatMediaType(disposition, map[string]string{"filename": filename}); len(s) > 0 {
+		return s
+	}
+	return disposition
+}
+
+// ByteStorage adapts a Storage to the StoragePort of the up package, which uploads the files as []byte.
+type ByteStorage struct {
+	Storage Storage
+}
+
+func NewByteStorage(storage Storage) *ByteStorage {
+	return &ByteStorage{Storage: storage}
+}
+func (s *ByteStorage) Upload(ctx context.Context, directory string, filename string, data []byte, contentType string) (string, error) {
+	return s.Storage.Upload(ctx, directory, filename, bytes.NewReader(data), contentType)
+}
+func (s *ByteStorage) Delete(ctx context.Context, id string) (bool, error) {
+	return s.Storage.Delete(ctx, id)
+}
+
+// keyOf returns the key of a file by its url, which starts with the base url, or returns the id if it is not a url.
+func keyOf(base string, id string) (string, bool) {
+	if len(base) > 0 && strings.HasPrefix(id, strings.TrimSuffix(base, "/")+"/") {
+		id = id[len(strings.TrimSuffix(base, "/"))+1:]
+		if i := strings.IndexAny(id, "?#"); i >= 0 {
+			id = id[:i]
+		}
+		if key, err := url.PathUnescape(id); err == nil {
+			return key, true
+		}
+		return "", false
+	}
+	if strings.Contains(id, "://") {
+		return "", false
+	}
+	return strings.TrimPrefix(id, "/"), true
+}
+
+// escapePath escapes the segments of a key for a url.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
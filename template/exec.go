@@ -950,7 +950,9 @@ func (s *state) printValue(n Node, v reflect.Value) {
 	if !ok {
 		s.errorf("can't print %s of type %s", n, v.Type())
 	}
-	if !strings.Contains(n.String(),"SKIP ") {
+	if s.tmpl.option.print == printHTML {
+		iface = HTMLEscapeString(fmt.Sprint(iface))
+	} else if s.tmpl.option.print == printQuoted && !strings.Contains(n.String(),"SKIP ") {
 		a := v.Interface()
 		if v.Kind() == reflect.Ptr {
 			if v.IsNil() {
@@ -17,8 +17,18 @@ const (
 	mapError                             // Error out
 )
 
+// printMode defines how a value is printed.
+type printMode int
+
+const (
+	printQuoted printMode = iota // Quote strings and times, to build a query.
+	printText                    // Print the value as is.
+	printHTML                    // Escape the value for HTML.
+)
+
 type option struct {
 	missingKey missingKeyAction
+	print      printMode
 }
 
 // Option sets options for the template. Options are described by
@@ -39,6 +49,15 @@ type option struct {
 //	"missingkey=error"
 //		Execution stops immediately with an error.
 //
+// print: Control how a value is printed.
+//	"print=quoted"
+//		The default behavior: strings and times are quoted, to build a query.
+//		Values in a SKIP pipeline are printed as is.
+//	"print=text"
+//		The value is printed as is, for a plain text document.
+//	"print=html"
+//		The value is escaped for HTML.
+//
 func (t *Template) Option(opt ...string) *Template {
 	t.init()
 	for _, s := range opt {
@@ -68,6 +87,18 @@ func (t *Template) setOption(opt string) {
 				t.option.missingKey = mapError
 				return
 			}
+		case "print":
+			switch elems[1] {
+			case "quoted":
+				t.option.print = printQuoted
+				return
+			case "text":
+				t.option.print = printText
+				return
+			case "html":
+				t.option.print = printHTML
+				return
+			}
 		}
 	}
 	panic("unrecognized option: " + opt)
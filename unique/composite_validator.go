@@ -0,0 +1,164 @@
+package unique
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sv "github.com/core-go/core"
+)
+
+// CompositeValidator checks that the values of several columns, such as tenant_id and code, are unique in a table, so that a code is unique per tenant.
+//   - The columns are the gorm columns of the fields of T; the scope columns, such as tenant_id, are first, and the error is of the field of the last column.
+//   - The row of the model, by the primary keys, is excluded, so that the same validator is used to insert and to update; it is not excluded if a key is empty, as on insert.
+//   - The check is skipped if a column is nil, because null values are not equal in SQL.
+type CompositeValidator[T any] struct {
+	db             *sql.DB
+	driver         string
+	validate       func(ctx context.Context, model T) ([]sv.ErrorMessage, error)
+	tableName      string
+	columns        []string
+	indexes        []int
+	jsonFieldName  string
+	param          string
+	idColumnFields []string
+	keyIndexes     map[string]int
+}
+
+func NewCompositeValidator[T any](db *sql.DB, tableName string, columns []string, options ...func(context.Context, T) ([]sv.ErrorMessage, error)) (*CompositeValidator[T], error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("columns are required")
+	}
+	var validate func(context.Context, T) ([]sv.ErrorMessage, error)
+	if len(options) > 0 {
+		validate = options[0]
+	}
+	var t T
+	modelType := reflect.TypeOf(t)
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	keyIndexes, err := getColumnIndexes(modelType)
+	if err != nil {
+		return nil, err
+	}
+	idColumnFields, _ := findPrimaryKeys(modelType)
+	for _, id := range idColumnFields {
+		if _, ok := keyIndexes[strings.ToLower(id)]; !ok {
+			idColumnFields = nil
+			break
+		}
+	}
+	cols := make([]string, len(columns))
+	indexes := make([]int, len(columns))
+	jsonNames := make([]string, len(columns))
+	for i, column := range columns {
+		cols[i] = strings.ToLower(column)
+		index, ok := keyIndexes[cols[i]]
+		if !ok {
+			return nil, fmt.Errorf("column '%s' is not found in %s", column, modelType.Name())
+		}
+		indexes[i] = index
+		jsonNames[i] = getJsonName(modelType.Field(index))
+	}
+	return &CompositeValidator[T]{
+		db:             db,
+		driver:         getDriver(db),
+		validate:       validate,
+		tableName:      tableName,
+		columns:        cols,
+		indexes:        indexes,
+		jsonFieldName:  jsonNames[len(jsonNames)-1],
+		param:          strings.Join(jsonNames, ","),
+		idColumnFields: idColumnFields,
+		keyIndexes:     keyIndexes,
+	}, nil
+}
+func (v *CompositeValidator[T]) Validate(ctx context.Context, model T) ([]sv.ErrorMessage, error) {
+	var errs []sv.ErrorMessage
+	var err error
+	if v.validate != nil {
+		errs, err = v.validate(ctx, model)
+		if err != nil {
+			return errs, err
+		}
+	} else {
+		errs = make([]sv.ErrorMessage, 0)
+	}
+	vo := reflect.Indirect(reflect.ValueOf(model))
+	if vo.Kind() == reflect.Ptr {
+		vo = reflect.Indirect(vo)
+	}
+	values := make([]interface{}, 0, len(v.columns)+len(v.idColumnFields))
+	for _, index := range v.indexes {
+		f := vo.Field(index)
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				return errs, nil
+			}
+			f = f.Elem()
+		}
+		values = append(values, f.Interface())
+	}
+	ids := make([]string, 0, len(v.idColumnFields))
+	for _, id := range v.idColumnFields {
+		f := vo.Field(v.keyIndexes[strings.ToLower(id)])
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				break
+			}
+			f = f.Elem()
+		}
+		if f.IsZero() {
+			break
+		}
+		values = append(values, f.Interface())
+		ids = append(ids, id)
+	}
+	if len(ids) < len(v.idColumnFields) {
+		values = values[:len(v.columns)]
+		ids = nil
+	}
+	syntax := getDriverParam(v.driver, values)
+	conditions := make([]string, len(v.columns))
+	for i, column := range v.columns {
+		conditions[i] = fmt.Sprintf("%s = %s", column, syntax[i])
+	}
+	where := strings.Join(conditions, " and ")
+	if len(ids) > 0 {
+		for i, id := range ids {
+			ids[i] = fmt.Sprintf("%s = %s", id, syntax[len(v.columns)+i])
+		}
+		where = where + " and not (" + strings.Join(ids, " and ") + ")"
+	}
+	query := buildExistQuery(v.driver, v.tableName, v.columns[0], where)
+	rows, err := v.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return errs, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		errs = append(errs, sv.ErrorMessage{Field: v.jsonFieldName, Code: "duplicate", Param: v.param})
+	}
+	return errs, rows.Err()
+}
+
+func buildExistQuery(driver string, tableName string, column string, where string) string {
+	switch driver {
+	case driverMssql:
+		return fmt.Sprintf("select top 1 %s from %s where %s", column, tableName, where)
+	case driverOracle:
+		return fmt.Sprintf("select %s from %s where %s", column, tableName, where) + fmt.Sprintf(oraclePagingFormat, "0", "1")
+	default:
+		return fmt.Sprintf("select %s from %s where %s", column, tableName, where) + fmt.Sprintf(defaultPagingFormat, "1", "0")
+	}
+}
+func getJsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if len(name) == 0 || name == "-" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,117 @@
+package unique
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sv "github.com/core-go/core"
+)
+
+// ReferenceValidator checks that the value of a column of T exists in a column of another table, such as the customer_id of an order in the table customers.
+//   - If the field is a slice, such as the role ids of a user, each value must exist, and the error is of the index, such as "roles[1]".
+//   - The check is skipped if the value is nil or empty, so that it is used with the tag required of the validator.
+type ReferenceValidator[T any] struct {
+	db            *sql.DB
+	driver        string
+	validate      func(ctx context.Context, model T) ([]sv.ErrorMessage, error)
+	fieldIndex    int
+	jsonFieldName string
+	refTable      string
+	refColumn     string
+}
+
+func NewReferenceValidator[T any](db *sql.DB, columnName string, refTable string, refColumn string, options ...func(context.Context, T) ([]sv.ErrorMessage, error)) (*ReferenceValidator[T], error) {
+	var validate func(context.Context, T) ([]sv.ErrorMessage, error)
+	if len(options) > 0 {
+		validate = options[0]
+	}
+	var t T
+	modelType := reflect.TypeOf(t)
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	keyIndexes, err := getColumnIndexes(modelType)
+	if err != nil {
+		return nil, err
+	}
+	index, ok := keyIndexes[strings.ToLower(columnName)]
+	if !ok {
+		return nil, fmt.Errorf("column '%s' is not found in %s", columnName, modelType.Name())
+	}
+	return &ReferenceValidator[T]{
+		db:            db,
+		driver:        getDriver(db),
+		validate:      validate,
+		fieldIndex:    index,
+		jsonFieldName: getJsonName(modelType.Field(index)),
+		refTable:      refTable,
+		refColumn:     refColumn,
+	}, nil
+}
+func (v *ReferenceValidator[T]) Validate(ctx context.Context, model T) ([]sv.ErrorMessage, error) {
+	var errs []sv.ErrorMessage
+	var err error
+	if v.validate != nil {
+		errs, err = v.validate(ctx, model)
+		if err != nil {
+			return errs, err
+		}
+	} else {
+		errs = make([]sv.ErrorMessage, 0)
+	}
+	vo := reflect.Indirect(reflect.ValueOf(model))
+	if vo.Kind() == reflect.Ptr {
+		vo = reflect.Indirect(vo)
+	}
+	f := vo.Field(v.fieldIndex)
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return errs, nil
+		}
+		f = f.Elem()
+	}
+	var values []interface{}
+	isSlice := f.Kind() == reflect.Slice || f.Kind() == reflect.Array
+	if isSlice {
+		for i := 0; i < f.Len(); i++ {
+			values = append(values, reflect.Indirect(f.Index(i)).Interface())
+		}
+	} else if !f.IsZero() {
+		values = append(values, f.Interface())
+	}
+	if len(values) == 0 {
+		return errs, nil
+	}
+	syntax := getDriverParam(v.driver, values)
+	query := fmt.Sprintf("select %s from %s where %s in (%s)", v.refColumn, v.refTable, v.refColumn, strings.Join(syntax, ","))
+	rows, err := v.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return errs, err
+	}
+	defer rows.Close()
+	exist := make(map[string]bool)
+	for rows.Next() {
+		var s sql.NullString
+		if err = rows.Scan(&s); err != nil {
+			return errs, err
+		}
+		exist[s.String] = true
+	}
+	if err = rows.Err(); err != nil {
+		return errs, err
+	}
+	for i, value := range values {
+		if exist[fmt.Sprint(value)] {
+			continue
+		}
+		field := v.jsonFieldName
+		if isSlice {
+			field = fmt.Sprintf("%s[%d]", field, i)
+		}
+		errs = append(errs, sv.ErrorMessage{Field: field, Code: "exists", Param: v.refTable})
+	}
+	return errs, nil
+}
@@ -0,0 +1,210 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound         = errors.New("upload not found")
+	ErrOffsetConflict   = errors.New("offset does not match the offset of the upload")
+	ErrSizeExceeded     = errors.New("size exceeds the length of the upload")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrCompleted        = errors.New("upload is completed")
+)
+
+// ChunkInfo is a resumable upload: Offset is the number of the bytes received, and the upload is completed when Offset is Length.
+//   - Id is the id of the entity of the attachment, and Key is the id of the upload.
+//   - Checksum is the checksum of the file, such as "sha256 <base64>", which is verified when the upload is completed.
+type ChunkInfo struct {
+	Key              string            `yaml:"key" mapstructure:"key" json:"key,omitempty" gorm:"column:key;primary_key" bson:"_id,omitempty" dynamodbav:"key,omitempty" firestore:"-"`
+	Id               string            `yaml:"id" mapstructure:"id" json:"id,omitempty" gorm:"column:id" bson:"id,omitempty" dynamodbav:"id,omitempty" firestore:"id,omitempty"`
+	OriginalFileName string            `yaml:"original_file_name" mapstructure:"original_file_name" json:"originalFileName,omitempty" gorm:"column:original_file_name" bson:"originalFileName,omitempty" dynamodbav:"originalFileName,omitempty" firestore:"originalFileName,omitempty"`
+	FileName         string            `yaml:"file_name" mapstructure:"file_name" json:"fileName,omitempty" gorm:"column:file_name" bson:"fileName,omitempty" dynamodbav:"fileName,omitempty" firestore:"fileName,omitempty"`
+	Type             string            `yaml:"type" mapstructure:"type" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
+	Length           int64             `yaml:"length" mapstructure:"length" json:"length" gorm:"column:length" bson:"length" dynamodbav:"length" firestore:"length"`
+	Offset           int64             `yaml:"offset" mapstructure:"offset" json:"offset" gorm:"column:offset" bson:"offset" dynamodbav:"offset" firestore:"offset"`
+	Checksum         string            `yaml:"checksum" mapstructure:"checksum" json:"checksum,omitempty" gorm:"column:checksum" bson:"checksum,omitempty" dynamodbav:"checksum,omitempty" firestore:"checksum,omitempty"`
+	Metadata         map[string]string `yaml:"metadata" mapstructure:"metadata" json:"metadata,omitempty" gorm:"-" bson:"metadata,omitempty" dynamodbav:"metadata,omitempty" firestore:"metadata,omitempty"`
+	Completed        bool              `yaml:"completed" mapstructure:"completed" json:"completed,omitempty" gorm:"column:completed" bson:"completed,omitempty" dynamodbav:"completed,omitempty" firestore:"completed,omitempty"`
+	CreatedAt        time.Time         `yaml:"created_at" mapstructure:"created_at" json:"createdAt,omitempty" gorm:"column:created_at" bson:"createdAt,omitempty" dynamodbav:"createdAt,omitempty" firestore:"createdAt,omitempty"`
+	ExpiresAt        time.Time         `yaml:"expires_at" mapstructure:"expires_at" json:"expiresAt,omitempty" gorm:"column:expires_at" bson:"expiresAt,omitempty" dynamodbav:"expiresAt,omitempty" firestore:"expiresAt,omitempty"`
+}
+
+// ChunkPort is the temporary storage of the chunks of the resumable uploads, until they are completed and uploaded to StoragePort.
+//   - Append writes the bytes of r at offset, which must be the offset of the upload, and returns the new offset; the bytes after the length are not written, and ErrSizeExceeded is returned.
+//   - Reader returns the content of an upload, and Expired returns the keys of the uploads, which expire before a time.
+//   - Lock locks an upload, which is completed, and returns the function to unlock it; it returns ErrOffsetConflict if a chunk is appended or the upload is completed by another request.
+type ChunkPort interface {
+	Create(ctx context.Context, info *ChunkInfo) error
+	Load(ctx context.Context, key string) (*ChunkInfo, error)
+	Save(ctx context.Context, info *ChunkInfo) error
+	Append(ctx context.Context, key string, offset int64, r io.Reader) (int64, error)
+	Reader(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Expired(ctx context.Context, before time.Time) ([]string, error)
+	Lock(ctx context.Context, key string) (func(), error)
+}
+
+// FileChunkStore stores an upload in a directory: the content in a .bin file, and the info in a .info JSON file, which is written to a temporary file, then renamed.
+type FileChunkStore struct {
+	Directory string
+	mu        sync.Mutex
+	locks     map[string]*sync.Mutex
+}
+
+func NewFileChunkStore(directory string) (*FileChunkStore, error) {
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &FileChunkStore{Directory: directory, locks: make(map[string]*sync.Mutex)}, nil
+}
+
+func (s *FileChunkStore) Create(ctx context.Context, info *ChunkInfo) error {
+	file, err := os.OpenFile(s.path(info.Key, ".bin"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return s.Save(ctx, info)
+}
+func (s *FileChunkStore) Load(ctx context.Context, key string) (*ChunkInfo, error) {
+	data, err := os.ReadFile(s.path(key, ".info"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var info ChunkInfo
+	if err = json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+func (s *FileChunkStore) Save(ctx context.Context, info *ChunkInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	path := s.path(info.Key, ".info")
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Append writes a chunk; the chunks of an upload are written one by one, so a concurrent PATCH of the same upload gets ErrOffsetConflict.
+func (s *FileChunkStore) Append(ctx context.Context, key string, offset int64, r io.Reader) (int64, error) {
+	lock := s.lock(key)
+	if !lock.TryLock() {
+		return offset, ErrOffsetConflict
+	}
+	defer lock.Unlock()
+	info, err := s.Load(ctx, key)
+	if err != nil {
+		return offset, err
+	}
+	if info.Offset != offset {
+		return info.Offset, ErrOffsetConflict
+	}
+	file, err := os.OpenFile(s.path(key, ".bin"), os.O_WRONLY, 0644)
+	if err != nil {
+		return offset, err
+	}
+	defer file.Close()
+	// the bytes after the offset of the info are from an interrupted chunk, which was not saved
+	if err = file.Truncate(offset); err != nil {
+		return offset, err
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+	n, err := io.Copy(file, io.LimitReader(r, info.Length-offset))
+	if err == nil {
+		// a byte more than the length is an error of the client
+		var b [1]byte
+		if m, _ := r.Read(b[:]); m > 0 {
+			err = ErrSizeExceeded
+		}
+	}
+	if er1 := file.Sync(); er1 != nil && err == nil {
+		err = er1
+	}
+	// the bytes, which are written before a dropped connection, are kept, so the client resumes from the new offset
+	info.Offset = offset + n
+	if er2 := s.Save(ctx, info); er2 != nil && err == nil {
+		err = er2
+	}
+	return info.Offset, err
+}
+func (s *FileChunkStore) Reader(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(key, ".bin"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+func (s *FileChunkStore) Delete(ctx context.Context, key string) error {
+	for _, ext := range []string{".bin", ".info"} {
+		if err := os.Remove(s.path(key, ext)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	s.mu.Lock()
+	delete(s.locks, key)
+	s.mu.Unlock()
+	return nil
+}
+func (s *FileChunkStore) Expired(ctx context.Context, before time.Time) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.Directory, "*.info"))
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, file := range files {
+		key := strings.TrimSuffix(filepath.Base(file), ".info")
+		info, err := s.Load(ctx, key)
+		if err != nil {
+			continue
+		}
+		if !info.ExpiresAt.IsZero() && info.ExpiresAt.Before(before) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Lock locks an upload by the lock of Append, so an upload is not completed while a chunk is appended, nor by two requests.
+func (s *FileChunkStore) Lock(ctx context.Context, key string) (func(), error) {
+	lock := s.lock(key)
+	if !lock.TryLock() {
+		return nil, ErrOffsetConflict
+	}
+	return lock.Unlock, nil
+}
+func (s *FileChunkStore) lock(key string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[key] = lock
+	}
+	return lock
+}
+func (s *FileChunkStore) path(key string, ext string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_", "..", "_").Replace(key)
+	return filepath.Join(s.Directory, name+ext)
+}
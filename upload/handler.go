@@ -1,7 +1,6 @@
 package upload
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -52,20 +51,12 @@ type Handler struct {
 func (u *Handler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	id := GetRequiredString(w, r, u.idIndex)
 	if len(id) > 0 {
-		// Parse our multipart form, 10 << 20 specifies a maximum upload of 20 MB files.
-		err := r.ParseMultipartForm(u.maxSizeMemory)
+		// The parts are read as a stream, so a file is passed to the storage while it is received, and is not loaded into memory.
+		reader, err := r.MultipartReader()
 		if err != nil {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
 			return
 		}
-
-		files := r.MultipartForm.File[u.KeyFile]
-		_, fileHeader, _ := r.FormFile(u.KeyFile)
-
-		contentType := fileHeader.Header.Get(contentTypeHeader)
-		if len(contentType) == 0 {
-			contentType = getExt(fileHeader.Filename)
-		}
 		generateStr, err := u.generateId(r.Context())
 		if err != nil {
 			if u.LogError != nil {
@@ -76,19 +67,41 @@ func (u *Handler) UploadFile(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
-		var list []Request
-		for i, _ := range files { // loop through the files one by one
-			file, err := files[i].Open()
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
 			if err != nil {
 				http.Error(w, "Bad Request", http.StatusBadRequest)
 				return
 			}
-			defer file.Close()
-			out := bytes.NewBuffer(nil)
-
-			_, err = io.Copy(out, file)
-
+			if part.FormName() != u.KeyFile || len(part.FileName()) == 0 {
+				part.Close()
+				continue
+			}
+			fileName := part.FileName()
+			if valid, err2 := validateExtension(fileName, u.AllowedExtensions); !valid {
+				http.Error(w, err2.Error(), http.StatusBadRequest)
+				return
+			}
+			contentType := part.Header.Get(contentTypeHeader)
+			if len(contentType) == 0 {
+				contentType = getExt(fileName)
+			}
+			name := strings.Replace(generateStr+"_"+fileName, " ", "", -1)
+			data := &limitReader{Reader: part, max: u.MaxSize}
+			rs, err := u.Service.Upload(r.Context(), id, Request{fileName, name, contentType, 0, data})
+			part.Close()
+			if data.exceeded {
+				http.Error(w, fmt.Sprintf("Limit maxsize: %d bytes", u.MaxSize), http.StatusBadRequest)
+				return
+			}
 			if err != nil {
+				if code := StatusCode(err); code > 0 {
+					http.Error(w, err.Error(), code)
+					return
+				}
 				if u.LogError != nil {
 					u.LogError(r.Context(), err.Error())
 					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -97,37 +110,32 @@ func (u *Handler) UploadFile(w http.ResponseWriter, r *http.Request) {
 				}
 				return
 			}
-			bytes := out.Bytes()
-			name := generateStr + "_" + files[i].Filename
-			if valid, err2 := u.validateExtension(files[i].Filename, u.AllowedExtensions); !valid {
-				http.Error(w, err2.Error(), http.StatusBadRequest)
-				return
-			}
-			if u.MaxSize > 0 && fileHeader.Size > u.MaxSize {
-				http.Error(w, fmt.Sprintf("Limit maxsize: %d bytes", u.MaxSize), http.StatusBadRequest)
-				return
-			}
-			name = strings.Replace(name, " ", "", -1)
-			list = append(list, Request{files[i].Filename, name, contentType, fileHeader.Size, bytes})
-		}
-		if len(list) == 0 {
-			http.Error(w, "require input file", http.StatusBadRequest)
-		}
-		rs, err := u.Service.Upload(r.Context(), id, list[0])
-		if err != nil {
-			if u.LogError != nil {
-				u.LogError(r.Context(), err.Error())
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
+			respond(w, http.StatusOK, *rs)
 			return
 		}
-		respond(w, http.StatusOK, *rs)
+		http.Error(w, "require input file", http.StatusBadRequest)
+	}
+}
+
+// limitReader returns an error if a file has more than max bytes; max 0 is no limit.
+type limitReader struct {
+	io.Reader
+	max      int64
+	n        int64
+	exceeded bool
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	n, err := l.Reader.Read(p)
+	l.n += int64(n)
+	if l.max > 0 && l.n > l.max {
+		l.exceeded = true
+		return n, fmt.Errorf("limit maxsize: %d bytes", l.max)
 	}
+	return n, err
 }
 
-func (u *Handler) validateExtension(filename string, allowedExtensions string) (bool, error) {
+func validateExtension(filename string, allowedExtensions string) (bool, error) {
 	if len(allowedExtensions) == 0 {
 		return true, nil
 	}
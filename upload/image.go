@@ -0,0 +1,298 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	_ "image/gif"
+)
+
+// ImageVariant is a resized copy of an image, such as "400", which fits in 400x400, or a thumbnail, which is cropped to fill Width x Height.
+//   - If Width or Height is 0, it is not limited; an image is not enlarged.
+//   - Type is the content type of the variant, image/jpeg or image/png; it is the type of the image by default, and image/png for a gif.
+type ImageVariant struct {
+	Name   string `yaml:"name" mapstructure:"name" json:"name,omitempty" gorm:"column:name" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
+	Width  int    `yaml:"width" mapstructure:"width" json:"width,omitempty" gorm:"column:width" bson:"width,omitempty" dynamodbav:"width,omitempty" firestore:"width,omitempty"`
+	Height int    `yaml:"height" mapstructure:"height" json:"height,omitempty" gorm:"column:height" bson:"height,omitempty" dynamodbav:"height,omitempty" firestore:"height,omitempty"`
+	Crop   bool   `yaml:"crop" mapstructure:"crop" json:"crop,omitempty" gorm:"column:crop" bson:"crop,omitempty" dynamodbav:"crop,omitempty" firestore:"crop,omitempty"`
+	Type   string `yaml:"type" mapstructure:"type" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
+}
+
+// stripMetadata removes EXIF, XMP, IPTC and the text chunks from a jpeg or a png without decoding it; the color profile is kept.
+func stripMetadata(data []byte, contentType string) []byte {
+	switch contentType {
+	case "image/jpeg":
+		return stripJpeg(data)
+	case "image/png":
+		return stripPng(data)
+	}
+	return data
+}
+func stripJpeg(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return data
+		}
+		marker := data[i+1]
+		if marker == 0xD9 || marker == 0xDA {
+			// the entropy-coded data starts after SOS, and there is no metadata after it
+			return append(out, data[i:]...)
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0xFF {
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if end > len(data) {
+			return data
+		}
+		// APP1 is EXIF or XMP, APP13 is IPTC, and COM is a comment
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return data
+}
+func stripPng(data []byte) []byte {
+	if len(data) < 8 || string(data[1:4]) != "PNG" {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:8]...)
+	i := 8
+	for i+12 <= len(data) {
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:i+4]))
+		if end > len(data) || end < i {
+			return data
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// orientation returns the EXIF orientation of a jpeg, from 1 to 8, or 1 if there is no orientation.
+func orientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	i := 2
+	for i+4 <= len(data) && data[i] == 0xFF {
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if end > len(data) {
+			return 1
+		}
+		if marker == 0xE1 && end-i > 10 && string(data[i+4:i+10]) == "Exif\x00\x00" {
+			return exifOrientation(data[i+10 : end])
+		}
+		i = end
+	}
+	return 1
+}
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	n := int(order.Uint16(tiff[ifd : ifd+2]))
+	for k := 0; k < n; k++ {
+		entry := ifd + 2 + k*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			if v := int(order.Uint16(tiff[entry+8 : entry+10])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// toRGBA copies an image to an RGBA image at the origin, and rotates or flips it by the EXIF orientation.
+func toRGBA(src image.Image, orientation int) *image.RGBA {
+	b := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(img, img.Bounds(), src, b.Min, draw.Src)
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], img.Pix[sy*img.Stride+sx*4:sy*img.Stride+sx*4+4])
+		}
+	}
+	return dst
+}
+
+// resizeVariant resizes an image to fit in the size of a variant, or crops it to fill the size if Crop is true.
+func resizeVariant(img *image.RGBA, v ImageVariant) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if v.Crop && v.Width > 0 && v.Height > 0 {
+		cw, ch := w, h
+		if w*v.Height > h*v.Width {
+			cw = int(math.Round(float64(h) * float64(v.Width) / float64(v.Height)))
+		} else {
+			ch = int(math.Round(float64(w) * float64(v.Height) / float64(v.Width)))
+		}
+		x0, y0 := (w-cw)/2, (h-ch)/2
+		img = img.SubImage(image.Rect(x0, y0, x0+cw, y0+ch)).(*image.RGBA)
+		if cw <= v.Width {
+			return img
+		}
+		return resize(img, v.Width, v.Height)
+	}
+	ratio := 1.0
+	if v.Width > 0 && w > v.Width {
+		ratio = float64(v.Width) / float64(w)
+	}
+	if v.Height > 0 && h > v.Height {
+		ratio = math.Min(ratio, float64(v.Height)/float64(h))
+	}
+	if ratio >= 1 {
+		return img
+	}
+	dw, dh := int(math.Round(float64(w)*ratio)), int(math.Round(float64(h)*ratio))
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+	return resize(img, dw, dh)
+}
+
+type contribution struct {
+	index  int
+	weight float32
+}
+
+// resize scales down an image by area averaging, in a horizontal pass, then a vertical pass.
+func resize(src *image.RGBA, w int, h int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	tmp := make([]float32, w*sh*4)
+	xs := contributions(sw, w)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y):]
+		for x, cs := range xs {
+			t := tmp[(y*w+x)*4 : (y*w+x)*4+4]
+			for _, c := range cs {
+				p := row[c.index*4 : c.index*4+4]
+				t[0] += float32(p[0]) * c.weight
+				t[1] += float32(p[1]) * c.weight
+				t[2] += float32(p[2]) * c.weight
+				t[3] += float32(p[3]) * c.weight
+			}
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	ys := contributions(sh, h)
+	for y, cs := range ys {
+		for x := 0; x < w; x++ {
+			var s [4]float32
+			for _, c := range cs {
+				t := tmp[(c.index*w+x)*4 : (c.index*w+x)*4+4]
+				s[0] += t[0] * c.weight
+				s[1] += t[1] * c.weight
+				s[2] += t[2] * c.weight
+				s[3] += t[3] * c.weight
+			}
+			p := dst.Pix[y*dst.Stride+x*4 : y*dst.Stride+x*4+4]
+			for k := 0; k < 4; k++ {
+				p[k] = clamp(s[k])
+			}
+		}
+	}
+	return dst
+}
+func contributions(src int, dst int) [][]contribution {
+	scale := float64(src) / float64(dst)
+	cs := make([][]contribution, dst)
+	for d := 0; d < dst; d++ {
+		start, end := float64(d)*scale, float64(d+1)*scale
+		for i := int(start); i < src && float64(i) < end; i++ {
+			lo, hi := math.Max(start, float64(i)), math.Min(end, float64(i+1))
+			if hi > lo {
+				cs[d] = append(cs[d], contribution{index: i, weight: float32((hi - lo) / scale)})
+			}
+		}
+	}
+	return cs
+}
+func clamp(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+func encodeImage(img image.Image, contentType string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
@@ -4,25 +4,44 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"io"
 )
 
 type Request struct {
-	OriginalFileName string `yaml:"original_file_name" mapstructure:"name" json:"original_file_name,omitempty" gorm:"column:original_filename" bson:"original_filename,omitempty" dynamodbav:"original_filename,omitempty" firestore:"original_filename,omitempty"`
-	Filename         string `yaml:"filename" mapstructure:"filename" json:"filename,omitempty" gorm:"column:filename" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
-	Type             string `yaml:"name" mapstructure:"name" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
-	Size             int64  `yaml:"name" mapstructure:"name" json:"size,omitempty" gorm:"column:size" bson:"size,omitempty" dynamodbav:"size,omitempty" firestore:"size,omitempty"`
-	Data             []byte `yaml:"name" mapstructure:"name" json:"data,omitempty" gorm:"column:data" bson:"data,omitempty" dynamodbav:"data,omitempty" firestore:"data,omitempty"`
+	OriginalFileName string    `yaml:"original_file_name" mapstructure:"name" json:"original_file_name,omitempty" gorm:"column:original_filename" bson:"original_filename,omitempty" dynamodbav:"original_filename,omitempty" firestore:"original_filename,omitempty"`
+	Filename         string    `yaml:"filename" mapstructure:"filename" json:"filename,omitempty" gorm:"column:filename" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
+	Type             string    `yaml:"name" mapstructure:"name" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
+	Size             int64     `yaml:"name" mapstructure:"name" json:"size,omitempty" gorm:"column:size" bson:"size,omitempty" dynamodbav:"size,omitempty" firestore:"size,omitempty"`
+	Data             io.Reader `yaml:"-" mapstructure:"-" json:"-" gorm:"-" bson:"-" dynamodbav:"-" firestore:"-"`
 }
 
 type Upload struct {
-	OriginalFileName string `yaml:"name" mapstructure:"name" json:"originalFileName,omitempty" gorm:"column:original_filename" bson:"original_filename,omitempty" dynamodbav:"original_filename,omitempty" firestore:"original_filename,omitempty"`
-	FileName         string `yaml:"file_name" mapstructure:"file_name" json:"fileName,omitempty" gorm:"column:file_name" bson:"fileName,omitempty" dynamodbav:"fileName,omitempty" firestore:"fileName,omitempty"`
-	Url              string `yaml:"url" mapstructure:"url" json:"url,omitempty" gorm:"column:url" bson:"url,omitempty" dynamodbav:"url,omitempty" firestore:"url,omitempty" avro:"url" validate:"required"`
-	Type             string `yaml:"type" mapstructure:"type" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
-	Size             int64  `yaml:"size" mapstructure:"size" json:"size,omitempty" gorm:"column:size" bson:"size,omitempty" dynamodbav:"size,omitempty" firestore:"size,omitempty"`
+	OriginalFileName string    `yaml:"name" mapstructure:"name" json:"originalFileName,omitempty" gorm:"column:original_filename" bson:"original_filename,omitempty" dynamodbav:"original_filename,omitempty" firestore:"original_filename,omitempty"`
+	FileName         string    `yaml:"file_name" mapstructure:"file_name" json:"fileName,omitempty" gorm:"column:file_name" bson:"fileName,omitempty" dynamodbav:"fileName,omitempty" firestore:"fileName,omitempty"`
+	Url              string    `yaml:"url" mapstructure:"url" json:"url,omitempty" gorm:"column:url" bson:"url,omitempty" dynamodbav:"url,omitempty" firestore:"url,omitempty" avro:"url" validate:"required"`
+	Type             string    `yaml:"type" mapstructure:"type" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
+	Size             int64     `yaml:"size" mapstructure:"size" json:"size,omitempty" gorm:"column:size" bson:"size,omitempty" dynamodbav:"size,omitempty" firestore:"size,omitempty"`
+	Width            int       `yaml:"width" mapstructure:"width" json:"width,omitempty" gorm:"column:width" bson:"width,omitempty" dynamodbav:"width,omitempty" firestore:"width,omitempty"`
+	Height           int       `yaml:"height" mapstructure:"height" json:"height,omitempty" gorm:"column:height" bson:"height,omitempty" dynamodbav:"height,omitempty" firestore:"height,omitempty"`
+	Variants         []Variant `yaml:"variants" mapstructure:"variants" json:"variants,omitempty" gorm:"column:variants" bson:"variants,omitempty" dynamodbav:"variants,omitempty" firestore:"variants,omitempty"`
 }
 
+// Variant is a resized copy of an image, which is generated by the ImageVariant of the same Name.
+type Variant struct {
+	Name     string `yaml:"name" mapstructure:"name" json:"name,omitempty" gorm:"column:name" bson:"name,omitempty" dynamodbav:"name,omitempty" firestore:"name,omitempty"`
+	FileName string `yaml:"file_name" mapstructure:"file_name" json:"fileName,omitempty" gorm:"column:file_name" bson:"fileName,omitempty" dynamodbav:"fileName,omitempty" firestore:"fileName,omitempty"`
+	Url      string `yaml:"url" mapstructure:"url" json:"url,omitempty" gorm:"column:url" bson:"url,omitempty" dynamodbav:"url,omitempty" firestore:"url,omitempty"`
+	Type     string `yaml:"type" mapstructure:"type" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
+	Size     int64  `yaml:"size" mapstructure:"size" json:"size,omitempty" gorm:"column:size" bson:"size,omitempty" dynamodbav:"size,omitempty" firestore:"size,omitempty"`
+	Width    int    `yaml:"width" mapstructure:"width" json:"width,omitempty" gorm:"column:width" bson:"width,omitempty" dynamodbav:"width,omitempty" firestore:"width,omitempty"`
+	Height   int    `yaml:"height" mapstructure:"height" json:"height,omitempty" gorm:"column:height" bson:"height,omitempty" dynamodbav:"height,omitempty" firestore:"height,omitempty"`
+}
+
+// Value stores an Upload as JSON, or NULL if it has no url, so the column of a deleted file is NULL.
 func (u Upload) Value() (driver.Value, error) {
+	if len(u.Url) == 0 {
+		return nil, nil
+	}
 	return json.Marshal(u)
 }
 func (u *Upload) Scan(value interface{}) error {
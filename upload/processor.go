@@ -0,0 +1,278 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	ErrTypeNotAllowed = errors.New("content type is not allowed")
+	ErrFileTooLarge   = errors.New("file is too large")
+	ErrInvalidImage   = errors.New("invalid image")
+)
+
+// ProcessConfig is the config of the processing of the uploads of a resource, such as an avatar or a document.
+//   - AllowedTypes are the content types, which are allowed, such as "image/*" or "application/pdf"; the type is detected by the content, not by the name or the Content-Type of the request.
+//   - MaxSize is the max size of a file, and MaxSizes is the max size by the content type, such as "image/*": 5242880.
+//   - MaxPixels is the max width x height of an image, which is decoded, to reject the decompression bombs; it is 50 megapixels by default.
+//   - StripMetadata removes EXIF, XMP and IPTC from the images, so the location, where a photo is taken, is not published; Variants are the resized copies of the images.
+type ProcessConfig struct {
+	AllowedTypes  []string         `yaml:"allowed_types" mapstructure:"allowed_types" json:"allowedTypes,omitempty" gorm:"column:allowedtypes" bson:"allowedTypes,omitempty" dynamodbav:"allowedTypes,omitempty" firestore:"allowedTypes,omitempty"`
+	MaxSize       int64            `yaml:"max_size" mapstructure:"max_size" json:"maxSize,omitempty" gorm:"column:maxsize" bson:"maxSize,omitempty" dynamodbav:"maxSize,omitempty" firestore:"maxSize,omitempty"`
+	MaxSizes      map[string]int64 `yaml:"max_sizes" mapstructure:"max_sizes" json:"maxSizes,omitempty" gorm:"column:maxsizes" bson:"maxSizes,omitempty" dynamodbav:"maxSizes,omitempty" firestore:"maxSizes,omitempty"`
+	MaxPixels     int64            `yaml:"max_pixels" mapstructure:"max_pixels" json:"maxPixels,omitempty" gorm:"column:maxpixels" bson:"maxPixels,omitempty" dynamodbav:"maxPixels,omitempty" firestore:"maxPixels,omitempty"`
+	StripMetadata bool             `yaml:"strip_metadata" mapstructure:"strip_metadata" json:"stripMetadata,omitempty" gorm:"column:stripmetadata" bson:"stripMetadata,omitempty" dynamodbav:"stripMetadata,omitempty" firestore:"stripMetadata,omitempty"`
+	Quality       int              `yaml:"quality" mapstructure:"quality" json:"quality,omitempty" gorm:"column:quality" bson:"quality,omitempty" dynamodbav:"quality,omitempty" firestore:"quality,omitempty"`
+	Variants      []ImageVariant   `yaml:"variants" mapstructure:"variants" json:"variants,omitempty" gorm:"column:variants" bson:"variants,omitempty" dynamodbav:"variants,omitempty" firestore:"variants,omitempty"`
+}
+
+// Content is a processed file, or a variant of an image, which has the Name of the variant.
+type Content struct {
+	Name   string
+	Type   string
+	Size   int64
+	Width  int
+	Height int
+	Data   io.Reader
+}
+
+// Processed is the result of Process; Close removes the temporary file.
+type Processed struct {
+	Content
+	Variants []Content
+	file     *os.File
+}
+
+func (p *Processed) Close() error {
+	if p.file == nil {
+		return nil
+	}
+	p.file.Close()
+	return os.Remove(p.file.Name())
+}
+
+// Processor validates and processes the files before they are stored:
+//   - A file is spooled to a temporary file in TempDir, and the spooling stops when the file is more than the max size.
+//   - The content type is detected by the magic number, and checked by AllowedTypes and MaxSizes.
+//   - The file is scanned by Scanner, if it is set.
+//   - A jpeg, png or gif is rotated by its EXIF orientation, its metadata is removed, and the variants are generated.
+type Processor struct {
+	Config  ProcessConfig
+	Scanner Scanner
+	TempDir string
+}
+
+// NewProcessor creates a processor; opts is the scanner, such as a ClamScanner.
+func NewProcessor(config ProcessConfig, opts ...Scanner) *Processor {
+	if config.MaxPixels <= 0 {
+		config.MaxPixels = 50000000
+	}
+	if config.Quality <= 0 {
+		config.Quality = 85
+	}
+	p := &Processor{Config: config}
+	if len(opts) > 0 {
+		p.Scanner = opts[0]
+	}
+	return p
+}
+
+func (p *Processor) Process(ctx context.Context, req Request) (*Processed, error) {
+	file, err := os.CreateTemp(p.TempDir, "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	rs := &Processed{file: file}
+	if err = p.process(ctx, req, rs); err != nil {
+		rs.Close()
+		return nil, err
+	}
+	return rs, nil
+}
+func (p *Processor) process(ctx context.Context, req Request, rs *Processed) error {
+	limit := p.maxSize()
+	src := req.Data
+	if limit > 0 {
+		src = io.LimitReader(src, limit+1)
+	}
+	size, err := io.Copy(rs.file, src)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && size > limit {
+		return ErrFileTooLarge
+	}
+	header := make([]byte, 512)
+	n, err := rs.file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	contentType := DetectContentType(header[:n])
+	if !MatchType(contentType, p.Config.AllowedTypes) {
+		return fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+	if max := p.maxSizeOf(contentType); max > 0 && size > max {
+		return ErrFileTooLarge
+	}
+	if p.Scanner != nil {
+		if err = p.Scanner.Scan(ctx, io.NewSectionReader(rs.file, 0, size)); err != nil {
+			return err
+		}
+	}
+	rs.Type, rs.Size, rs.Data = contentType, size, io.NewSectionReader(rs.file, 0, size)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return p.processImage(rs)
+	}
+	return nil
+}
+
+// processImage loads an image into memory, because it is decoded; its size is limited by the max size and MaxPixels.
+func (p *Processor) processImage(rs *Processed) error {
+	if !p.Config.StripMetadata && len(p.Config.Variants) == 0 {
+		return nil
+	}
+	data := make([]byte, rs.Size)
+	if _, err := rs.file.ReadAt(data, 0); err != nil && err != io.EOF {
+		return err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidImage, err.Error())
+	}
+	if int64(config.Width)*int64(config.Height) > p.Config.MaxPixels {
+		return fmt.Errorf("%w: %dx%d pixels", ErrFileTooLarge, config.Width, config.Height)
+	}
+	rs.Width, rs.Height = config.Width, config.Height
+	o := 1
+	if rs.Type == "image/jpeg" {
+		o = orientation(data)
+	}
+	var img *image.RGBA
+	if o != 1 || len(p.Config.Variants) > 0 {
+		src, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidImage, err.Error())
+		}
+		img = toRGBA(src, o)
+	}
+	if p.Config.StripMetadata {
+		if o != 1 {
+			// the metadata is not kept by the encoder, and the orientation is applied to the pixels
+			if data, err = encodeImage(img, rs.Type, p.Config.Quality); err != nil {
+				return err
+			}
+			rs.Width, rs.Height = img.Rect.Dx(), img.Rect.Dy()
+		} else {
+			data = stripMetadata(data, rs.Type)
+		}
+		rs.Size, rs.Data = int64(len(data)), bytes.NewReader(data)
+	}
+	for _, v := range p.Config.Variants {
+		contentType := v.Type
+		if len(contentType) == 0 {
+			contentType = rs.Type
+			if contentType == "image/gif" {
+				contentType = "image/png"
+			}
+		}
+		resized := resizeVariant(img, v)
+		b, err := encodeImage(resized, contentType, p.Config.Quality)
+		if err != nil {
+			return err
+		}
+		rs.Variants = append(rs.Variants, Content{Name: v.Name, Type: contentType, Size: int64(len(b)),
+			Width: resized.Rect.Dx(), Height: resized.Rect.Dy(), Data: bytes.NewReader(b)})
+	}
+	return nil
+}
+
+// maxSize is the max size to spool a file, before its type is detected.
+func (p *Processor) maxSize() int64 {
+	if p.Config.MaxSize <= 0 {
+		return 0
+	}
+	max := p.Config.MaxSize
+	for _, size := range p.Config.MaxSizes {
+		if size > max {
+			max = size
+		}
+	}
+	return max
+}
+func (p *Processor) maxSizeOf(contentType string) int64 {
+	if size, ok := p.Config.MaxSizes[contentType]; ok {
+		return size
+	}
+	if i := strings.Index(contentType, "/"); i > 0 {
+		if size, ok := p.Config.MaxSizes[contentType[:i]+"/*"]; ok {
+			return size
+		}
+	}
+	return p.Config.MaxSize
+}
+
+var signatures = []struct {
+	offset int
+	magic  string
+	mime   string
+}{
+	{0, "II*\x00", "image/tiff"},
+	{0, "MM\x00*", "image/tiff"},
+	{4, "ftypheic", "image/heic"},
+	{4, "ftypheix", "image/heic"},
+	{4, "ftypmif1", "image/heif"},
+	{4, "ftypavif", "image/avif"},
+	{0, "7z\xBC\xAF\x27\x1C", "application/x-7z-compressed"},
+	{0, "\x1F\x8B", "application/gzip"},
+	{0, "MZ", "application/x-msdownload"},
+	{0, "\x7FELF", "application/x-executable"},
+}
+
+// DetectContentType detects the content type by the magic number of the first 512 bytes, like http.DetectContentType, with some types, which it does not detect, such as tiff and heic; the parameters, such as charset, are removed.
+func DetectContentType(header []byte) string {
+	for _, s := range signatures {
+		if len(header) >= s.offset+len(s.magic) && string(header[s.offset:s.offset+len(s.magic)]) == s.magic {
+			return s.mime
+		}
+	}
+	contentType := http.DetectContentType(header)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType
+}
+
+// MatchType checks if a content type is in a list, which can have the wildcards, such as "image/*"; an empty list allows all types.
+func MatchType(contentType string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == "*/*" || t == contentType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusCode returns the http status of an error of Process, or 0 if it is not an error of the content of a file.
+func StatusCode(err error) int {
+	var infected *InfectedError
+	switch {
+	case errors.Is(err, ErrTypeNotAllowed):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrInvalidImage), errors.As(err, &infected):
+		return http.StatusUnprocessableEntity
+	}
+	return 0
+}
@@ -0,0 +1,456 @@
+package upload
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/core-go/core"
+)
+
+const (
+	TusVersion         = "1.0.0"
+	offsetContentType  = "application/offset+octet-stream"
+	statusChecksumFail = 460
+)
+
+// ResumableHandler handles the resumable uploads by the tus protocol 1.0.0, with the extensions creation, expiration, checksum and termination:
+//   - POST creates an upload of Upload-Length bytes for the entity of the id of the path, and returns the Location of the upload; the metadata filename, filetype and checksum are supported, and checksum is the checksum of the file, such as "sha256 <base64>".
+//   - PATCH appends a chunk at Upload-Offset, and HEAD returns the offset, so a client resumes an upload after a dropped connection; a chunk with Upload-Checksum is verified.
+//   - When the last chunk is received, the checksum of the file is verified, and the file is passed to Complete, which uploads it to the storage, such as UploadService.Upload.
+//   - The chunks are kept in Store until the upload is completed; Expire deletes the uploads, which are not completed before Expiration.
+type ResumableHandler struct {
+	Store             ChunkPort
+	Complete          func(ctx context.Context, id string, req Request) (interface{}, error)
+	LogError          func(context.Context, string, ...map[string]interface{})
+	AllowedExtensions string
+	MaxSize           int64
+	Expiration        time.Duration
+	generateId        func(ctx context.Context) (string, error)
+	idIndex           int
+}
+
+// NewResumableHandler creates a handler, which completes the uploads by service.Upload.
+func NewResumableHandler(service UploadService, store ChunkPort, logError func(context.Context, string, ...map[string]interface{}),
+	generate func(ctx context.Context) (string, error), config FileConfig, opts ...int,
+) *ResumableHandler {
+	return NewResumable(func(ctx context.Context, id string, req Request) (interface{}, error) {
+		return service.Upload(ctx, id, req)
+	}, store, logError, generate, config, opts...)
+}
+
+// NewResumable creates a handler with a function to complete the uploads; opts are the index of the id in the path, like NewHandler, and the expiration in seconds, which is 24 hours by default.
+func NewResumable(complete func(ctx context.Context, id string, req Request) (interface{}, error), store ChunkPort, logError func(context.Context, string, ...map[string]interface{}),
+	generate func(ctx context.Context) (string, error), config FileConfig, opts ...int,
+) *ResumableHandler {
+	idIndex := 1
+	if len(opts) > 0 && opts[0] >= 0 {
+		idIndex = opts[0]
+	}
+	expiration := 24 * time.Hour
+	if len(opts) > 1 && opts[1] > 0 {
+		expiration = time.Duration(opts[1]) * time.Second
+	}
+	return &ResumableHandler{Store: store, Complete: complete, LogError: logError, AllowedExtensions: config.AllowedExtensions, MaxSize: config.MaxSize,
+		Expiration: expiration, generateId: generate, idIndex: idIndex}
+}
+
+// ServeHTTP dispatches a request by the method, or by X-HTTP-Method-Override, for the clients which cannot send PATCH.
+func (h *ResumableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.Method
+	if override := r.Header.Get("X-HTTP-Method-Override"); len(override) > 0 && method == http.MethodPost {
+		method = strings.ToUpper(override)
+	}
+	switch method {
+	case http.MethodOptions:
+		h.Options(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	case http.MethodHead:
+		h.Head(w, r)
+	case http.MethodPatch:
+		h.Patch(w, r)
+	case http.MethodDelete:
+		h.Terminate(w, r)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *ResumableHandler) Options(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Tus-Resumable", TusVersion)
+	header.Set("Tus-Version", TusVersion)
+	header.Set("Tus-Extension", "creation,expiration,checksum,termination")
+	header.Set("Tus-Checksum-Algorithm", "md5,sha1,sha256")
+	if h.MaxSize > 0 {
+		header.Set("Tus-Max-Size", strconv.FormatInt(h.MaxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ResumableHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	id := GetRequiredString(w, r, h.idIndex)
+	if len(id) == 0 {
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "Upload-Length is required", http.StatusBadRequest)
+		return
+	}
+	if h.MaxSize > 0 && length > h.MaxSize {
+		http.Error(w, fmt.Sprintf("Limit maxsize: %d bytes", h.MaxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := ParseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, "invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+	fileName := metadata["filename"]
+	if len(fileName) == 0 {
+		fileName = metadata["name"]
+	}
+	if len(fileName) == 0 {
+		http.Error(w, "filename is required in Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+	if len(h.AllowedExtensions) > 0 {
+		if valid, err := validateExtension(fileName, h.AllowedExtensions); !valid {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	checksum := metadata["checksum"]
+	if len(checksum) > 0 {
+		if _, _, err = parseChecksum(checksum); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	contentType := metadata["filetype"]
+	if len(contentType) == 0 {
+		contentType = getExt(fileName)
+	}
+	prefix, err := h.generateId(r.Context())
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	key, err := newKey()
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	now := time.Now()
+	info := &ChunkInfo{Key: key, Id: id, OriginalFileName: fileName, FileName: strings.Replace(prefix+"_"+fileName, " ", "", -1), Type: contentType,
+		Length: length, Checksum: checksum, Metadata: metadata, CreatedAt: now, ExpiresAt: now.Add(h.Expiration)}
+	if err = h.Store.Create(r.Context(), info); err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+key)
+	w.Header().Set("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *ResumableHandler) Head(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+	info, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(info.Length, 10))
+	w.Header().Set("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Patch appends a chunk; if the upload is completed by the chunk, the result of Complete is returned. A PATCH at the end of an upload, which failed to complete, completes it again.
+// A PATCH of an upload, which is completed, or which is being completed by another request, gets 409.
+func (h *ResumableHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	if r.Header.Get(contentTypeHeader) != offsetContentType {
+		http.Error(w, "Content-Type must be "+offsetContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Upload-Offset is required", http.StatusBadRequest)
+		return
+	}
+	info, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if info.Completed {
+		http.Error(w, ErrCompleted.Error(), http.StatusConflict)
+		return
+	}
+	if info.Offset != offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+		http.Error(w, ErrOffsetConflict.Error(), http.StatusConflict)
+		return
+	}
+	if offset < info.Length {
+		var body io.Reader = r.Body
+		var chunkHash hash.Hash
+		var expected []byte
+		if checksum := r.Header.Get("Upload-Checksum"); len(checksum) > 0 {
+			chunkHash, expected, err = parseChecksum(checksum)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = io.TeeReader(r.Body, chunkHash)
+		}
+		newOffset, err := h.Store.Append(r.Context(), info.Key, offset, body)
+		if err != nil {
+			w.Header().Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+			switch {
+			case errors.Is(err, ErrOffsetConflict):
+				http.Error(w, err.Error(), http.StatusConflict)
+			case errors.Is(err, ErrSizeExceeded):
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			default:
+				// the client resumes from the offset of HEAD, such as after a dropped connection
+				h.error(w, r, err)
+			}
+			return
+		}
+		if chunkHash != nil && !equal(chunkHash.Sum(nil), expected) {
+			// the chunk is discarded, so the client sends it again
+			info.Offset = offset
+			if err = h.Store.Save(r.Context(), info); err != nil {
+				h.error(w, r, err)
+				return
+			}
+			w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+			http.Error(w, ErrChecksumMismatch.Error(), statusChecksumFail)
+			return
+		}
+		info.Offset = newOffset
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	if info.Offset < info.Length {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	result, err := h.complete(r.Context(), info.Key)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrChecksumMismatch):
+			http.Error(w, err.Error(), statusChecksumFail)
+			return
+		case errors.Is(err, ErrOffsetConflict), errors.Is(err, ErrCompleted):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, ErrNotFound):
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		if code := StatusCode(err); code > 0 {
+			http.Error(w, err.Error(), code)
+			return
+		}
+		h.error(w, r, err)
+		return
+	}
+	core.JSON(w, http.StatusOK, result)
+}
+
+func (h *ResumableHandler) Terminate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	info, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Store.Delete(r.Context(), info.Key); err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Expire deletes the uploads, which are not completed before the expiration, and returns the number of the deleted uploads; it can be called by a scheduler.
+func (h *ResumableHandler) Expire(ctx context.Context) (int, error) {
+	keys, err := h.Store.Expired(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for i, key := range keys {
+		if err = h.Store.Delete(ctx, key); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
+
+// complete verifies the checksum of the file, and passes it to Complete; the chunks are deleted, if it is completed. If the checksum does not match, the upload is deleted, because the client must upload the file again.
+// The upload is locked, and is saved as completed before Complete is called, so it is not completed twice, even if the chunks cannot be deleted.
+func (h *ResumableHandler) complete(ctx context.Context, key string) (interface{}, error) {
+	unlock, err := h.Store.Lock(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	// the upload is loaded again under the lock, because another request may have completed it
+	info, err := h.Store.Load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if info.Completed {
+		return nil, ErrCompleted
+	}
+	if info.Offset < info.Length {
+		return nil, ErrOffsetConflict
+	}
+	if len(info.Checksum) > 0 {
+		fileHash, expected, err := parseChecksum(info.Checksum)
+		if err != nil {
+			return nil, err
+		}
+		reader, err := h.Store.Reader(ctx, info.Key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(fileHash, reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		if !equal(fileHash.Sum(nil), expected) {
+			if err = h.Store.Delete(ctx, info.Key); err != nil {
+				return nil, err
+			}
+			return nil, ErrChecksumMismatch
+		}
+	}
+	reader, err := h.Store.Reader(ctx, info.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	info.Completed = true
+	if err = h.Store.Save(ctx, info); err != nil {
+		return nil, err
+	}
+	result, err := h.Complete(ctx, info.Id, Request{OriginalFileName: info.OriginalFileName, Filename: info.FileName, Type: info.Type, Size: info.Length, Data: reader})
+	if err != nil {
+		// a PATCH at the end of the upload completes it again
+		info.Completed = false
+		if er2 := h.Store.Save(ctx, info); er2 != nil && h.LogError != nil {
+			h.LogError(ctx, er2.Error())
+		}
+		return nil, err
+	}
+	if err = h.Store.Delete(ctx, info.Key); err != nil && h.LogError != nil {
+		h.LogError(ctx, err.Error())
+	}
+	return result, nil
+}
+
+// load loads the upload of the key of the path, and writes 404 if it is not found, or 410 if it is expired.
+func (h *ResumableHandler) load(w http.ResponseWriter, r *http.Request) (*ChunkInfo, bool) {
+	key := GetString(r)
+	info, err := h.Store.Load(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+		} else {
+			h.error(w, r, err)
+		}
+		return nil, false
+	}
+	if !info.ExpiresAt.IsZero() && info.ExpiresAt.Before(time.Now()) {
+		h.Store.Delete(r.Context(), key)
+		http.Error(w, "Gone", http.StatusGone)
+		return nil, false
+	}
+	return info, true
+}
+func (h *ResumableHandler) error(w http.ResponseWriter, r *http.Request, err error) {
+	if h.LogError != nil {
+		h.LogError(r.Context(), err.Error())
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ParseMetadata parses Upload-Metadata of tus, which is a list of the keys and the base64 values, separated by commas.
+func ParseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 {
+			continue
+		}
+		parts := strings.SplitN(pair, " ", 2)
+		if len(parts) == 1 {
+			metadata[parts[0]] = ""
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		metadata[parts[0]] = string(value)
+	}
+	return metadata, nil
+}
+
+// parseChecksum parses a checksum, such as "sha256 <base64>"; the value can be base64 or hex.
+func parseChecksum(checksum string) (hash.Hash, []byte, error) {
+	parts := strings.SplitN(strings.TrimSpace(checksum), " ", 2)
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("invalid checksum '%s'", checksum)
+	}
+	var h hash.Hash
+	switch strings.ToLower(parts[0]) {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return nil, nil, fmt.Errorf("checksum algorithm '%s' is not supported", parts[0])
+	}
+	value := strings.TrimSpace(parts[1])
+	if len(value) == 2*h.Size() {
+		if sum, err := hex.DecodeString(value); err == nil {
+			return h, sum, nil
+		}
+	}
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) != h.Size() {
+		return nil, nil, fmt.Errorf("invalid checksum '%s'", checksum)
+	}
+	return h, sum, nil
+}
+func equal(a []byte, b []byte) bool {
+	return hex.EncodeToString(a) == hex.EncodeToString(b)
+}
+func newKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
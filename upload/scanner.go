@@ -0,0 +1,127 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Scanner scans the content of a file, such as by an antivirus; Scan returns an InfectedError if the content is dangerous.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// ScanFunc adapts a function to a Scanner, such as a hook, which calls an external service.
+type ScanFunc func(ctx context.Context, r io.Reader) error
+
+func (f ScanFunc) Scan(ctx context.Context, r io.Reader) error {
+	return f(ctx, r)
+}
+
+type InfectedError struct {
+	Signature string
+}
+
+func (e *InfectedError) Error() string {
+	return "file is infected: " + e.Signature
+}
+
+// ClamScanner scans by the INSTREAM command of the protocol of clamd, on a unix socket, such as /var/run/clamav/clamd.ctl, or a tcp address, such as localhost:3310.
+type ClamScanner struct {
+	Network   string
+	Address   string
+	Timeout   time.Duration
+	ChunkSize int
+}
+
+// NewClamScanner creates a scanner; opts is the network, which is unix by default, or tcp if the address has a port.
+func NewClamScanner(address string, opts ...string) *ClamScanner {
+	network := "unix"
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		network = opts[0]
+	} else if _, _, err := net.SplitHostPort(address); err == nil {
+		network = "tcp"
+	}
+	return &ClamScanner{Network: network, Address: address, Timeout: time.Minute, ChunkSize: 64 * 1024}
+}
+
+func (s *ClamScanner) Scan(ctx context.Context, r io.Reader) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if s.Timeout > 0 && (!ok || time.Now().Add(s.Timeout).Before(deadline)) {
+		deadline = time.Now().Add(s.Timeout)
+	}
+	if !deadline.IsZero() {
+		conn.SetDeadline(deadline)
+	}
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+	size := s.ChunkSize
+	if size <= 0 {
+		size = 64 * 1024
+	}
+	// a chunk is the length in 4 bytes in network byte order, then the bytes; a chunk of length 0 ends the stream
+	buf := make([]byte, 4+size)
+	for {
+		n, er1 := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err = conn.Write(buf[:4+n]); err != nil {
+				// clamd closes the connection when the stream is more than its StreamMaxLength, and replies the reason
+				if reply, er2 := readReply(conn); er2 == nil {
+					return parseReply(reply)
+				}
+				return err
+			}
+		}
+		if er1 == io.EOF || er1 == io.ErrUnexpectedEOF {
+			break
+		}
+		if er1 != nil {
+			return er1
+		}
+	}
+	if _, err = conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return err
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	return parseReply(reply)
+}
+
+func readReply(r io.Reader) (string, error) {
+	reply, err := bufio.NewReader(r).ReadString(0)
+	if err != nil && (err != io.EOF || len(reply) == 0) {
+		return "", err
+	}
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// parseReply parses a reply, such as "stream: OK", "stream: Eicar-Signature FOUND" or "INSTREAM size limit exceeded. ERROR".
+func parseReply(reply string) error {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &InfectedError{Signature: strings.TrimSuffix(reply, " FOUND")}
+	case strings.HasSuffix(reply, " ERROR"):
+		return errors.New("clamd: " + strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return fmt.Errorf("clamd: unexpected reply '%s'", reply)
+	}
+}
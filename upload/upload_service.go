@@ -3,8 +3,10 @@ package upload
 import (
 	"context"
 	"errors"
+	"io"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type UploadService interface {
@@ -12,11 +14,17 @@ type UploadService interface {
 	Delete(ctx context.Context, id string, url string) (int64, error)
 }
 
+// StoragePort stores the files; Upload reads data until io.EOF, so a file is streamed, and is not loaded into memory.
 type StoragePort interface {
-	Upload(ctx context.Context, directory string, filename string, data []byte, contentType string) (string, error)
+	Upload(ctx context.Context, directory string, filename string, data io.Reader, contentType string) (string, error)
 	Delete(ctx context.Context, id string) (bool, error)
 }
 
+// KeyResolver is implemented by a StoragePort, which gets the id of a file by its url, such as a storage, which stores the files in many directories; otherwise, the id is the last 2 segments of the url.
+type KeyResolver interface {
+	Key(url string) (string, bool)
+}
+
 type UploadUseCase struct {
 	repository       StorageRepository
 	Service          StoragePort
@@ -24,15 +32,21 @@ type UploadUseCase struct {
 	GeneralDirectory string
 	Directory        string
 	KeyFile          string
+	Processor        *Processor
 }
 
+// NewUploadService creates a service; opts is the processor, which validates the files, and generates the variants of the images, before they are stored.
 func NewUploadService(
 	repository StorageRepository,
 	service StoragePort, provider string, generalDirectory string,
-	keyFile string, directory string) UploadService {
+	keyFile string, directory string, opts ...*Processor) UploadService {
 
+	var processor *Processor
+	if len(opts) > 0 {
+		processor = opts[0]
+	}
 	return &UploadUseCase{Service: service, Provider: provider, GeneralDirectory: generalDirectory,
-		KeyFile: keyFile, Directory: directory, repository: repository}
+		KeyFile: keyFile, Directory: directory, repository: repository, Processor: processor}
 }
 
 func (u *UploadUseCase) Upload(ctx context.Context, id string, req Request) (*Upload, error) {
@@ -40,10 +54,23 @@ func (u *UploadUseCase) Upload(ctx context.Context, id string, req Request) (*Up
 	if err != nil {
 		return nil, err
 	}
-	url, err := u.uploadFileOnServer(ctx, req.Filename, req.Type, req.Size, req.Data)
+	var processed *Processed
+	if u.Processor != nil {
+		processed, err = u.Processor.Process(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		defer processed.Close()
+		req.Type, req.Size, req.Data = processed.Type, processed.Size, processed.Data
+	}
+	data := &counter{Reader: req.Data}
+	url, err := u.uploadFileOnServer(ctx, req.Filename, req.Type, req.Size, data)
 	if err != nil {
 		return nil, err
 	}
+	if req.Size <= 0 {
+		req.Size = data.n
+	}
 	attachment := Upload{
 		OriginalFileName: req.OriginalFileName,
 		FileName:         req.Filename,
@@ -51,16 +78,40 @@ func (u *UploadUseCase) Upload(ctx context.Context, id string, req Request) (*Up
 		Size:             req.Size,
 		Url:              url,
 	}
+	if processed != nil {
+		attachment.Width, attachment.Height = processed.Width, processed.Height
+		for _, v := range processed.Variants {
+			fileName := removeExt(req.Filename) + "_" + v.Name + extOf(v.Type, req.Filename)
+			vurl, err := u.uploadFileOnServer(ctx, fileName, v.Type, v.Size, v.Data)
+			if err != nil {
+				u.cleanup(attachment)
+				return nil, err
+			}
+			attachment.Variants = append(attachment.Variants, Variant{Name: v.Name, FileName: fileName, Url: vurl, Type: v.Type, Size: v.Size, Width: v.Width, Height: v.Height})
+		}
+	}
 	rows, err := u.repository.Update(ctx, id, attachment)
 	if err != nil {
+		u.cleanup(attachment)
 		return nil, err
 	}
 	if rows > 0 {
 		return &attachment, nil
 	}
+	u.cleanup(attachment)
 	return nil, nil
 }
 
+// cleanup deletes the stored files of an upload, which is not saved, so they are not left in the storage; it does not use the context of the request, which may be canceled.
+func (u *UploadUseCase) cleanup(attachment Upload) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	u.deleteFile(attachment.Url, ctx)
+	for _, v := range attachment.Variants {
+		u.deleteFile(v.Url, ctx)
+	}
+}
+
 func (u *UploadUseCase) Delete(ctx context.Context, id string, url string) (int64, error) {
 	attachment, err := u.repository.Load(ctx, id)
 	if err != nil {
@@ -69,25 +120,25 @@ func (u *UploadUseCase) Delete(ctx context.Context, id string, url string) (int6
 	if attachment == nil {
 		return -1, errors.New("not found item")
 	}
-	exist := false
-	if attachment.Url == url {
-		_, err2 := u.deleteFile(url, ctx)
-		if err2 != nil {
+	if attachment.Url != url {
+		return -1, errors.New("no exist file " + url)
+	}
+	// the files are deleted before the row is cleared, so a file is not left in the storage without a row, if a deletion fails; the delete can be retried
+	for _, v := range attachment.Variants {
+		if _, err2 := u.deleteFile(v.Url, ctx); err2 != nil {
 			return 0, err2
 		}
-		exist = true
 	}
-	if exist == false {
-		return -1, errors.New("no exist file " + url)
+	if _, err2 := u.deleteFile(url, ctx); err2 != nil {
+		return 0, err2
 	}
-	_, err2 := u.repository.Update(ctx, id, *attachment)
-	if err2 != nil {
+	if _, err2 := u.repository.Update(ctx, id, Upload{}); err2 != nil {
 		return 0, err2
 	}
 	return 1, nil
 }
 
-func (u *UploadUseCase) uploadFileOnServer(ctx context.Context, fileName string, contentType string, size int64, data []byte) (rs string, errorRespone error) {
+func (u *UploadUseCase) uploadFileOnServer(ctx context.Context, fileName string, contentType string, size int64, data io.Reader) (rs string, errorRespone error) {
 	directory := u.Directory
 	rs, err2 := u.Service.Upload(ctx, directory, fileName, data, contentType)
 	if err2 != nil {
@@ -97,12 +148,29 @@ func (u *UploadUseCase) uploadFileOnServer(ctx context.Context, fileName string,
 }
 
 func (u *UploadUseCase) deleteFile(url string, ctx context.Context) (bool, error) {
+	if resolver, ok := u.Service.(KeyResolver); ok {
+		if key, ok := resolver.Key(url); ok {
+			return u.Service.Delete(ctx, key)
+		}
+	}
 	arrOrigin := strings.Split(url, "/")
 	delOriginUrl := arrOrigin[len(arrOrigin)-2] + "/" + arrOrigin[len(arrOrigin)-1]
 	rs, err := u.Service.Delete(ctx, delOriginUrl)
 	return rs, err
 }
 
+// counter counts the bytes of a file, which is streamed, so the size is known after the upload.
+type counter struct {
+	io.Reader
+	n int64
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 func getExt(file string) string {
 	ext := filepath.Ext(file)
 	if strings.HasPrefix(ext, ":") {
@@ -111,3 +179,21 @@ func getExt(file string) string {
 	}
 	return ext
 }
+
+func removeExt(file string) string {
+	return file[:len(file)-len(filepath.Ext(file))]
+}
+
+// extOf is the extension of a variant, which is the extension of the file, if the variant has the same type.
+func extOf(contentType string, file string) string {
+	switch contentType {
+	case "image/jpeg":
+		if ext := strings.ToLower(filepath.Ext(file)); ext == ".jpg" || ext == ".jpeg" {
+			return filepath.Ext(file)
+		}
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	}
+	return filepath.Ext(file)
+}
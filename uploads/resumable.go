@@ -0,0 +1,16 @@
+package uploads
+
+import (
+	"context"
+
+	"github.com/core-go/core/upload"
+)
+
+// NewResumableHandler creates a handler of the resumable uploads of the tus protocol, which adds the completed files to the attachments by service.Upload; see upload.ResumableHandler.
+func NewResumableHandler(service UploadService, store upload.ChunkPort, logError func(context.Context, string, ...map[string]interface{}),
+	generate func(ctx context.Context) (string, error), config FileConfig, opts ...int,
+) *upload.ResumableHandler {
+	return upload.NewResumable(func(ctx context.Context, id string, req upload.Request) (interface{}, error) {
+		return service.Upload(ctx, id, Request(req))
+	}, store, logError, generate, upload.FileConfig(config), opts...)
+}
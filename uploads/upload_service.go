@@ -3,8 +3,11 @@ package uploads
 import (
 	"context"
 	"errors"
+	"io"
 	"path/filepath"
 	"strings"
+
+	"github.com/core-go/core/upload"
 )
 
 type UploadService interface {
@@ -12,8 +15,9 @@ type UploadService interface {
 	Delete(ctx context.Context, id string, url string) (int64, error)
 }
 
+// StoragePort stores the files; Upload reads data until io.EOF, so a file is streamed, and is not loaded into memory.
 type StoragePort interface {
-	Upload(ctx context.Context, directory string, filename string, data []byte, contentType string) (string, error)
+	Upload(ctx context.Context, directory string, filename string, data io.Reader, contentType string) (string, error)
 	Delete(ctx context.Context, id string) (bool, error)
 }
 
@@ -44,10 +48,14 @@ func (u *UploadUseCase) Upload(ctx context.Context, id string, req Request) (*Up
 		attachments = make([]Upload, 0)
 	}
 
-	url, err := u.uploadFileOnServer(ctx, req.Filename, req.Type, req.Size, req.Data)
+	data := &counter{Reader: req.Data}
+	url, err := u.uploadFileOnServer(ctx, req.Filename, req.Type, req.Size, data)
 	if err != nil {
 		return nil, err
 	}
+	if req.Size <= 0 {
+		req.Size = data.n
+	}
 	attachment := Upload{
 		OriginalFileName: req.OriginalFileName,
 		FileName:         req.Filename,
@@ -74,28 +82,30 @@ func (u *UploadUseCase) Delete(ctx context.Context, id string, url string) (int6
 	if attachments == nil || len(attachments) == 0 {
 		return -1, errors.New("list file is empty")
 	}
-	exist := false
-	for index, item := range attachments {
+	index := -1
+	for i, item := range attachments {
 		if item.Url == url {
-			_, err2 := u.deleteFile(url, ctx)
-			if err2 != nil {
-				return 0, err2
-			}
-			attachments = append(attachments[:index], attachments[index+1:]...)
-			exist = true
+			index = i
+			break
 		}
 	}
-	if exist == false {
+	if index < 0 {
 		return -1, errors.New("no exist file " + url)
 	}
-	_, err2 := u.repository.Update(ctx, id, attachments)
-	if err2 != nil {
+	remaining := make([]Upload, 0, len(attachments)-1)
+	remaining = append(remaining, attachments[:index]...)
+	remaining = append(remaining, attachments[index+1:]...)
+	// the file is deleted before the row is updated, so a file is not left in the storage without a row, if the deletion fails; the delete can be retried
+	if _, err2 := u.deleteFile(url, ctx); err2 != nil {
+		return 0, err2
+	}
+	if _, err2 := u.repository.Update(ctx, id, remaining); err2 != nil {
 		return 0, err2
 	}
 	return 1, nil
 }
 
-func (u *UploadUseCase) uploadFileOnServer(ctx context.Context, fileName string, contentType string, size int64, data []byte) (rs string, errorRespone error) {
+func (u *UploadUseCase) uploadFileOnServer(ctx context.Context, fileName string, contentType string, size int64, data io.Reader) (rs string, errorRespone error) {
 	directory := u.Directory
 	rs, err2 := u.Service.Upload(ctx, directory, fileName, data, contentType)
 	if err2 != nil {
@@ -105,12 +115,29 @@ func (u *UploadUseCase) uploadFileOnServer(ctx context.Context, fileName string,
 }
 
 func (u *UploadUseCase) deleteFile(url string, ctx context.Context) (bool, error) {
+	if resolver, ok := u.Service.(upload.KeyResolver); ok {
+		if key, ok := resolver.Key(url); ok {
+			return u.Service.Delete(ctx, key)
+		}
+	}
 	arrOrigin := strings.Split(url, "/")
 	delOriginUrl := arrOrigin[len(arrOrigin)-2] + "/" + arrOrigin[len(arrOrigin)-1]
 	rs, err := u.Service.Delete(ctx, delOriginUrl)
 	return rs, err
 }
 
+// counter counts the bytes of a file, which is streamed, so the size is known after the upload.
+type counter struct {
+	io.Reader
+	n int64
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 func getExt(file string) string {
 	ext := filepath.Ext(file)
 	if strings.HasPrefix(ext, ":") {
@@ -15,6 +15,18 @@ func Generate(ctx context.Context) (string, error) {
 	return RandomId(), nil
 }
 
+// V7 returns a time ordered UUID (RFC 9562 version 7), in the canonical format, so that the ids are sorted by the creation time and the inserts into a b-tree index are sequential.
+func V7() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+func GenerateV7(ctx context.Context) (string, error) {
+	return V7()
+}
+
 func Func(auto *bool) func(context.Context) (string, error) {
 	if auto != nil && *auto {
 		return Generate
@@ -0,0 +1,264 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/ja"
+	"github.com/go-playground/locales/vi"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	trde "github.com/go-playground/validator/v10/translations/de"
+	tren "github.com/go-playground/validator/v10/translations/en"
+	tres "github.com/go-playground/validator/v10/translations/es"
+	trfr "github.com/go-playground/validator/v10/translations/fr"
+	trja "github.com/go-playground/validator/v10/translations/ja"
+	trvi "github.com/go-playground/validator/v10/translations/vi"
+	"gopkg.in/yaml.v3"
+)
+
+// Lang is the key of the language in the context.
+const Lang = "lang"
+
+type Locale struct {
+	Translator locales.Translator
+	Register   func(v *validator.Validate, trans ut.Translator) error
+	// Messages are the messages of the custom validations
+	Messages map[string]string
+}
+
+// Locales are the locales, which can be registered by RegisterTranslators. Add a locale here to register it.
+var Locales = map[string]Locale{
+	"en": {Translator: en.New(), Register: tren.RegisterDefaultTranslations, Messages: translations},
+	"vi": {Translator: vi.New(), Register: trvi.RegisterDefaultTranslations, Messages: viTranslations},
+	"fr": {Translator: fr.New(), Register: trfr.RegisterDefaultTranslations, Messages: frTranslations},
+	"de": {Translator: de.New(), Register: trde.RegisterDefaultTranslations, Messages: deTranslations},
+	"ja": {Translator: ja.New(), Register: trja.RegisterDefaultTranslations, Messages: jaTranslations},
+	"es": {Translator: es.New(), Register: tres.RegisterDefaultTranslations, Messages: esTranslations},
+}
+
+// Translators has a translator per language, and the messages, which override the messages of the translators.
+//   - The key of a message is the tag, such as "required", or the field and the tag, such as "address.city.required"; the field is the field of ErrorMessage.
+//   - In a message, {0} is the field and {1} is the param of the tag.
+//
+// UserLanguage returns the language of the user, such as from the settings; if it is empty, the language is from the Accept-Language header.
+type Translators struct {
+	Default      string
+	Translators  map[string]ut.Translator
+	Messages     map[string]map[string]string
+	UserLanguage func(ctx context.Context) string
+}
+
+// RegisterTranslators registers the translators of the languages; the first language is the default. If there is no language, only "en" is registered.
+func RegisterTranslators(validate *validator.Validate, languages ...string) (*Translators, error) {
+	if len(languages) == 0 {
+		languages = []string{"en"}
+	}
+	first, ok := Locales[languages[0]]
+	if !ok {
+		return nil, fmt.Errorf("locale '%s' is not supported", languages[0])
+	}
+	list := make([]locales.Translator, 0, len(languages))
+	for _, lang := range languages {
+		l, ok := Locales[lang]
+		if !ok {
+			return nil, fmt.Errorf("locale '%s' is not supported", lang)
+		}
+		list = append(list, l.Translator)
+	}
+	uni := ut.New(first.Translator, list...)
+	t := &Translators{Default: languages[0], Translators: make(map[string]ut.Translator), Messages: make(map[string]map[string]string)}
+	for _, lang := range languages {
+		l := Locales[lang]
+		trans, _ := uni.GetTranslator(l.Translator.Locale())
+		if err := l.Register(validate, trans); err != nil {
+			return nil, err
+		}
+		for tag, text := range l.Messages {
+			if err := AddMessage(validate, trans, tag, text, true); err != nil {
+				return nil, err
+			}
+		}
+		t.Translators[lang] = trans
+	}
+	return t, nil
+}
+
+// Get returns the translator of the language, such as "vi-VN" or "vi", or the default translator, and the language of the translator.
+func (t *Translators) Get(lang string) (ut.Translator, string) {
+	if l := t.Match(lang); len(l) > 0 {
+		return t.Translators[l], l
+	}
+	return t.Translators[t.Default], t.Default
+}
+
+// Match returns the registered language of a language tag, or an empty string.
+func (t *Translators) Match(lang string) string {
+	lang = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+	if len(lang) == 0 {
+		return ""
+	}
+	if _, ok := t.Translators[lang]; ok {
+		return lang
+	}
+	if i := strings.IndexByte(lang, '-'); i > 0 {
+		if _, ok := t.Translators[lang[:i]]; ok {
+			return lang[:i]
+		}
+	}
+	return ""
+}
+
+// Accept returns the registered language, which is the most preferred by the Accept-Language header, or an empty string.
+func (t *Translators) Accept(header string) string {
+	type item struct {
+		lang string
+		q    float64
+	}
+	var items []item
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if len(fields[0]) == 0 {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			items = append(items, item{lang: fields[0], q: q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	for _, it := range items {
+		if l := t.Match(it.lang); len(l) > 0 {
+			return l
+		}
+	}
+	return ""
+}
+
+// Language returns the language of the context: the language in the context, then the language of the user, then the default.
+func (t *Translators) Language(ctx context.Context) string {
+	if lang, ok := ctx.Value(Lang).(string); ok {
+		if l := t.Match(lang); len(l) > 0 {
+			return l
+		}
+	}
+	if t.UserLanguage != nil {
+		if l := t.Match(t.UserLanguage(ctx)); len(l) > 0 {
+			return l
+		}
+	}
+	return t.Default
+}
+
+// Handle sets the language of the request into the context: the language of the user, then the Accept-Language header, then the default.
+func (t *Translators) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var lang string
+		if t.UserLanguage != nil {
+			lang = t.Match(t.UserLanguage(ctx))
+		}
+		if len(lang) == 0 {
+			lang = t.Accept(r.Header.Get("Accept-Language"))
+		}
+		if len(lang) == 0 {
+			lang = t.Default
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, Lang, lang)))
+	})
+}
+
+// AddMessages adds the messages of a language, which override the messages of the translator.
+func (t *Translators) AddMessages(lang string, messages map[string]string) {
+	m, ok := t.Messages[lang]
+	if !ok {
+		m = make(map[string]string)
+		t.Messages[lang] = m
+	}
+	for k, v := range messages {
+		m[k] = v
+	}
+}
+
+// LoadMessages loads the messages from the files of a directory, whose names are name.lang.json, name.lang.yaml or name.lang.yml, such as validation.vi.yaml.
+// The files of the languages, which are not registered, are skipped.
+func (t *Translators) LoadMessages(directory string) error {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		ext := filepath.Ext(name)
+		if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		base := strings.TrimSuffix(name, ext)
+		i := strings.LastIndexByte(base, '.')
+		if i < 0 {
+			continue
+		}
+		lang := t.Match(base[i+1:])
+		if len(lang) == 0 {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(directory, name))
+		if err != nil {
+			return err
+		}
+		messages := make(map[string]string)
+		if ext == ".json" {
+			err = json.Unmarshal(data, &messages)
+		} else {
+			err = yaml.Unmarshal(data, &messages)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot load %s: %w", name, err)
+		}
+		t.AddMessages(lang, messages)
+	}
+	return nil
+}
+
+// Message returns the message, which overrides the message of the translator, or an empty string.
+func (t *Translators) Message(lang string, field string, fe validator.FieldError) string {
+	m := t.Messages[lang]
+	if m == nil {
+		return ""
+	}
+	text, ok := m[field+"."+fe.Tag()]
+	if !ok {
+		if text, ok = m[fe.Tag()]; !ok {
+			return ""
+		}
+	}
+	return strings.NewReplacer("{0}", fe.Field(), "{1}", fe.Param()).Replace(text)
+}
+
+// WithLanguage returns a context with the language, to validate in this language.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, Lang, lang)
+}
@@ -0,0 +1,73 @@
+package validator
+
+// The messages of the custom validations; email, url, uri, ip, ipv4 and ipv6 are translated by the default translations of each language.
+var viTranslations = map[string]string{
+	"fax":          "{0} phải là số fax hợp lệ",
+	"phone":        "{0} phải là số điện thoại hợp lệ",
+	"digit":        "{0} chỉ được chứa chữ số",
+	"pin":          "{0} chỉ được chứa chữ số",
+	"abc":          "{0} chỉ được chứa chữ cái",
+	"id":           "{0} phải là ID hợp lệ",
+	"code":         "{0} phải là mã hợp lệ",
+	"country_code": "{0} phải là mã quốc gia hợp lệ",
+	"username":     "{0} phải là tên đăng nhập hợp lệ",
+	"regex":        "{0} phải khớp với mẫu đã cho",
+	"after_now":    "{0} phải sau thời điểm hiện tại",
+	"now_or_after": "{0} phải từ thời điểm hiện tại trở đi",
+}
+var frTranslations = map[string]string{
+	"fax":          "{0} doit être un numéro de fax valide",
+	"phone":        "{0} doit être un numéro de téléphone valide",
+	"digit":        "{0} ne doit contenir que des chiffres",
+	"pin":          "{0} ne doit contenir que des chiffres",
+	"abc":          "{0} ne doit contenir que des lettres",
+	"id":           "{0} doit être un identifiant valide",
+	"code":         "{0} doit être un code valide",
+	"country_code": "{0} doit être un code pays valide",
+	"username":     "{0} doit être un nom d'utilisateur valide",
+	"regex":        "{0} doit correspondre au motif fourni",
+	"after_now":    "{0} doit être postérieur à maintenant",
+	"now_or_after": "{0} doit être maintenant ou plus tard",
+}
+var deTranslations = map[string]string{
+	"fax":          "{0} muss eine gültige Faxnummer sein",
+	"phone":        "{0} muss eine gültige Telefonnummer sein",
+	"digit":        "{0} darf nur Ziffern enthalten",
+	"pin":          "{0} darf nur Ziffern enthalten",
+	"abc":          "{0} darf nur Buchstaben enthalten",
+	"id":           "{0} muss eine gültige ID sein",
+	"code":         "{0} muss ein gültiger Code sein",
+	"country_code": "{0} muss ein gültiger Ländercode sein",
+	"username":     "{0} muss ein gültiger Benutzername sein",
+	"regex":        "{0} muss dem angegebenen Muster entsprechen",
+	"after_now":    "{0} muss in der Zukunft liegen",
+	"now_or_after": "{0} muss jetzt oder in der Zukunft liegen",
+}
+var jaTranslations = map[string]string{
+	"fax":          "{0}は有効なFAX番号でなければなりません",
+	"phone":        "{0}は有効な電話番号でなければなりません",
+	"digit":        "{0}は数字のみを含む必要があります",
+	"pin":          "{0}は数字のみを含む必要があります",
+	"abc":          "{0}は英字のみを含む必要があります",
+	"id":           "{0}は有効なIDでなければなりません",
+	"code":         "{0}は有効なコードでなければなりません",
+	"country_code": "{0}は有効な国コードでなければなりません",
+	"username":     "{0}は有効なユーザー名でなければなりません",
+	"regex":        "{0}は指定されたパターンに一致する必要があります",
+	"after_now":    "{0}は現在より後でなければなりません",
+	"now_or_after": "{0}は現在以降でなければなりません",
+}
+var esTranslations = map[string]string{
+	"fax":          "{0} debe ser un número de fax válido",
+	"phone":        "{0} debe ser un número de teléfono válido",
+	"digit":        "{0} debe contener solo dígitos",
+	"pin":          "{0} debe contener solo dígitos",
+	"abc":          "{0} debe contener solo letras",
+	"id":           "{0} debe ser un ID válido",
+	"code":         "{0} debe ser un código válido",
+	"country_code": "{0} debe ser un código de país válido",
+	"username":     "{0} debe ser un nombre de usuario válido",
+	"regex":        "{0} debe coincidir con el patrón indicado",
+	"after_now":    "{0} debe ser posterior a ahora",
+	"now_or_after": "{0} debe ser ahora o posterior",
+}
@@ -23,6 +23,8 @@ type Validator struct {
 	CustomValidateList []CustomValidate
 	IgnoreField        bool
 	Map                map[string]string
+	// Translators translates the messages in the language of the context; if it is nil, the messages are translated by Trans
+	Translators *Translators
 }
 
 func NewChecker(opts ...bool) (*Validator, error) {
@@ -57,6 +59,27 @@ func NewValidatorWithMap(mp map[string]string, opts ...bool) (*Validator, error)
 	}
 	return validator, nil
 }
+
+// NewLocaleValidator creates a validator, which translates the messages into the language of the context, see Translators.Language. The first language is the default.
+func NewLocaleValidator(languages []string, opts ...bool) (*Validator, error) {
+	ignoreField := false
+	if len(opts) > 0 {
+		ignoreField = opts[0]
+	}
+	validate := validator.New()
+	list := GetCustomValidateList()
+	for _, v := range list {
+		if err := validate.RegisterValidation(v.Tag, v.Fn); err != nil {
+			return nil, err
+		}
+	}
+	translators, err := RegisterTranslators(validate, languages...)
+	if err != nil {
+		return nil, err
+	}
+	trans := translators.Translators[translators.Default]
+	return &Validator{validate: validate, Trans: &trans, CustomValidateList: list, IgnoreField: ignoreField, Translators: translators}, nil
+}
 func NewDefaultChecker() (*validator.Validate, ut.Translator, error) {
 	return NewDefaultValidator()
 }
@@ -91,7 +114,11 @@ func (p *Validator) Validate(ctx context.Context, model interface{}) ([]s.ErrorM
 	err := p.validate.Struct(model)
 
 	if err != nil {
-		errors, err = p.MapErrors(err)
+		if p.Translators != nil {
+			errors, err = p.MapErrorsIn(err, p.Translators.Language(ctx))
+		} else {
+			errors, err = p.MapErrors(err)
+		}
 	}
 	v := ctx.Value(method)
 	if v != nil {
@@ -188,3 +215,21 @@ func (p *Validator) MapErrors(err error) (list []s.ErrorMessage, err1 error) {
 	}
 	return
 }
+
+// MapErrorsIn maps the errors with the translator and the messages of the language.
+func (p *Validator) MapErrorsIn(err error, lang string) (list []s.ErrorMessage, err1 error) {
+	if _, ok := err.(*validator.InvalidValidationError); ok {
+		err1 = fmt.Errorf("InvalidValidationError")
+		return
+	}
+	tr, lang := p.Translators.Get(lang)
+	for _, err := range err.(validator.ValidationErrors) {
+		field := s.FormatErrorField(err.Namespace())
+		msg := p.Translators.Message(lang, field, err)
+		if len(msg) == 0 {
+			msg = err.Translate(tr)
+		}
+		list = append(list, s.ErrorMessage{Field: field, Code: getTagName(err), Message: msg, Param: err.Param()})
+	}
+	return
+}
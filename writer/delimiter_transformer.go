@@ -18,8 +18,11 @@ type Delimiter struct {
 	Scale  int
 }
 
+// DelimiterTransformer formats a model as a line of the values, separated by Delimiter.
+// By default, only the strings, which have the delimiter, are quoted; if Quote is true, the values are quoted as RFC 4180, if they have a quote, the delimiter or a line break.
 type DelimiterTransformer[T any] struct {
 	Delimiter  string
+	Quote      bool
 	formatCols map[int]Delimiter
 }
 
@@ -45,11 +48,35 @@ func NewDelimiterFormatter[T any](opts ...string) (*DelimiterTransformer[T], err
 }
 
 func (f *DelimiterTransformer[T]) Transform(ctx context.Context, model *T) string {
-	return ToTextWithDelimiter(model, f.Delimiter, f.formatCols)
+	return ToTextWithDelimiter(model, f.Delimiter, f.formatCols, f.Quote)
 }
 
-func ToTextWithDelimiter(model interface{}, delimiter string, formatCols map[int]Delimiter) string {
+// ToTextWithDelimiter joins the values of the fields by the delimiter; opts[0] quotes the values as RFC 4180, otherwise only the strings, which have the delimiter, are quoted.
+func ToTextWithDelimiter(model interface{}, delimiter string, formatCols map[int]Delimiter, opts ...bool) string {
+	rfc := len(opts) > 0 && opts[0]
+	arr, strs := toValues(model, formatCols)
+	for i, value := range arr {
+		if rfc {
+			if strings.Contains(value, `"`) || strings.Contains(value, delimiter) || strings.ContainsAny(value, "\r\n") {
+				arr[i] = "\"" + strings.ReplaceAll(value, `"`, `""`) + "\""
+			}
+		} else if strs[i] && strings.Contains(value, delimiter) {
+			arr[i] = "\"" + strings.ReplaceAll(value, `"`, `""`) + "\""
+		}
+	}
+	return strings.Join(arr, delimiter) + "\n"
+}
+
+// ToValues formats the fields of formatCols, in the order of the fields, without quoting, such as for a csv.Writer.
+func ToValues(model interface{}, formatCols map[int]Delimiter) []string {
+	arr, _ := toValues(model, formatCols)
+	return arr
+}
+
+// toValues returns the formatted values, and if each value is of a string field.
+func toValues(model interface{}, formatCols map[int]Delimiter) ([]string, []bool) {
 	arr := make([]string, 0)
+	strs := make([]bool, 0)
 	sumValue := reflect.Indirect(reflect.ValueOf(model))
 	for i := 0; i < sumValue.NumField(); i++ {
 		format, ok := formatCols[i]
@@ -57,6 +84,7 @@ func ToTextWithDelimiter(model interface{}, delimiter string, formatCols map[int
 			field := sumValue.Field(i)
 			kind := field.Kind()
 			var value string
+			str := false
 			if kind == reflect.Ptr && field.IsNil() {
 				value = ""
 			} else {
@@ -65,12 +93,8 @@ func ToTextWithDelimiter(model interface{}, delimiter string, formatCols map[int
 					v = reflect.Indirect(reflect.ValueOf(v)).Interface()
 				}
 				if s, okS := v.(string); okS {
-					if strings.Contains(value, `"`) || strings.Contains(s, delimiter) {
-						s = strings.ReplaceAll(s, `"`, `""`)
-						value = "\"" + s + "\""
-					} else {
-						value = s
-					}
+					value = s
+					str = true
 				} else if d, okD := v.(time.Time); okD {
 					if len(format.Format) > 0 {
 						value = d.Format(format.Format)
@@ -107,9 +131,10 @@ func ToTextWithDelimiter(model interface{}, delimiter string, formatCols map[int
 				}
 			}
 			arr = append(arr, value)
+			strs = append(strs, str)
 		}
 	}
-	return strings.Join(arr, delimiter) + "\n"
+	return arr, strs
 }
 func GetIndexesByTag(modelType reflect.Type, tagName string, skipTag string) (map[int]Delimiter, error) {
 	ma := make(map[int]Delimiter)
@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/core-go/core/io/zip"
 )
 
 var (
@@ -20,6 +22,7 @@ type FileWriter struct {
 	GenerateFileName func() string
 	Out              *bufio.Writer
 	File             *os.File
+	closer           io.Closer
 }
 
 func NewFileWriter(buildFileName func() string) (*FileWriter, error) {
@@ -47,6 +50,32 @@ func NewFileWriter(buildFileName func() string) (*FileWriter, error) {
 	return &fw, nil
 }
 
+// NewWriter creates a writer of w, such as an HTTP response or an object of a storage; the content is compressed by the extension of fileName, .gz or .zip.
+// Close completes the compressed content, but does not close w.
+func NewWriter(w io.Writer, fileName string) (*FileWriter, error) {
+	cw, err := zip.NewWriter(w, fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &FileWriter{FileName: fileName, Out: bufio.NewWriter(cw), closer: cw}, nil
+}
+
+// NewCompressedFileWriter creates a file, which is compressed by the extension of the file name, .gz or .zip.
+func NewCompressedFileWriter(buildFileName func() string) (*FileWriter, error) {
+	fw, err := NewFileWriter(buildFileName)
+	if err != nil {
+		return nil, err
+	}
+	cw, err := zip.NewWriter(fw.File, fw.FileName)
+	if err != nil {
+		fw.File.Close()
+		return nil, err
+	}
+	fw.Out = bufio.NewWriter(cw)
+	fw.closer = cw
+	return fw, nil
+}
+
 func (fw *FileWriter) Write(p []byte) (n int, err error) {
 	mux.Lock()
 	defer mux.Unlock()
@@ -55,8 +84,18 @@ func (fw *FileWriter) Write(p []byte) (n int, err error) {
 
 // CloseWriter close the underlying writer.
 func (fw *FileWriter) Close() error {
-	fw.Out.Flush()
-	return fw.File.Close()
+	err := fw.Out.Flush()
+	if fw.closer != nil {
+		if er1 := fw.closer.Close(); er1 != nil && err == nil {
+			err = er1
+		}
+	}
+	if fw.File != nil {
+		if er2 := fw.File.Close(); er2 != nil && err == nil {
+			err = er2
+		}
+	}
+	return err
 }
 
 func CloseAllWriters() error {
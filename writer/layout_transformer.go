@@ -0,0 +1,126 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/core-go/core/layout"
+)
+
+// LayoutTransformer transforms T to a line by a layout.Layout, which is loaded from YAML or JSON, instead of the format tags; T is a struct or a map[string]interface{}.
+//   - Record is the name of the detail record; the default is the first detail record of the layout.
+//   - The detail records are counted and summed by Checker, so Trailer writes the counts and the sums of the fields with Check.
+//   - Transform has the signature of the Transform of Exporter, which has no error: a record which cannot be encoded is skipped, and the first error is kept in Err.
+type LayoutTransformer[T any] struct {
+	Layout  *layout.Layout
+	Record  string
+	Checker *layout.Checker
+	Err     error
+	mu      sync.Mutex
+}
+
+func NewLayoutTransformer[T any](l *layout.Layout, opts ...string) (*LayoutTransformer[T], error) {
+	name := ""
+	if len(opts) > 0 && len(opts[0]) > 0 {
+		name = opts[0]
+	} else {
+		for _, r := range l.Records {
+			if r.Kind == layout.Detail {
+				name = r.Name
+				break
+			}
+		}
+	}
+	r, ok := l.Record(name)
+	if !ok || r.Kind != layout.Detail {
+		return nil, fmt.Errorf("layout %s has no detail record '%s'", l.Name, name)
+	}
+	return &LayoutTransformer[T]{Layout: l, Record: name, Checker: layout.NewChecker(l)}, nil
+}
+
+func (t *LayoutTransformer[T]) Transform(ctx context.Context, model *T) string {
+	line, err := t.Encode(ctx, model)
+	if err != nil {
+		t.mu.Lock()
+		if t.Err == nil {
+			t.Err = err
+		}
+		t.mu.Unlock()
+		return ""
+	}
+	return line
+}
+
+// Encode encodes a detail record, and adds it to Checker.
+func (t *LayoutTransformer[T]) Encode(ctx context.Context, model *T) (string, error) {
+	var v interface{} = model
+	if m, ok := v.(*map[string]interface{}); ok {
+		v = *m
+	}
+	line, err := t.Layout.Encode(t.Record, v)
+	if err != nil {
+		return "", err
+	}
+	r, values, err := t.Layout.Decode(line)
+	if err != nil {
+		return "", err
+	}
+	t.mu.Lock()
+	err = t.Checker.Add(r, values)
+	t.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	return line + "\n", nil
+}
+
+// Header encodes the header record by v, which is a struct, a pointer to a struct or a map[string]interface{}; v can be nil if the fields of the header are constants.
+func (t *LayoutTransformer[T]) Header(v interface{}) (string, error) {
+	return t.encode(layout.Header, v, false)
+}
+
+// Trailer encodes the trailer record by v and by the counts and the sums of the detail records, and resets the counts and the sums, for the next batch.
+func (t *LayoutTransformer[T]) Trailer(v interface{}) (string, error) {
+	return t.encode(layout.Trailer, v, true)
+}
+
+func (t *LayoutTransformer[T]) encode(kind string, v interface{}, check bool) (string, error) {
+	var r *layout.Record
+	for i := range t.Layout.Records {
+		if t.Layout.Records[i].Kind == kind {
+			r = &t.Layout.Records[i]
+			break
+		}
+	}
+	if r == nil {
+		return "", fmt.Errorf("layout %s has no %s", t.Layout.Name, kind)
+	}
+	if v == nil {
+		v = map[string]interface{}{}
+	}
+	if check {
+		t.mu.Lock()
+		values, err := t.Checker.Values(r)
+		t.Checker.Reset()
+		t.mu.Unlock()
+		if err != nil {
+			return "", err
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			for k, value := range m {
+				if _, exist := values[k]; !exist {
+					values[k] = value
+				}
+			}
+		} else if len(values) > 0 {
+			return "", fmt.Errorf("the trailer of layout %s must be a map[string]interface{}", t.Layout.Name)
+		}
+		v = values
+	}
+	line, err := t.Layout.Encode(r.Name, v)
+	if err != nil {
+		return "", err
+	}
+	return line + "\n", nil
+}
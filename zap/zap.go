@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -14,6 +15,7 @@ import (
 
 var fieldConfig FieldConfig
 var logger *zap.Logger
+var atomicLevel *zap.AtomicLevel
 
 func SetLogger(logger0 *zap.Logger) {
 	logger = logger0
@@ -75,10 +77,28 @@ func InitializeWithWriter(c Config, getWriter func(logLocation string, rotationT
 	l, err := cfg.Build(options...)
 	if err == nil {
 		logger = l
+		atomicLevel = &cfg.Level
 	}
 	return l, err
 }
 
+// SetLevel changes the level of the logger created by Initialize at runtime.
+func SetLevel(level string) error {
+	if atomicLevel == nil {
+		return errors.New("logger is not initialized")
+	}
+	return atomicLevel.UnmarshalText([]byte(level))
+}
+
+// Reload applies the level of the new config. It can be subscribed to a config watcher with config.Select.
+func Reload(before Config, after Config) {
+	if before.Level != after.Level && len(after.Level) > 0 {
+		if err := SetLevel(after.Level); err != nil && logger != nil {
+			logger.Error("cannot set log level: " + err.Error())
+		}
+	}
+}
+
 func IsDebugEnable() bool {
 	if logger == nil {
 		return false
@@ -637,3 +657,11 @@ func LogDPanic(ctx context.Context, msg string, opts ...map[string]interface{})
 		DPanicWithFields(ctx, msg, nil)
 	}
 }
+
+// Sync flushes the buffered logs. It should be called before the application exits.
+func Sync() error {
+	if logger == nil {
+		return nil
+	}
+	return logger.Sync()
+}